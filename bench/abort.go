@@ -0,0 +1,57 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorBudget aborts a phase early once the share of failed operations over
+// a sliding window exceeds MaxRate, instead of spending the full duration
+// producing identical failures.
+type ErrorBudget struct {
+	MaxRate float64       // percent of attempts (ops+errors); 0 disables
+	Window  time.Duration // sliding window the rate is measured over
+}
+
+func (b ErrorBudget) enabled() bool { return b.MaxRate > 0 && b.Window > 0 }
+
+// minWindowAttempts keeps a handful of early failures from tripping the
+// budget before the window has seen meaningful traffic.
+const minWindowAttempts = 100
+
+// watchErrors samples the result counters until ctx ends and calls cancel
+// when the error rate over the trailing window exceeds the budget.
+func (r *Result) watchErrors(ctx context.Context, cancel context.CancelFunc, b ErrorBudget) {
+	tick := max(b.Window/10, 10*time.Millisecond)
+	t := r.clock.NewTicker(tick)
+	defer t.Stop()
+
+	type sample struct{ ops, errs int64 }
+	ring := make([]sample, 0, int(b.Window/tick)+1)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C():
+		}
+
+		cur := sample{atomic.LoadInt64(&r.Ops), atomic.LoadInt64(&r.Errors)}
+		if len(ring) == cap(ring) {
+			ring = append(ring[:0], ring[1:]...)
+		}
+		ring = append(ring, cur)
+
+		first := ring[0]
+		ops, errs := cur.ops-first.ops, cur.errs-first.errs
+		if ops+errs < minWindowAttempts {
+			continue
+		}
+		if rate := float64(errs) * 100 / float64(ops+errs); rate > b.MaxRate {
+			r.AbortReason = fmt.Sprintf("error rate %.1f%% over %s exceeded budget %.1f%%", rate, b.Window, b.MaxRate)
+			cancel()
+			return
+		}
+	}
+}
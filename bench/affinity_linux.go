@@ -0,0 +1,30 @@
+//go:build linux
+
+package bench
+
+import (
+	"runtime"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/unix"
+)
+
+const pinSupported = true
+
+// pinWorker locks the calling goroutine to its OS thread and restricts that
+// thread to cpus. The thread is discarded when the goroutine exits locked,
+// so the affinity never leaks into the rest of the process.
+func pinWorker(cpus []int) {
+	if len(cpus) == 0 {
+		return
+	}
+	runtime.LockOSThread()
+
+	var set unix.CPUSet
+	for _, c := range cpus {
+		set.Set(c)
+	}
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		log.Warn().Err(err).Ints("cpuset", cpus).Msg("failed to pin worker")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package bench
+
+const pinSupported = false
+
+func pinWorker(_ []int) {}
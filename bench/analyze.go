@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+)
+
+// Delta compares a result with an earlier phase of the same run, in
+// percent of the earlier value.
+type Delta struct {
+	Versus string  `json:"versus"` // workload compared against
+	Ops    float64 `json:"ops_pct"`
+	P50    float64 `json:"p50_pct"`
+	P99    float64 `json:"p99_pct"`
+}
+
+func newDelta(base, cur Result) *Delta {
+	pct := func(b, c float64) float64 {
+		if b == 0 {
+			return 0
+		}
+		return (c - b) * 100 / b
+	}
+	return &Delta{
+		Versus: base.Workload,
+		Ops:    pct(opsPerSec(base), opsPerSec(cur)),
+		P50:    pct(float64(base.P50), float64(cur.P50)),
+		P99:    pct(float64(base.P99), float64(cur.P99)),
+	}
+}
+
+func (d *Delta) String() string {
+	return fmt.Sprintf("ops/s %+.1f%%  p50 %+.1f%%  p99 %+.1f%% (vs %s)", d.Ops, d.P50, d.P99, d.Versus)
+}
+
+// analyzeStmt is the statement collecting planner statistics on engine.
+func analyzeStmt(engine string) (string, error) {
+	switch engine {
+	case "sqlite", "pgx":
+		return `ANALYZE`, nil
+	case "mariadb", "tidb":
+		return `ANALYZE TABLE kv, typed`, nil
+	case "chai":
+		return "", &errSkip{"chai has no ANALYZE"}
+	case "clickhouse":
+		return "", &errSkip{"clickhouse has no planner statistics to collect"}
+	}
+	return "", &errSkip{"no known ANALYZE statement for " + engine}
+}
+
+// analyzeRead is a read of the analyze experiment.
+type analyzeRead struct {
+	name    string
+	queries []readQuery
+}
+
+// analyzePhases is the analyze experiment: the reads over typed whose plans
+// depend on statistics (the filter buckets, sort and group) run once as
+// "<read>-unanalyzed", then, after ANALYZE, again as "<read>-analyzed"
+// with a Delta against the first run. "unanalyzed" means whatever
+// statistics the database already had, so start from a fresh one.
+func analyzePhases(engine string, sortLimit int) []phaseSpec {
+	reads := make([]analyzeRead, 0, len(filterBuckets)+2)
+	for _, b := range filterBuckets {
+		reads = append(reads, analyzeRead{b.name(), b.queries()})
+	}
+	reads = append(reads, analyzeRead{"sort", sortQueries(sortLimit)}, analyzeRead{"group", groupQueries})
+
+	before := make(map[string]Result, len(reads))
+	phases := make([]phaseSpec, 0, 2*len(reads))
+	for _, r := range reads {
+		phaseName := r.name + "-unanalyzed"
+		phases = append(phases, phaseSpec{phaseName, func(s *suite) (WorkloadFunc, error) {
+			if _, err := analyzeStmt(engine); err != nil {
+				return nil, err
+			}
+			if err := s.typedReady(); err != nil {
+				return nil, err
+			}
+			wf := readWorkload(phaseName, dollarPlaceholders(s.cfg), r.queries)
+			return func(ctx context.Context, db Executor, ph Phase) Result {
+				res := wf(ctx, db, ph)
+				if !ph.Warmup {
+					before[r.name] = res
+				}
+				return res
+			}, nil
+		}})
+	}
+	for _, r := range reads {
+		phaseName := r.name + "-analyzed"
+		phases = append(phases, phaseSpec{phaseName, func(s *suite) (WorkloadFunc, error) {
+			q, err := analyzeStmt(engine)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.typedReady(); err != nil {
+				return nil, err
+			}
+			if err := s.prepare("analyze", func() error {
+				_, err := s.db.ExecContext(s.ctx, q)
+				return err
+			}); err != nil {
+				return nil, err
+			}
+			wf := readWorkload(phaseName, dollarPlaceholders(s.cfg), r.queries)
+			return func(ctx context.Context, db Executor, ph Phase) Result {
+				res := wf(ctx, db, ph)
+				if base, ok := before[r.name]; ok && !ph.Warmup && !base.Aborted {
+					res.Delta = newDelta(base, res)
+				}
+				return res
+			}, nil
+		}})
+	}
+	return phases
+}
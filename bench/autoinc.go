@@ -0,0 +1,62 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"time"
+
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+)
+
+// autoIncSchema creates kv_auto, whose key the engine generates: an
+// AUTOINCREMENT column, an identity (sequence) or, on chai, a sequence
+// default. ClickHouse has no generated keys.
+func autoIncSchema(engine string) (string, error) {
+	if engine == "clickhouse" {
+		return "", &errSkip{"clickhouse has no auto-increment keys"}
+	}
+	return schemaFor(engine, embed.AutoPgSchema, embed.AutoSqliteSchema, embed.AutoChaiSchema, embed.AutoMysqlSchema, "")
+}
+
+// autoIncWorkload is the insert workload on kv_auto: only the payload is
+// sent and the engine picks the key, so against insert it compares the
+// engine's key generation with client-supplied keys.
+func autoIncWorkload(dollar bool, batch int, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv_auto(v) VALUES(?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("autoinc", ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				ops := res.txOps(worker)
+
+				res.insertTxs(ctx, db, q, batch, insertBatch{
+					row: func(_ *sql.Tx, stmt *sql.Stmt, _ int) bool {
+						start := res.clock.Now()
+						octx, done := res.op(ctx)
+						_, err := stmt.ExecContext(octx, pl.pick(rnd))
+						if err = done(err); err != nil {
+							res.addErrorCnt(err)
+							return interrupts(err)
+						}
+						ops.add(res.clock.Since(start))
+						return false
+					},
+					ended: ops.end,
+				})
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
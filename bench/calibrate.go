@@ -0,0 +1,95 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// calibrationOps is how many writes each DiskCalibration measurement
+// times.
+const calibrationOps = 200
+
+// DiskCalibration is the raw latency of the storage under the data
+// directory, measured before the suite as the hardware baseline of every
+// durable write: 4 KiB writes each followed by an fsync (see timeFsyncs),
+// and (Linux only)
+// 4 KiB O_DIRECT writes, bypassing the page cache, with and without
+// O_DSYNC.
+type DiskCalibration struct {
+	Dir         string        `json:"dir"`
+	Fsync       *LatencyStats `json:"fsync,omitempty"`
+	Direct      *LatencyStats `json:"direct,omitempty"`
+	DirectDSync *LatencyStats `json:"direct_dsync,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+func (c DiskCalibration) pretty() string {
+	out := c.Dir
+	if c.Error != "" {
+		out += ": " + c.Error
+	}
+	for _, m := range []struct {
+		name string
+		s    *LatencyStats
+	}{{"fsync", c.Fsync}, {"O_DIRECT", c.Direct}, {"O_DIRECT|O_DSYNC", c.DirectDSync}} {
+		if m.s != nil {
+			out += fmt.Sprintf("\n\t\t\t  %-17s P50=%s  P95=%s  P99=%s", m.name, fDur(m.s.P50), fDur(m.s.P95), fDur(m.s.P99))
+		}
+	}
+	return out
+}
+
+// calibrationDir is the directory holding path, or path itself when it is
+// one (the key-value baselines' data directories).
+func calibrationDir(path string) string {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return path
+	}
+	return filepath.Dir(path)
+}
+
+// calibrateDir measures a DiskCalibration of dir in scratch files it
+// removes again. Failures are recorded in Error.
+func calibrateDir(dir string) *DiskCalibration {
+	c := &DiskCalibration{Dir: dir}
+	f, err := os.CreateTemp(dir, ".calibrate-*")
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	fsync, err := timeFsyncs(f, calibrationOps)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.Fsync = fsync.stats()
+	direct, dsync, err := timeDirectWrites(dir)
+	if err != nil {
+		c.Error = "O_DIRECT: " + err.Error()
+	}
+	c.Direct, c.DirectDSync = direct.stats(), dsync.stats()
+	return c
+}
+
+// timeFsyncs times n 4 KiB writes to f, each with its fsync: the cost of
+// one durable write, as DiskCalibration.Fsync and Machine.FsyncP50 both
+// report it.
+func timeFsyncs(f *os.File, n int) (histogram, error) {
+	var h histogram
+	block := make([]byte, 4<<10)
+	for range n {
+		start := time.Now()
+		if _, err := f.Write(block); err != nil {
+			return h, err
+		}
+		if err := f.Sync(); err != nil {
+			return h, err
+		}
+		h.add(time.Since(start))
+	}
+	return h, nil
+}
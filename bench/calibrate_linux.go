@@ -0,0 +1,47 @@
+//go:build linux
+
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// directAlign is the buffer, offset and size alignment O_DIRECT needs on
+// common devices.
+const directAlign = 4 << 10
+
+// timeDirectWrites times calibrationOps 4 KiB O_DIRECT writes to a scratch
+// file in dir, then as many with O_DSYNC added.
+func timeDirectWrites(dir string) (direct, dsync histogram, err error) {
+	buf := make([]byte, 2*directAlign)
+	off := directAlign - int(uintptr(unsafe.Pointer(&buf[0]))%directAlign)
+	block := buf[off%directAlign:][:directAlign]
+	for _, m := range []struct {
+		h     *histogram
+		flags int
+	}{{&direct, 0}, {&dsync, unix.O_DSYNC}} {
+		path := filepath.Join(dir, ".calibrate-direct")
+		fd, err := unix.Open(path, unix.O_CREAT|unix.O_TRUNC|unix.O_WRONLY|unix.O_DIRECT|m.flags, 0o600)
+		if err != nil {
+			return direct, dsync, err // e.g. EINVAL on tmpfs
+		}
+		for i := range calibrationOps {
+			start := time.Now()
+			if _, err = unix.Pwrite(fd, block, int64(i*directAlign)); err != nil {
+				break
+			}
+			m.h.add(time.Since(start))
+		}
+		_ = unix.Close(fd)
+		_ = os.Remove(path)
+		if err != nil {
+			return direct, dsync, err
+		}
+	}
+	return direct, dsync, nil
+}
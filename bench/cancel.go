@@ -0,0 +1,145 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cancelSettle bounds how long the cancel workload waits after its phase
+// for pool connections and goroutines to return to their level before it;
+// what is still missing then counts as leaked.
+const cancelSettle = 2 * time.Second
+
+// CancelStats is how the engine honored the cancel workload's context
+// cancellations. Latency runs from cancel() to the query returning.
+type CancelStats struct {
+	Fired            int64         `json:"fired"`
+	Honored          int64         `json:"honored"`
+	Finished         int64         `json:"finished,omitempty"` // completed normally despite the cancellation
+	Latency          *LatencyStats `json:"latency,omitempty"`
+	LeakedConns      int           `json:"leaked_conns,omitempty"`
+	LeakedGoroutines int           `json:"leaked_goroutines,omitempty"`
+}
+
+func (s CancelStats) pretty() string {
+	out := fmt.Sprintf("%s fired, %s honored", commaI(s.Fired), commaI(s.Honored))
+	if s.Latency != nil {
+		out += fmt.Sprintf(" in P50=%s  P95=%s  P99=%s", fDur(s.Latency.P50), fDur(s.Latency.P95), fDur(s.Latency.P99))
+	}
+	if s.Finished > 0 {
+		out += fmt.Sprintf(", %s finished regardless", commaI(s.Finished))
+	}
+	if s.LeakedConns > 0 || s.LeakedGoroutines > 0 {
+		out += fmt.Sprintf(", leaked %d conn(s) and %d goroutine(s)", s.LeakedConns, s.LeakedGoroutines)
+	}
+	return out
+}
+
+// cancelWorkload runs a query that makes the engine sort all of kv before
+// its first row, and cancels pct percent of them through their context
+// after a random delay below after. Queries left alone are the ops;
+// canceled ones are reported in Result.Cancel instead, with the time the
+// engine took to give up on them. After the phase the connection pool and
+// goroutine count are given cancelSettle to return to their levels from
+// before it, so cancellations that strand connections or goroutines show
+// as leaks.
+func cancelWorkload(dollar bool, pct int, after time.Duration) WorkloadFunc {
+	q := bind(dollar, `SELECT k, v FROM kv ORDER BY v DESC`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("cancel", ph)
+		pool, _ := db.(interface{ Stats() sql.DBStats })
+		inUse := func() int {
+			if pool == nil {
+				return 0
+			}
+			return pool.Stats().InUse
+		}
+		conns, goroutines := inUse(), runtime.NumGoroutine()
+
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var (
+			fired, honored, finished atomic.Int64
+			mu                       sync.Mutex
+			lat                      histogram
+		)
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					octx, done := res.op(ctx)
+					qctx, qcancel := context.WithCancel(octx)
+					start := res.clock.Now()
+					// when the cancellation fired, as an offset from start
+					var at atomic.Int64
+					var timer *time.Timer
+					if rnd.Intn(100) < pct {
+						delay := time.Duration(rnd.Int63n(int64(max(1, after))))
+						timer = time.AfterFunc(delay, func() {
+							at.Store(int64(res.clock.Since(start)))
+							qcancel()
+						})
+					}
+					n, err := streamRows(qctx, db, q, nil, 0, func() {})
+					end := res.clock.Since(start)
+					canceled := timer != nil && !timer.Stop()
+					qcancel()
+					err = done(err)
+					res.addRows(n)
+					if canceled {
+						if ctx.Err() != nil {
+							return // the phase ended as well: no telling which stopped it
+						}
+						fired.Add(1)
+						if err == nil {
+							finished.Add(1)
+							continue
+						}
+						if isCanceled(err) {
+							honored.Add(1)
+							mu.Lock()
+							lat.add(end - time.Duration(at.Load()))
+							mu.Unlock()
+							continue
+						}
+					}
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, end)
+				}
+			}(w)
+		}
+		wg.Wait()
+		cancel()
+		out := res.finalize()
+
+		// after finalize, so the phase's own goroutines are gone
+		stats := &CancelStats{Fired: fired.Load(), Honored: honored.Load(), Finished: finished.Load(), Latency: lat.stats()}
+		for deadline := time.Now().Add(cancelSettle); ; time.Sleep(10 * time.Millisecond) {
+			stats.LeakedConns = max(0, inUse()-conns)
+			stats.LeakedGoroutines = max(0, runtime.NumGoroutine()-goroutines)
+			if (stats.LeakedConns == 0 && stats.LeakedGoroutines == 0) || time.Now().After(deadline) {
+				break
+			}
+		}
+		out.Cancel = stats
+		return out
+	}
+}
@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// capability is an SQL or driver feature some workloads depend on.
+// Isolation levels are capabilities of their own ("isolation:<name>", see
+// isolationLevels).
+type capability string
+
+const (
+	capReturning   capability = "returning"    // INSERT ... RETURNING
+	capOnConflict  capability = "on-conflict"  // INSERT ... ON CONFLICT DO NOTHING
+	capBlobBetween capability = "blob-between" // BETWEEN comparisons on BLOB columns
+	capPointUpdate capability = "point-update" // single-row UPDATE by primary key
+	capPointDelete capability = "point-delete" // single-row DELETE by primary key
+	capSavepoint   capability = "savepoints"   // SAVEPOINT / ROLLBACK TO SAVEPOINT
+	capFTS5        capability = "fts5"         // sqlite FTS5 virtual tables
+	capTrigram     capability = "pg-trgm"      // installed pg_trgm extension
+)
+
+func capIsolation(level string) capability { return capability("isolation:" + level) }
+
+// capabilities is the set of features the connected engine supports.
+type capabilities map[capability]bool
+
+// probeKey is written by the probes and never committed.
+const probeKey = "__capability_probe__"
+
+// detectCapabilities probes the engine for each capability inside
+// transactions that are rolled back, so the result reflects the actual
+// engine and driver versions rather than a table that goes stale.
+func detectCapabilities(ctx context.Context, db *sql.DB, dollar bool) capabilities {
+	probes := map[capability]func(tx *sql.Tx) error{
+		capReturning: func(tx *sql.Tx) error {
+			var k string
+			return tx.QueryRowContext(ctx, bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?) RETURNING k`), probeKey, []byte("x")).Scan(&k)
+		},
+		capOnConflict: func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?) ON CONFLICT DO NOTHING`), probeKey, []byte("x"))
+			return err
+		},
+		capBlobBetween: func(tx *sql.Tx) error {
+			var n int64
+			return tx.QueryRowContext(ctx, bind(dollar, `SELECT COUNT(*) FROM kv WHERE v BETWEEN ? AND ?`), []byte("a"), []byte("b")).Scan(&n)
+		},
+		capPointUpdate: func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, bind(dollar, `UPDATE kv SET v = ? WHERE k = ?`), []byte("x"), probeKey)
+			return err
+		},
+		capPointDelete: func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, bind(dollar, `DELETE FROM kv WHERE k = ?`), probeKey)
+			return err
+		},
+		capSavepoint: func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT capability_probe`); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT capability_probe`)
+			return err
+		},
+		capFTS5: func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `CREATE VIRTUAL TABLE temp.capability_probe USING fts5(t)`)
+			return err
+		},
+		capTrigram: func(tx *sql.Tx) error {
+			var f float64
+			return tx.QueryRowContext(ctx, `SELECT similarity('probe', 'prove')`).Scan(&f)
+		},
+	}
+
+	caps := capabilities{}
+	for c, probe := range probes {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			continue
+		}
+		caps[c] = probe(tx) == nil
+		_ = tx.Rollback()
+	}
+	for name, level := range isolationLevels {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: level})
+		if err == nil {
+			// some drivers only reject the level on first use
+			var one int
+			err = tx.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+			_ = tx.Rollback()
+		}
+		caps[capIsolation(name)] = err == nil
+	}
+	return caps
+}
+
+// list returns the supported capabilities, sorted, for the report.
+func (c capabilities) list() []string {
+	var out []string
+	for k, ok := range c {
+		if ok {
+			out = append(out, string(k))
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// errSkip tells Run to record a phase as skipped instead of failing the
+// suite.
+type errSkip struct{ reason string }
+
+func (e *errSkip) Error() string { return "skipped: " + e.reason }
+
+// require returns an *errSkip naming the capabilities the engine lacks, or
+// nil when it has them all. Phase builders return its error as-is.
+func (s *suite) require(needs ...capability) error {
+	var missing []string
+	for _, c := range needs {
+		if !s.caps[c] {
+			missing = append(missing, string(c))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &errSkip{fmt.Sprintf("%s does not support %s", s.cfg.Engine, strings.Join(missing, ", "))}
+}
+
+// skippedResult is the report entry for a phase that did not run.
+func skippedResult(name string, err error) (Result, bool) {
+	var skip *errSkip
+	if !errors.As(err, &skip) {
+		return Result{}, false
+	}
+	return Result{Workload: name, Skipped: true, SkipReason: skip.reason}, true
+}
@@ -0,0 +1,190 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai"
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+)
+
+// openChaiNative opens dsn with chai's Go API and creates the kv schema.
+// The database/sql driver cannot share the files while this handle is
+// open, so a chai-native run does all of its work through it.
+func openChaiNative(dsn string) (*chai.DB, error) {
+	p := strings.TrimPrefix(dsn, "file:")
+	if p != "" && p != "." {
+		_ = os.MkdirAll(filepath.Dir(filepath.FromSlash(p)), 0755)
+	}
+	db, err := chai.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Exec(embed.ChaiSchema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// chaiKeySnapshot is FetchKeySnapshot through chai's Go API.
+func chaiKeySnapshot(db *chai.DB, n int) ([]string, error) {
+	res, err := db.Query(fmt.Sprintf(`SELECT k FROM kv ORDER BY k DESC LIMIT %d`, n))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	keys := make([]string, 0, n)
+	err = res.Iterate(func(r *chai.Row) error {
+		var k string
+		if err := r.Scan(&k); err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("snapshot is empty: no keys fetched")
+	}
+	return keys, nil
+}
+
+// chaiNativeWorkload mirrors the database/sql workloads on a *chai.DB:
+// the same statements, prepared per worker, timed the same way, so the
+// difference to the chai engine is the database/sql driver layer.
+func chaiNativeWorkload(name string, db *chai.DB, write bool, newOp func(db *chai.DB, worker int) (opFunc, error)) WorkloadFunc {
+	return opWorkload(name, write, func(ctx context.Context, worker int) (opFunc, error) {
+		return newOp(db.WithContext(ctx), worker)
+	})
+}
+
+// chaiNativePhase returns the chai-native variant of a core kv workload;
+// ok is false for workloads without one.
+func chaiNativePhase(name string, cfg Config) (spec phaseSpec, ok bool, err error) {
+	var pl payloads
+	switch name {
+	case "insert", "update":
+		fixed := map[string]string{"insert": "payload", "update": "updated"}[name]
+		if pl, err = newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, fixed); err != nil {
+			return phaseSpec{}, false, err
+		}
+	case "select", "range", "delete":
+	default:
+		return phaseSpec{}, false, nil
+	}
+
+	if name == "insert" {
+		kg, err := newKeyGens(cfg.KeyGen, cfg.RandflakeSecret)
+		if err != nil {
+			return phaseSpec{}, false, err
+		}
+		batch := max(1, cfg.TxBatch)
+		return phaseSpec{name, func(s *suite) (WorkloadFunc, error) {
+			return chaiNativeWorkload(name, s.chai, true, func(db *chai.DB, worker int) (opFunc, error) {
+				gen, err := kg(worker)
+				if err != nil {
+					return nil, err
+				}
+				return func(_ context.Context, rnd *rand.Rand, clk Clock, observe func(time.Duration)) error {
+					tx, err := db.Begin(true)
+					if err != nil {
+						return err
+					}
+					defer tx.Rollback()
+					stmt, err := tx.Prepare(`INSERT INTO kv(k, v) VALUES(?, ?)`)
+					if err != nil {
+						return err
+					}
+					for range batch {
+						k, err := gen.next()
+						if err != nil {
+							return err
+						}
+						start := clk.Now()
+						if err := stmt.Exec(k, pl.pick(rnd)); err != nil {
+							return err
+						}
+						observe(clk.Since(start))
+					}
+					return tx.Commit()
+				}, nil
+			}), nil
+		}}, true, nil
+	}
+
+	return phaseSpec{name, func(s *suite) (WorkloadFunc, error) {
+		keys, err := s.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		write := name == "update" || name == "delete"
+		return chaiNativeWorkload(name, s.chai, write, func(db *chai.DB, _ int) (opFunc, error) {
+			return chaiKeyOp(db, name, keys, pl)
+		}), nil
+	}}, true, nil
+}
+
+func chaiKeyOp(db *chai.DB, name string, keys []string, pl payloads) (opFunc, error) {
+	q := map[string]string{
+		"select": `SELECT v FROM kv WHERE k = ?`,
+		"range":  `SELECT k, v FROM kv WHERE k BETWEEN ? AND ? LIMIT ?`,
+		"update": `UPDATE kv SET v = ? WHERE k = ?`,
+		"delete": `DELETE FROM kv WHERE k = ?`,
+	}[name]
+	stmt, err := db.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	key := func(rnd *rand.Rand) string { return keys[rnd.Intn(len(keys))] }
+
+	return func(_ context.Context, rnd *rand.Rand, clk Clock, observe func(time.Duration)) error {
+		start := clk.Now()
+		switch name {
+		case "select":
+			row, err := stmt.QueryRow(key(rnd))
+			if err != nil {
+				return err
+			}
+			var v []byte
+			if err := row.Scan(&v); err != nil {
+				return err
+			}
+		case "range":
+			lo, hi := key(rnd), key(rnd)
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			res, err := stmt.Query(lo, hi, 100)
+			if err != nil {
+				return err
+			}
+			err = res.Iterate(func(r *chai.Row) error {
+				var k string
+				var v []byte
+				return r.Scan(&k, &v)
+			})
+			_ = res.Close()
+			if err != nil {
+				return err
+			}
+		case "update":
+			if err := stmt.Exec(pl.pick(rnd), key(rnd)); err != nil {
+				return err
+			}
+		default: // delete
+			if err := stmt.Exec(key(rnd)); err != nil {
+				return err
+			}
+		}
+		observe(clk.Since(start))
+		return nil
+	}, nil
+}
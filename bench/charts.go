@@ -0,0 +1,174 @@
+package bench
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// vegaLiteSchema is the Vega-Lite version the chart specs are written for.
+const vegaLiteSchema = "https://vega.github.io/schema/vega-lite/v5.json"
+
+// cdfPoints is how many points a latency CDF chart plots per run.
+const cdfPoints = 200
+
+// Chart is a ready-to-render Vega-Lite spec with its data inlined, so it
+// opens as is in the Vega editor or renders with vl2svg/vl2png.
+type Chart struct {
+	Name string // file name, e.g. insert.cdf.vl.json
+	Spec map[string]any
+}
+
+// JSON renders the spec.
+func (c Chart) JSON() []byte {
+	j, _ := json.MarshalIndent(c.Spec, "", "  ")
+	return j
+}
+
+// Charts builds from one or more reports, each drawn as its own series:
+//
+//	compare.vl.json:            ops/s and p99 per workload
+//	<workload>.timeline.vl.json: ops and p99 per second
+//	<workload>.cdf.vl.json:      latency CDF, from the raw samples (--samples) if any
+func Charts(reps []*Report) []Chart {
+	labels := runLabels(reps)
+	var (
+		compare   []map[string]any
+		workloads []string
+		timeline  = make(map[string][]map[string]any)
+		cdf       = make(map[string][]map[string]any)
+	)
+	for i, rep := range reps {
+		for _, r := range rep.Results {
+			if r.Skipped {
+				continue
+			}
+			if !slices.Contains(workloads, r.Workload) {
+				workloads = append(workloads, r.Workload)
+			}
+			compare = append(compare, map[string]any{
+				"run": labels[i], "workload": r.Workload, "ops_per_sec": opsPerSec(r), "p99_ms": ms(r.P99),
+			})
+			for sec, s := range r.Timeline {
+				timeline[r.Workload] = append(timeline[r.Workload], map[string]any{
+					"run": labels[i], "second": sec + 1, "ops": s.Ops, "p99_ms": ms(s.P99),
+				})
+			}
+			points := cdfOf(r.Samples)
+			if points == nil {
+				for q, d := range r.CDF {
+					points = append(points, cdfPoint{d, float64(q+1) / float64(len(r.CDF))})
+				}
+			}
+			for _, p := range points {
+				cdf[r.Workload] = append(cdf[r.Workload], map[string]any{
+					"run": labels[i], "latency_ms": ms(p.latency), "fraction": p.fraction,
+				})
+			}
+		}
+	}
+
+	run := map[string]any{"field": "run", "type": "nominal", "title": "run"}
+	charts := []Chart{{"compare.vl.json", map[string]any{
+		"$schema": vegaLiteSchema,
+		"title":   "Throughput and tail latency per workload",
+		"data":    map[string]any{"values": compare},
+		"vconcat": []any{
+			groupedBar("ops_per_sec", "ops/s", run),
+			groupedBar("p99_ms", "p99 (ms)", run),
+		},
+	}}}
+	for _, w := range workloads {
+		if rows := timeline[w]; len(rows) > 0 {
+			line := func(field, title string) map[string]any {
+				return map[string]any{
+					"mark": "line",
+					"encoding": map[string]any{
+						"x":     map[string]any{"field": "second", "type": "quantitative", "title": "second"},
+						"y":     map[string]any{"field": field, "type": "quantitative", "title": title},
+						"color": run,
+					},
+				}
+			}
+			charts = append(charts, Chart{chartFile(w, "timeline"), map[string]any{
+				"$schema": vegaLiteSchema,
+				"title":   w + ": throughput over time",
+				"data":    map[string]any{"values": rows},
+				"vconcat": []any{line("ops", "ops/s"), line("p99_ms", "p99 (ms)")},
+			}})
+		}
+		if rows := cdf[w]; len(rows) > 0 {
+			charts = append(charts, Chart{chartFile(w, "cdf"), map[string]any{
+				"$schema": vegaLiteSchema,
+				"title":   w + ": latency CDF",
+				"data":    map[string]any{"values": rows},
+				"mark":    map[string]any{"type": "line", "interpolate": "step-after"},
+				"encoding": map[string]any{
+					"x":     map[string]any{"field": "latency_ms", "type": "quantitative", "title": "latency (ms)", "scale": map[string]any{"type": "log"}},
+					"y":     map[string]any{"field": "fraction", "type": "quantitative", "title": "fraction of operations"},
+					"color": run,
+				},
+			}})
+		}
+	}
+	return charts
+}
+
+func groupedBar(field, title string, run map[string]any) map[string]any {
+	return map[string]any{
+		"mark": "bar",
+		"encoding": map[string]any{
+			"x":       map[string]any{"field": "workload", "type": "nominal", "sort": nil},
+			"xOffset": run,
+			"y":       map[string]any{"field": field, "type": "quantitative", "title": title},
+			"color":   run,
+		},
+	}
+}
+
+// runLabels names each report's series by its engine, adding the run id
+// where engines repeat.
+func runLabels(reps []*Report) []string {
+	seen := make(map[string]int, len(reps))
+	for _, rep := range reps {
+		seen[rep.Meta.Engine]++
+	}
+	labels := make([]string, len(reps))
+	for i, rep := range reps {
+		labels[i] = rep.Meta.Engine
+		if seen[rep.Meta.Engine] > 1 {
+			labels[i] += " " + rep.RunID
+		}
+	}
+	return labels
+}
+
+var unsafeFileRunes = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func chartFile(workload, kind string) string {
+	return unsafeFileRunes.ReplaceAllString(workload, "_") + "." + kind + ".vl.json"
+}
+
+type cdfPoint struct {
+	latency  time.Duration
+	fraction float64
+}
+
+// cdfOf thins the empirical CDF of samples to at most cdfPoints points.
+func cdfOf(samples []time.Duration) []cdfPoint {
+	if len(samples) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	n := min(cdfPoints, len(sorted))
+	out := make([]cdfPoint, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := i*len(sorted)/n - 1
+		out = append(out, cdfPoint{sorted[idx], float64(idx+1) / float64(len(sorted))})
+	}
+	return out
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the time source of a phase: its deadline, the latencies its
+// workers time, its per-second timeline and its watchdogs. Phases run on
+// the wall clock; a fakeClock makes that logic deterministic.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// AfterFunc calls f in its own goroutine once d has passed; stop
+	// prevents that if it has not happened yet.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the part of *time.Ticker the phases use.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// wallClock is the real Clock.
+type wallClock struct{}
+
+func (wallClock) Now() time.Time                  { return time.Now() }
+func (wallClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+func (wallClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+func (wallClock) NewTicker(d time.Duration) Ticker { return wallTicker{time.NewTicker(d)} }
+
+// wallTimers marks the clocks whose timers are the wall clock's, the
+// timing modes' included (they embed wallClock).
+func (wallClock) wallTimers() {}
+
+type wallTicker struct{ *time.Ticker }
+
+func (t wallTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// withDeadline is context.WithTimeout on clock c. Clocks with wall timers
+// get context.WithTimeout itself, with its Deadline and cheap Err.
+func withDeadline(ctx context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := c.(interface{ wallTimers() }); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	cc := &clockCtx{Context: ctx, done: make(chan struct{})}
+	stopParent := context.AfterFunc(ctx, func() { cc.cancel(ctx.Err()) })
+	stopTimer := c.AfterFunc(d, func() { cc.cancel(context.DeadlineExceeded) })
+	return cc, func() {
+		stopTimer()
+		stopParent()
+		cc.cancel(context.Canceled)
+	}
+}
+
+// clockCtx is a context whose deadline runs on a Clock other than the
+// wall clock. Unlike one canceled with a cause, its Err is
+// context.DeadlineExceeded once the deadline passes, as with
+// context.WithTimeout. Deadline and Value are its parent's.
+type clockCtx struct {
+	context.Context
+	done chan struct{}
+	mu   sync.Mutex
+	err  error
+}
+
+func (c *clockCtx) Done() <-chan struct{} { return c.done }
+
+func (c *clockCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *clockCtx) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// fakeClock only moves when Advance is called, firing the timers and
+// tickers that came due on the way.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at     time.Time
+	period time.Duration // tickers only
+	fire   func(now time.Time)
+	done   bool
+}
+
+func newFakeClock(start time.Time) *fakeClock { return &fakeClock{now: start} }
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Since(t time.Time) time.Duration { return c.Now().Sub(t) }
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	t := c.add(d, 0, func(time.Time) { go f() })
+	return func() bool { return c.stop(t) }
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	ch := make(chan time.Time, 1)
+	t := c.add(d, d, func(now time.Time) {
+		select {
+		case ch <- now:
+		default: // like time.Ticker, drop ticks for slow receivers
+		}
+	})
+	return &fakeTicker{c: ch, stop: func() { c.stop(t) }}
+}
+
+// Advance moves the clock forward by d, firing everything due in order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.done && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		now := next.at
+		c.now = now
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			next.done = true
+		}
+		c.mu.Unlock()
+		next.fire(now)
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+func (c *fakeClock) add(d, period time.Duration, fire func(time.Time)) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), period: period, fire: fire}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) stop(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	was := !t.done
+	t.done = true
+	return was
+}
+
+type fakeTicker struct {
+	c    chan time.Time
+	stop func()
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+func (t *fakeTicker) Stop()               { t.stop() }
@@ -0,0 +1,238 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pending counts the timers and tickers of c that may still fire.
+func (c *fakeClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if !t.done {
+			n++
+		}
+	}
+	return n
+}
+
+// waitFor polls cond for the goroutines of a phase to catch up with the
+// fake clock.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for end := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(end) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not done")
+	}
+}
+
+// addOps records n operations and advances clk until the collector has
+// counted them.
+func addOps(t *testing.T, r *Result, clk *fakeClock, n int) {
+	t.Helper()
+	want := atomic.LoadInt64(&r.Ops) + int64(n)
+	for range n {
+		r.addLatency(0, time.Millisecond)
+	}
+	waitFor(t, "the operations to be collected", func() bool {
+		clk.Advance(latFlushEvery)
+		return atomic.LoadInt64(&r.Ops) >= want
+	})
+}
+
+func TestWithDeadlineFakeClock(t *testing.T) {
+	clk := newFakeClock(time.Unix(0, 0))
+	ctx, cancel := withDeadline(context.Background(), clk, time.Second)
+	defer cancel()
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+
+	clk.Advance(time.Second - 1)
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("Err before the deadline = %v", err)
+	}
+	clk.Advance(1)
+	waitDone(t, ctx)
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Err = %v, want context.DeadlineExceeded", err)
+	}
+	waitDone(t, child)
+	if err := child.Err(); err != context.DeadlineExceeded {
+		t.Errorf("child Err = %v, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel = withDeadline(context.Background(), clk, time.Second)
+	cancel()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("Err after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestStartFakeClock(t *testing.T) {
+	clk := newFakeClock(time.Unix(0, 0))
+	r := newResult("test", Phase{Concurrency: 1, Duration: time.Second, Clock: clk})
+	ctx, cancel := r.start(context.Background())
+	defer cancel()
+
+	addOps(t, r, clk, 3)
+	clk.Advance(time.Second - clk.Since(r.startedAt) - 1)
+	if ctx.Err() != nil || r.stopped() {
+		t.Fatal("phase ended before its duration")
+	}
+	clk.Advance(1)
+	waitDone(t, ctx)
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("Err = %v, want context.DeadlineExceeded", err)
+	}
+	waitFor(t, "the stop flag", r.stopped)
+
+	res := r.finalize()
+	if res.Duration != time.Second || res.Ops != 3 || res.Aborted {
+		t.Errorf("Duration, Ops, Aborted = %s, %d, %v; want 1s, 3, false", res.Duration, res.Ops, res.Aborted)
+	}
+}
+
+func TestWatchSamplesFakeClock(t *testing.T) {
+	phase := func(clk *fakeClock) Phase {
+		return Phase{Concurrency: 1, Duration: time.Second, MinSamples: 3, MaxDuration: 3 * time.Second, Clock: clk}
+	}
+	// the collector's ticker, the deadline and watchSamples' timer
+	const timers = 3
+
+	t.Run("enough at duration", func(t *testing.T) {
+		clk := newFakeClock(time.Unix(0, 0))
+		r := newResult("test", phase(clk))
+		ctx, cancel := r.start(context.Background())
+		defer cancel()
+		waitFor(t, "the timers", func() bool { return clk.pending() == timers })
+
+		addOps(t, r, clk, 3)
+		clk.Advance(time.Second - clk.Since(r.startedAt))
+		waitDone(t, ctx)
+		res := r.finalize()
+		if res.Duration != time.Second || res.Extension != 0 {
+			t.Errorf("Duration, Extension = %s, %s; want 1s, 0", res.Duration, res.Extension)
+		}
+	})
+
+	t.Run("extended until enough", func(t *testing.T) {
+		clk := newFakeClock(time.Unix(0, 0))
+		r := newResult("test", phase(clk))
+		ctx, cancel := r.start(context.Background())
+		defer cancel()
+		waitFor(t, "the timers", func() bool { return clk.pending() == timers })
+
+		clk.Advance(time.Second)
+		// watchSamples' timer is done, its ticker replaces it
+		waitFor(t, "the extension", func() bool { return clk.pending() == timers })
+		if ctx.Err() != nil {
+			t.Fatal("phase ended at its duration without min samples")
+		}
+		addOps(t, r, clk, 3)
+		waitFor(t, "the phase end", func() bool {
+			clk.Advance(sampleCheckEvery)
+			return ctx.Err() != nil
+		})
+		res := r.finalize()
+		if res.Extension <= 0 || res.Duration >= 3*time.Second || res.Aborted {
+			t.Errorf("Duration, Extension, Aborted = %s, %s, %v; want between 1s and 3s, not aborted", res.Duration, res.Extension, res.Aborted)
+		}
+		if res.Duration != clk.Since(r.startedAt) {
+			t.Errorf("Duration = %s, want the %s run", res.Duration, clk.Since(r.startedAt))
+		}
+	})
+
+	t.Run("short at max duration", func(t *testing.T) {
+		clk := newFakeClock(time.Unix(0, 0))
+		r := newResult("test", phase(clk))
+		ctx, cancel := r.start(context.Background())
+		defer cancel()
+		waitFor(t, "the timers", func() bool { return clk.pending() == timers })
+
+		clk.Advance(time.Second)
+		waitFor(t, "the extension", func() bool { return clk.pending() == timers })
+		clk.Advance(2 * time.Second)
+		waitDone(t, ctx)
+		if err := ctx.Err(); err != context.DeadlineExceeded {
+			t.Errorf("Err = %v, want context.DeadlineExceeded", err)
+		}
+		res := r.finalize()
+		if res.Duration != 3*time.Second || res.Extension != 2*time.Second {
+			t.Errorf("Duration, Extension = %s, %s; want 3s, 2s", res.Duration, res.Extension)
+		}
+	})
+}
+
+func TestOpTimeoutFakeClock(t *testing.T) {
+	clk := newFakeClock(time.Unix(0, 0))
+	r := newResult("test", Phase{Concurrency: 1, Duration: time.Second, OpTimeout: 100 * time.Millisecond, Clock: clk})
+	ctx, cancel := r.start(context.Background())
+	defer cancel()
+
+	// an operation outliving OpTimeout is a timeout
+	octx, done := r.op(ctx)
+	clk.Advance(100 * time.Millisecond)
+	waitDone(t, octx)
+	if err := done(octx.Err()); !isOpTimeout(err) {
+		t.Errorf("operation past OpTimeout: %v, want an opTimeoutError", err)
+	}
+
+	// one finishing in time is left alone
+	_, done = r.op(ctx)
+	clk.Advance(50 * time.Millisecond)
+	if err := done(nil); err != nil {
+		t.Errorf("operation within OpTimeout: %v", err)
+	}
+	wantErr := errors.New("failed")
+	_, done = r.op(ctx)
+	if err := done(wantErr); err != wantErr {
+		t.Errorf("failed operation: %v, want its error", err)
+	}
+
+	// one the phase end cuts short is canceled, not timed out
+	clk.Advance(time.Second - clk.Since(r.startedAt) - 50*time.Millisecond)
+	octx, done = r.op(ctx)
+	clk.Advance(50 * time.Millisecond)
+	waitDone(t, octx)
+	err := done(octx.Err())
+	if isOpTimeout(err) || !isCanceled(err) {
+		t.Errorf("operation cut short by the phase end: %v, want it canceled", err)
+	}
+	r.finalize()
+}
+
+// TestWithDeadlineTimingClocks checks that the timing modes, whose timers
+// are the wall clock's, get a stdlib deadline.
+func TestWithDeadlineTimingClocks(t *testing.T) {
+	for _, mode := range []string{"wall", "mono", "coarse"} {
+		clk, stop, err := timingClock(mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := withDeadline(context.Background(), clk, time.Hour)
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("%s: the deadline context has no Deadline", mode)
+		}
+		if _, ok := ctx.(*clockCtx); ok {
+			t.Errorf("%s: got a clockCtx", mode)
+		}
+		cancel()
+		stop()
+	}
+}
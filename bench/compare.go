@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Comparison is one workload present in both a baseline and a current
+// report. Significant is only meaningful when both sides carry raw
+// latency samples (see Config.Samples).
+type Comparison struct {
+	Workload    string
+	BaseOps     float64 // ops/s
+	CurOps      float64
+	Base, Cur   MedianCI
+	P           float64 // Mann-Whitney U two-sided p-value
+	HasSamples  bool
+	Significant bool
+}
+
+// MedianCI is a median latency with its 95% confidence interval.
+type MedianCI struct {
+	Median, Lo, Hi time.Duration
+}
+
+func newMedianCI(r Result) MedianCI {
+	if len(r.Samples) == 0 {
+		return MedianCI{Median: r.P50, Lo: r.P50, Hi: r.P50}
+	}
+	med, lo, hi := medianCI(r.Samples)
+	return MedianCI{med, lo, hi}
+}
+
+// Compare matches results by workload name and tests each pair's latency
+// samples at significance level alpha.
+func Compare(base, cur *Report, alpha float64) []Comparison {
+	byName := make(map[string]Result, len(base.Results))
+	for _, r := range base.Results {
+		byName[r.Workload] = r
+	}
+	var out []Comparison
+	for _, r := range cur.Results {
+		b, ok := byName[r.Workload]
+		if !ok || b.Skipped || r.Skipped {
+			continue
+		}
+		c := Comparison{
+			Workload:   r.Workload,
+			BaseOps:    opsPerSec(b),
+			CurOps:     opsPerSec(r),
+			Base:       newMedianCI(b),
+			Cur:        newMedianCI(r),
+			P:          1,
+			HasSamples: len(b.Samples) > 0 && len(r.Samples) > 0,
+		}
+		if c.HasSamples {
+			_, c.P = mannWhitneyU(b.Samples, r.Samples)
+			c.Significant = c.P < alpha
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func opsPerSec(r Result) float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Ops) / r.Duration.Seconds()
+}
+
+// verdict names the direction of a significant latency change and calls
+// everything else noise.
+func (c Comparison) verdict() string {
+	switch {
+	case !c.HasSamples:
+		return "untested (no samples)"
+	case !c.Significant:
+		return "noise"
+	case c.Cur.Median > c.Base.Median:
+		return "regression"
+	default:
+		return "improvement"
+	}
+}
+
+// ComparePretty renders comparisons one workload at a time, coloring
+// significant regressions red and improvements green when o.Color is set.
+func ComparePretty(cs []Comparison, o PrettyOptions) string {
+	pct := func(base, cur float64) string {
+		if base == 0 {
+			return "n/a"
+		}
+		return fmt.Sprintf("%+.1f%%", (cur-base)*100/base)
+	}
+	ci := func(m MedianCI) string {
+		return fmt.Sprintf("%s [%s, %s]", fDur(m.Median), fDur(m.Lo), fDur(m.Hi))
+	}
+	var b strings.Builder
+	for _, c := range cs {
+		fmt.Fprintf(&b, "Workload\t: %s\n", c.Workload)
+		fmt.Fprintf(&b, "Ops/s\t\t: %.1f -> %.1f (%s)\n", c.BaseOps, c.CurOps, pct(c.BaseOps, c.CurOps))
+		fmt.Fprintf(&b, "P50 (95%% CI)\t: %s -> %s\n", ci(c.Base), ci(c.Cur))
+		if c.HasSamples {
+			verdict := c.verdict()
+			switch verdict {
+			case "regression":
+				verdict = o.paint(ansiRed, verdict)
+			case "improvement":
+				verdict = o.paint(ansiGreen, verdict)
+			}
+			fmt.Fprintf(&b, "Mann-Whitney\t: p=%.4g -> %s\n\n", c.P, verdict)
+		} else {
+			fmt.Fprintf(&b, "Mann-Whitney\t: %s\n\n", c.verdict())
+		}
+	}
+	return o.finish(b.String())
+}
@@ -0,0 +1,133 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConflictStats is how the engine answered the conflict workload's
+// duplicate inserts: each should fail with an error classified as "unique".
+type ConflictStats struct {
+	Duplicates int64 `json:"duplicates"`
+	Detected   int64 `json:"detected"`            // rejected with a unique-violation error
+	Unclassed  int64 `json:"unclassed,omitempty"` // rejected with an error not recognized as one
+	Accepted   int64 `json:"accepted,omitempty"`  // not rejected at all
+}
+
+func (s ConflictStats) pretty() string {
+	out := fmt.Sprintf("%s/%s duplicates rejected as unique violations", commaI(s.Detected), commaI(s.Duplicates))
+	if s.Unclassed > 0 {
+		out += fmt.Sprintf(", %s with other errors", commaI(s.Unclassed))
+	}
+	if s.Accepted > 0 {
+		out += fmt.Sprintf(", %s ACCEPTED", commaI(s.Accepted))
+	}
+	return out
+}
+
+// conflictRecent is how many of its own keys a worker draws duplicates from.
+const conflictRecent = 256
+
+// conflictWorkload inserts single rows, conflictPct percent of them with a
+// key the worker inserted before. Duplicates are reported as
+// conflict/duplicate, timing how long the engine takes to detect the
+// conflict, and fresh keys as conflict/fresh; rejected duplicates are
+// expected and counted in Conflicts rather than Errors.
+func conflictWorkload(dollar bool, conflictPct int, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("conflict", ph)
+		fresh, duplicate := res.split("fresh"), res.split("duplicate")
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		stmt, err := db.PrepareContext(ctx, q)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer stmt.Close()
+		var dups, detected, unclassed, accepted atomic.Int64
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				var recent []string
+
+				for {
+					if res.stopped() {
+						return
+					}
+					dup := len(recent) > 0 && rnd.Intn(100) < conflictPct
+					var k string
+					if dup {
+						k = recent[rnd.Intn(len(recent))]
+					} else if k, err = gen.next(); err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					_, err = stmt.ExecContext(octx, k, pl.pick(rnd))
+					err = done(err)
+					release()
+					d := res.clock.Since(start)
+
+					if !dup {
+						if err != nil {
+							res.addErrorCnt(err)
+							continue
+						}
+						res.addLatency(worker, d)
+						fresh.addLatency(worker, d)
+						res.addInserted(1)
+						if len(recent) < conflictRecent {
+							recent = append(recent, k)
+						} else {
+							recent[rnd.Intn(conflictRecent)] = k
+						}
+						continue
+					}
+					switch {
+					case err == nil:
+						accepted.Add(1)
+						res.addInserted(1)
+					case isCanceled(err) || isOpTimeout(err):
+						res.addErrorCnt(err)
+						continue
+					case classifyError(err) == errUnique:
+						detected.Add(1)
+					default:
+						unclassed.Add(1)
+						res.log.Warn().Err(err).Msg("duplicate key rejected with an unrecognized error")
+					}
+					dups.Add(1)
+					res.addLatency(worker, d)
+					duplicate.addLatency(worker, d)
+				}
+			}(w)
+		}
+		wg.Wait()
+		res.Conflicts = &ConflictStats{Duplicates: dups.Load(), Detected: detected.Load(), Unclassed: unclassed.Load(), Accepted: accepted.Load()}
+		return res.finalize()
+	}
+}
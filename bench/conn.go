@@ -0,0 +1,152 @@
+package bench
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ConnOptions are connection settings for the pgx and MySQL-protocol
+// engines (mariadb, tidb) kept out of the DSN: TLS material and
+// credentials read from a file or the environment. Whatever is set
+// overrides the DSN.
+type ConnOptions struct {
+	CAFile     string // PEM bundle the server certificate is verified against; empty = system roots
+	CertFile   string // PEM client certificate for mutual TLS, with KeyFile
+	KeyFile    string
+	SkipVerify bool // encrypt without verifying the server certificate
+	User       string
+	// The password is read from PasswordFile (trailing newline trimmed),
+	// else from the environment variable PasswordEnv.
+	PasswordFile string
+	PasswordEnv  string
+}
+
+func (o ConnOptions) tls() bool {
+	return o.CAFile != "" || o.CertFile != "" || o.KeyFile != "" || o.SkipVerify
+}
+
+func (o ConnOptions) set() bool {
+	return o.tls() || o.User != "" || o.PasswordFile != "" || o.PasswordEnv != ""
+}
+
+// tlsConfig builds the client TLS config for a server named serverName.
+func (o ConnOptions) tlsConfig(serverName string) (*tls.Config, error) {
+	c := &tls.Config{ServerName: serverName, InsecureSkipVerify: o.SkipVerify}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("CA file: %w", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s holds no PEM certificates", o.CAFile)
+		}
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// password returns the injected password; ok is false when neither
+// PasswordFile nor PasswordEnv is set.
+func (o ConnOptions) password() (pw string, ok bool, err error) {
+	switch {
+	case o.PasswordFile != "":
+		b, err := os.ReadFile(o.PasswordFile)
+		if err != nil {
+			return "", false, fmt.Errorf("password file: %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	case o.PasswordEnv != "":
+		pw, ok := os.LookupEnv(o.PasswordEnv)
+		if !ok {
+			return "", false, fmt.Errorf("password variable %s is not set", o.PasswordEnv)
+		}
+		return pw, true, nil
+	}
+	return "", false, nil
+}
+
+// openDB opens dsn with cfg's database/sql engine, applying cfg.Conn.
+func openDB(cfg Config, dsn string) (*sql.DB, error) {
+	switch {
+	case !cfg.Conn.set():
+	case cfg.Engine == "pgx":
+		return openPgx(dsn, cfg.Conn)
+	case cfg.Engine == "mariadb", cfg.Engine == "tidb":
+		return openMySQL(dsn, cfg.Conn)
+	default:
+		return nil, fmt.Errorf("TLS and credential options are not supported with %s", cfg.Engine)
+	}
+	if cfg.Engine == "generic" {
+		return openGeneric(cfg.Driver, dsn)
+	}
+	return Open(cfg.Engine, dsn)
+}
+
+func openPgx(dsn string, o ConnOptions) (*sql.DB, error) {
+	c, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if o.User != "" {
+		c.User = o.User
+	}
+	if pw, ok, err := o.password(); err != nil {
+		return nil, err
+	} else if ok {
+		c.Password = pw
+	}
+	if o.tls() {
+		// every host the DSN lists, with no plaintext fallback as
+		// sslmode=prefer would allow
+		if c.TLSConfig, err = o.tlsConfig(c.Host); err != nil {
+			return nil, err
+		}
+		for _, fb := range c.Fallbacks {
+			if fb.TLSConfig, err = o.tlsConfig(fb.Host); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stdlib.OpenDB(*c), nil
+}
+
+func openMySQL(dsn string, o ConnOptions) (*sql.DB, error) {
+	c, err := mysqlConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if o.User != "" {
+		c.User = o.User
+	}
+	if pw, ok, err := o.password(); err != nil {
+		return nil, err
+	} else if ok {
+		c.Passwd = pw
+	}
+	if o.tls() {
+		// the driver fills in the server name from the address
+		if c.TLS, err = o.tlsConfig(""); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := mysql.NewConnector(c)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(conn), nil
+}
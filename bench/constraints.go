@@ -0,0 +1,178 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ConstraintVariants are the kv_cons variants the constraints experiment
+// can measure against the plain table: CHECK constraints on the inserted
+// columns, and a trigger writing every inserted key to an audit table.
+var ConstraintVariants = []string{"check", "trigger"}
+
+const consCheck = `CONSTRAINT kv_cons_check CHECK (n >= 0 AND n < 1000000000 AND s <> '')`
+
+// consDDL (re)creates kv_cons and its audit table as variant ("plain" or
+// one of ConstraintVariants) for engine. Chai has CHECK constraints but no
+// triggers; TiDB and ClickHouse have no triggers either.
+func consDDL(engine, variant string) ([]string, error) {
+	var key, blob, integer, text, suffix string
+	switch engine {
+	case "sqlite":
+		key, blob, integer, text = "TEXT", "BLOB", "INTEGER", "TEXT"
+	case "chai":
+		key, blob, integer, text = "TEXT", "BLOB", "BIGINT", "TEXT"
+	case "pgx":
+		key, blob, integer, text = "TEXT", "BYTEA", "BIGINT", "TEXT"
+	case "mariadb", "tidb":
+		key, blob, integer, text = "VARCHAR(255)", "LONGBLOB", "BIGINT", "TEXT"
+	case "clickhouse":
+		key, blob, integer, text, suffix = "String", "String", "Int64", "String", " ENGINE = MergeTree ORDER BY k"
+	default:
+		return nil, &errSkip{"no kv_cons schema for " + engine}
+	}
+	pk := " PRIMARY KEY"
+	if engine == "clickhouse" {
+		pk = ""
+	}
+
+	cols := fmt.Sprintf("k %s%s, v %s NOT NULL, n %s NOT NULL, s %s NOT NULL", key, pk, blob, integer, text)
+	if variant == "check" {
+		cols += ", " + consCheck
+	}
+	stmts := []string{
+		`DROP TABLE IF EXISTS kv_cons`,
+		`DROP TABLE IF EXISTS kv_cons_audit`,
+		fmt.Sprintf(`CREATE TABLE kv_cons (%s)%s`, cols, suffix),
+	}
+	if variant != "trigger" {
+		return stmts, nil
+	}
+
+	stmts = append(stmts, fmt.Sprintf(`CREATE TABLE kv_cons_audit (k %s NOT NULL)`, key))
+	switch engine {
+	case "sqlite":
+		stmts = append(stmts, `CREATE TRIGGER kv_cons_audit AFTER INSERT ON kv_cons BEGIN INSERT INTO kv_cons_audit(k) VALUES (NEW.k); END`)
+	case "pgx":
+		stmts = append(stmts,
+			`CREATE OR REPLACE FUNCTION kv_cons_audit() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN INSERT INTO kv_cons_audit(k) VALUES (NEW.k); RETURN NEW; END $$`,
+			`CREATE TRIGGER kv_cons_audit AFTER INSERT ON kv_cons FOR EACH ROW EXECUTE FUNCTION kv_cons_audit()`)
+	case "mariadb":
+		stmts = append(stmts, `CREATE TRIGGER kv_cons_audit AFTER INSERT ON kv_cons FOR EACH ROW INSERT INTO kv_cons_audit(k) VALUES (NEW.k)`)
+	default:
+		return nil, &errSkip{engine + " has no triggers"}
+	}
+	return stmts, nil
+}
+
+// constraintPhases is the constraints experiment: batched inserts into
+// kv_cons as a plain table (constraints-plain), then once per variant
+// (constraints-<variant>) with a Delta against the plain run, the write
+// penalty of evaluating the constraint or firing the trigger. Every phase
+// starts from a freshly created, empty kv_cons.
+func constraintPhases(engine string, variants []string, batch int, kg keyGens, pl payloads) ([]phaseSpec, error) {
+	for _, v := range variants {
+		if !slices.Contains(ConstraintVariants, v) {
+			return nil, fmt.Errorf("unknown constraint variant %q (%v)", v, ConstraintVariants)
+		}
+	}
+	var plain *Result
+	build := func(variant string) func(s *suite) (WorkloadFunc, error) {
+		return func(s *suite) (WorkloadFunc, error) {
+			stmts, err := consDDL(engine, variant)
+			if err != nil {
+				return nil, err
+			}
+			wf := consInsertWorkload(dollarPlaceholders(s.cfg), "constraints-"+variant, batch, kg, pl)
+			return func(ctx context.Context, db Executor, ph Phase) Result {
+				// warmup and measured run each start from an empty table
+				for _, q := range stmts {
+					if _, err := db.ExecContext(ctx, q); err != nil {
+						res := newResult("constraints-"+variant, ph)
+						res.logError(err, "failed to create kv_cons")
+						res.addErrorCnt(err)
+						return res.finalize()
+					}
+				}
+				res := wf(ctx, db, ph)
+				switch {
+				case ph.Warmup:
+				case variant == "plain":
+					plain = &res
+				case plain != nil && !plain.Aborted:
+					res.Delta = newDelta(*plain, res)
+				}
+				return res
+			}, nil
+		}
+	}
+	phases := []phaseSpec{{"constraints-plain", build("plain")}}
+	for _, v := range variants {
+		phases = append(phases, phaseSpec{"constraints-" + v, build(v)})
+	}
+	return phases, nil
+}
+
+// consInsertWorkload is the insert workload on kv_cons, whose extra columns
+// satisfy the CHECK constraint.
+func consInsertWorkload(dollar bool, name string, batch int, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv_cons(k, v, n, s) VALUES(?, ?, ?, ?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+
+				var lat []time.Duration // the batch's, counted once it commits
+				res.insertTxs(ctx, db, "", batch, insertBatch{
+					row: func(tx *sql.Tx, _ *sql.Stmt, _ int) bool {
+						k, err := gen.next()
+						if err != nil {
+							res.addErrorCnt(err)
+							return false
+						}
+						n := rnd.Int63n(1000000000)
+						start := res.clock.Now()
+						octx, done := res.op(ctx)
+						_, err = tx.ExecContext(octx, q, k, pl.pick(rnd), n, strconv.FormatInt(n, 36))
+						if err = done(err); err != nil {
+							res.addErrorCnt(err)
+							return true
+						}
+						lat = append(lat, res.clock.Since(start))
+						return false
+					},
+					ended: func(committed bool) {
+						if committed {
+							for _, d := range lat {
+								res.addLatency(worker, d)
+							}
+						}
+						lat = lat[:0]
+					},
+				})
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
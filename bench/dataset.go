@@ -0,0 +1,213 @@
+package bench
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Dataset is a user-provided CSV or JSONL file loaded into a table before the
+// suite runs, so reads operate on realistic keys and values.
+type Dataset struct {
+	Path    string
+	Format  string   // csv|jsonl; inferred from the extension when empty
+	Table   string   // target table; default kv
+	Columns []string // target columns; default k, v
+	Fields  []string // CSV header names / JSON keys feeding Columns; default Columns
+	Limit   int      // max rows to load; 0 loads everything
+}
+
+func (d Dataset) withDefaults() Dataset {
+	if d.Format == "" {
+		switch strings.ToLower(filepath.Ext(d.Path)) {
+		case ".jsonl", ".ndjson":
+			d.Format = "jsonl"
+		default:
+			d.Format = "csv"
+		}
+	}
+	if d.Table == "" {
+		d.Table = "kv"
+	}
+	if len(d.Columns) == 0 {
+		d.Columns = []string{"k", "v"}
+	}
+	if len(d.Fields) == 0 {
+		d.Fields = d.Columns
+	}
+	return d
+}
+
+// loadDataset streams d into its table and returns the number of rows loaded.
+func loadDataset(ctx context.Context, db Executor, dollar bool, d Dataset) (int, error) {
+	d = d.withDefaults()
+	if len(d.Fields) != len(d.Columns) {
+		return 0, fmt.Errorf("dataset: %d fields for %d columns", len(d.Fields), len(d.Columns))
+	}
+
+	f, err := os.Open(d.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var next func() ([]any, error)
+	switch d.Format {
+	case "csv":
+		next, err = csvRows(f, d.Fields)
+	case "jsonl":
+		next = jsonlRows(f, d.Fields)
+	default:
+		err = fmt.Errorf("dataset: unknown format %q", d.Format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// kv.v is a blob column; hand it bytes so every driver binds it as such
+	blobCol := -1
+	if d.Table == "kv" {
+		for i, c := range d.Columns {
+			if c == "v" {
+				blobCol = i
+			}
+		}
+	}
+	// loadRows asks for the next row only once the last one is inserted,
+	// so that is when it counts as loaded
+	loaded, pending := 0, false
+	limited := func() ([]any, error) {
+		if pending {
+			loaded++
+			pending = false
+		}
+		if d.Limit > 0 && loaded >= d.Limit {
+			return nil, io.EOF
+		}
+		row, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if blobCol >= 0 {
+			if s, ok := row[blobCol].(string); ok {
+				row[blobCol] = []byte(s)
+			}
+		}
+		pending = true
+		return row, nil
+	}
+
+	q := fmt.Sprintf(`INSERT INTO %s(%s) VALUES(%s)`, d.Table, strings.Join(d.Columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(d.Columns)), ", "))
+	log.Info().Str("path", d.Path).Str("table", d.Table).Msg("loading dataset")
+	if err := loadRows(ctx, db, bind(dollar, q), limited); err != nil {
+		return loaded, fmt.Errorf("dataset %s row %d: %w", d.Path, loaded+1, err)
+	}
+	log.Info().Int("rows", loaded).Msg("dataset loaded")
+	return loaded, nil
+}
+
+func csvRows(r io.Reader, fields []string) (func() ([]any, error), error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("dataset: read csv header: %w", err)
+	}
+	idx := make([]int, len(fields))
+	for i, f := range fields {
+		idx[i] = -1
+		for j, h := range header {
+			if strings.TrimSpace(h) == f {
+				idx[i] = j
+			}
+		}
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("dataset: csv has no column %q", f)
+		}
+	}
+	return func() ([]any, error) {
+		rec, err := cr.Read()
+		if err != nil {
+			return nil, err
+		}
+		row := make([]any, len(idx))
+		for i, j := range idx {
+			row[i] = rec[j]
+		}
+		return row, nil
+	}, nil
+}
+
+func jsonlRows(r io.Reader, fields []string) func() ([]any, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+	return func() ([]any, error) {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return nil, err
+		}
+		row := make([]any, len(fields))
+		for i, f := range fields {
+			switch v := obj[f].(type) {
+			case nil, string, bool:
+				row[i] = v
+			case json.Number:
+				row[i] = v.String()
+			default: // nested objects and arrays are stored as JSON text
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				row[i] = string(b)
+			}
+		}
+		return row, nil
+	}
+}
+
+// loadRows inserts the rows produced by next, loadBatch per transaction,
+// until next returns io.EOF.
+func loadRows(ctx context.Context, db Executor, q string, next func() ([]any, error)) error {
+	const loadBatch = 10000
+	for done := false; !done; {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		for range loadBatch {
+			args, err := next()
+			if errors.Is(err, io.EOF) {
+				done = true
+				break
+			}
+			if err == nil {
+				_, err = stmt.ExecContext(ctx, args...)
+			}
+			if err != nil {
+				_ = stmt.Close()
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		_ = stmt.Close()
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	deadlockPairs = 4                    // hot key pairs shared by all workers
+	deadlockHold  = 2 * time.Millisecond // pause between the two updates, widening the window
+)
+
+// deadlockWorkload has even workers update a pair of keys as (a, b) and
+// odd workers as (b, a) inside one transaction, so concurrent transactions
+// on the same pair wait on each other. Engines with a deadlock detector
+// abort one side: the time from BEGIN to that error is reported in
+// Result.Deadlock and the errors show up as the deadlock error kind.
+// Engines that serialize writers report busy/locked errors or plain waits
+// instead. Needs concurrency >= 2.
+func deadlockWorkload(dollar bool, keys []string, pl payloads) WorkloadFunc {
+	q := bind(dollar, `UPDATE kv SET v = ? WHERE k = ?`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("deadlock", ph)
+		if len(keys) < 2 {
+			return res.finalize()
+		}
+		pairs := min(deadlockPairs, len(keys)/2)
+
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					p := rnd.Intn(pairs)
+					first, second := keys[2*p], keys[2*p+1]
+					if worker%2 == 1 {
+						first, second = second, first
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					err = done(deadlockTx(octx, db, q, first, second, pl.pick(rnd)))
+					release()
+					if err != nil {
+						if classifyError(err) == errDeadlock {
+							res.addDeadlock(res.clock.Since(start))
+						}
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+func deadlockTx(ctx context.Context, db Executor, q, first, second string, v []byte) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, q, v, first); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		_ = tx.Rollback()
+		return ctx.Err()
+	case <-time.After(deadlockHold):
+	}
+	if _, err := tx.ExecContext(ctx, q, v, second); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
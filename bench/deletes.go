@@ -0,0 +1,159 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// deleteRangeSpan is how many snapshot keys one range delete spans.
+const deleteRangeSpan = 100
+
+// deleteStrategies are the ways the delete workload removes rows; they
+// differ by orders of magnitude across engines.
+//
+//	key:   one DELETE per random snapshot key (phase "delete")
+//	range: DELETE ... WHERE k BETWEEN over deleteRangeSpan snapshot keys
+//	all:   one TRUNCATE, or DELETE of every row where there is no TRUNCATE
+//	drop:  DROP TABLE kv and recreate it from the schema
+var deleteStrategies = []string{"key", "range", "all", "drop"}
+
+func validDeleteStrategy(s string) error {
+	for _, v := range deleteStrategies {
+		if s == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown delete strategy %q (key|range|all|drop)", s)
+}
+
+// deletePhaseName names the phase of strategy; key keeps the historical
+// "delete".
+func deletePhaseName(strategy string) string {
+	if strategy == "key" {
+		return "delete"
+	}
+	return "delete-" + strategy
+}
+
+// deleteRangeWorkload deletes ranges of deleteRangeSpan snapshot keys, one
+// statement per operation. Ranges already deleted delete nothing, which
+// RowsDeleted shows.
+func deleteRangeWorkload(dollar bool, keys []string) WorkloadFunc {
+	q := bind(dollar, `DELETE FROM kv WHERE k BETWEEN ? AND ?`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("delete-range", ph)
+		res.rows.untracked = true // which rows a range held is unknown
+		if len(keys) == 0 {
+			return res.finalize()
+		}
+
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		stmt, err := db.PrepareContext(ctx, q)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer stmt.Close()
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					i := rnd.Intn(len(keys))
+					lo, hi := keys[i], keys[min(i+deleteRangeSpan, len(keys)-1)]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					r, err := stmt.ExecContext(octx, lo, hi)
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+					if n, err := r.RowsAffected(); err == nil {
+						res.addRowsDeleted(n)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+// deleteAllWorkload empties kv with a single statement, timed once; the
+// phase lasts as long as that statement. The warmup run does nothing, as
+// it would leave nothing to measure.
+func deleteAllWorkload(engine, strategy string) WorkloadFunc {
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(deletePhaseName(strategy), ph)
+		res.rows.untracked = true
+		if ph.Warmup {
+			return res.finalize()
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		start := res.clock.Now()
+		var err error
+		if strategy == "drop" {
+			err = dropKV(ctx, db, engine)
+		} else {
+			err = truncateKV(ctx, db, engine, res)
+		}
+		took := res.clock.Since(start)
+		if err != nil {
+			res.addErrorCnt(err)
+		} else {
+			res.addLatency(0, took)
+		}
+		res.Concurrency, res.Duration = 1, took
+		return res.finalize()
+	}
+}
+
+// truncateKV removes every row of kv, by TRUNCATE where the engine has it.
+func truncateKV(ctx context.Context, db Executor, engine string, res *Result) error {
+	switch engine {
+	case "pgx", "mariadb", "tidb", "clickhouse":
+		_, err := db.ExecContext(ctx, `TRUNCATE TABLE kv`)
+		return err
+	}
+	r, err := db.ExecContext(ctx, `DELETE FROM kv`)
+	if err != nil {
+		return err
+	}
+	if n, err := r.RowsAffected(); err == nil {
+		res.addRowsDeleted(n)
+	}
+	return nil
+}
+
+// dropKV drops kv and recreates it, with its indexes, from the schema.
+func dropKV(ctx context.Context, db Executor, engine string) error {
+	if _, err := db.ExecContext(ctx, `DROP TABLE kv`); err != nil {
+		return err
+	}
+	return initSchema(ctx, db, engine)
+}
@@ -0,0 +1,183 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+	"github.com/rs/zerolog/log"
+)
+
+// docSet is the pool of person documents the doc workloads store: docs
+// for the query values, raw for what is written.
+type docSet struct {
+	docs []fakeDoc
+	raw  []string
+}
+
+// newDocSet generates n distinct documents, the same ones on every run.
+func newDocSet(n int) (docSet, error) {
+	f := gofakeit.New(payloadSeed)
+	s := docSet{docs: make([]fakeDoc, max(1, n)), raw: make([]string, max(1, n))}
+	for i := range s.docs {
+		s.docs[i] = fakePerson(f)
+		b, err := json.Marshal(s.docs[i])
+		if err != nil {
+			return docSet{}, err
+		}
+		s.raw[i] = string(b)
+	}
+	return s, nil
+}
+
+// docSchema creates kv_doc, whose doc column is the engine's document
+// type. ClickHouse is left out: its JSON type is still experimental.
+func docSchema(engine string) (string, error) {
+	if engine == "clickhouse" {
+		return "", &errSkip{"no document schema for clickhouse"}
+	}
+	return schemaFor(engine, embed.DocPgSchema, embed.DocSqliteSchema, embed.DocChaiSchema, embed.DocMysqlSchema, "")
+}
+
+// docParam is the placeholder for a JSON text document: chai parses it
+// into an object, the others take the text as is.
+func docParam(engine string) string {
+	if engine == "chai" {
+		return "CAST(? AS OBJECT)"
+	}
+	return "?"
+}
+
+// docField extracts the text at path from the doc column, e.g. address,
+// city: json_extract, the -> operators, JSON_EXTRACT or a chai path.
+func docField(engine string, path ...string) string {
+	switch engine {
+	case "pgx":
+		expr := "doc"
+		for i, p := range path {
+			op := "->"
+			if i == len(path)-1 {
+				op = "->>"
+			}
+			expr += op + "'" + p + "'"
+		}
+		return expr
+	case "mariadb", "tidb":
+		// MariaDB has no ->> operator
+		return "JSON_UNQUOTE(JSON_EXTRACT(doc, '$." + strings.Join(path, ".") + "'))"
+	case "chai":
+		return "doc." + strings.Join(path, ".")
+	}
+	return "json_extract(doc, '$." + strings.Join(path, ".") + "')"
+}
+
+// loadDocs fills kv_doc with rows documents, cycling through set, unless
+// it already has as many rows.
+func loadDocs(ctx context.Context, db Executor, engine string, dollar bool, rows int, set docSet) error {
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM kv_doc`).Scan(&n); err != nil {
+		return err
+	}
+	if n == rows {
+		log.Info().Int("rows", rows).Msg("reusing doc dataset")
+		return nil
+	}
+
+	log.Info().Int("rows", rows).Msg("loading doc dataset")
+	if _, err := db.ExecContext(ctx, `DELETE FROM kv_doc`); err != nil {
+		return err
+	}
+	i := 0
+	q := bind(dollar, `INSERT INTO kv_doc(k, doc) VALUES(?, `+docParam(engine)+`)`)
+	return loadRows(ctx, db, q, func() ([]any, error) {
+		if i == rows {
+			return nil, io.EOF
+		}
+		i++
+		return []any{fmt.Sprintf("doc-%09d", i), set.raw[i%len(set.raw)]}, nil
+	})
+}
+
+// docQueries look documents up by a top-level and by a nested field, each
+// value matching rows/len(set.docs) documents. Nothing indexes the fields,
+// so every query extracts them from every document.
+func docQueries(engine string, set docSet) []readQuery {
+	pick := func(rnd *rand.Rand) fakeDoc { return set.docs[rnd.Intn(len(set.docs))] }
+	return []readQuery{
+		{`SELECT k FROM kv_doc WHERE ` + docField(engine, "username") + ` = ?`, func(rnd *rand.Rand) []any {
+			return []any{pick(rnd).Username}
+		}},
+		{`SELECT k, ` + docField(engine, "email") + ` FROM kv_doc WHERE ` + docField(engine, "address", "city") + ` = ?`, func(rnd *rand.Rand) []any {
+			return []any{pick(rnd).Address.City}
+		}},
+	}
+}
+
+// docInsertWorkload inserts documents of set under generated keys, one per
+// statement, so against insert it shows what parsing and storing the
+// document type costs.
+func docInsertWorkload(engine string, dollar bool, set docSet, kg keyGens) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv_doc(k, doc) VALUES(?, `+docParam(engine)+`)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("doc-insert", ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		stmt, err := db.PrepareContext(ctx, q)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer stmt.Close()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					k, err := gen.next()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					_, err = stmt.ExecContext(octx, k, set.raw[rnd.Intn(len(set.raw))])
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
@@ -0,0 +1,194 @@
+package bench
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	_ "github.com/ClickHouse/clickhouse-go" // "clickhouse"
+	_ "github.com/chaisql/chai/driver"      // "chai"
+	_ "github.com/glebarez/go-sqlite"       // "sqlite"
+	"github.com/go-sql-driver/mysql"        // "mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"      // "pgx"
+)
+
+// engineAliases maps every accepted engine spelling to its canonical name,
+// which is also the database/sql driver name except for the MySQL-protocol
+// profiles mariadb and tidb. Those share the MySQL dialect schemas and the
+// mysql driver, and differ in default DSN and in the errors they expect
+// clients to retry (see classifyError). Everything past NormalizeEngine
+// compares against canonical names only.
+var engineAliases = map[string]string{
+	"chai":        "chai",
+	"chaisql":     "chai",
+	"chai-native": "chai-native",
+	"badger":      "badger",
+	"badgerdb":    "badger",
+	"pebble":      "pebble",
+	"bbolt":       "bbolt",
+	"bolt":        "bbolt",
+	"boltdb":      "bbolt",
+	"sqlite":      "sqlite",
+	"sqlite3":     "sqlite",
+	"go-sqlite":   "sqlite",
+	"pgx":         "pgx",
+	"pg":          "pgx",
+	"postgres":    "pgx",
+	"postgresql":  "pgx",
+	"mariadb":     "mariadb",
+	"maria":       "mariadb",
+	"tidb":        "tidb",
+	"clickhouse":  "clickhouse",
+	"ch":          "clickhouse",
+	"generic":     "generic",
+}
+
+// dollarEngines are the engines whose drivers only accept numbered ($n)
+// parameters; see bind. The generic engine's style comes from
+// Config.Placeholder instead (see dollarPlaceholders).
+var dollarEngines = map[string]bool{"pgx": true}
+
+// dollarPlaceholders reports whether cfg's queries take numbered ($n)
+// parameters.
+func dollarPlaceholders(cfg Config) bool {
+	if cfg.Engine == "generic" {
+		return cfg.Placeholder == "dollar"
+	}
+	return dollarEngines[cfg.Engine]
+}
+
+// NormalizeEngine returns the canonical name of engine (chai, chai-native,
+// badger, pebble, bbolt, sqlite, pgx, mariadb, tidb, clickhouse or
+// generic), accepting the aliases in engineAliases in any case.
+func NormalizeEngine(engine string) (string, error) {
+	if e, ok := engineAliases[strings.ToLower(strings.TrimSpace(engine))]; ok {
+		return e, nil
+	}
+	return "", fmt.Errorf("unknown engine: %s", engine)
+}
+
+func Open(engine, dsn string) (*sql.DB, error) {
+	e, err := NormalizeEngine(engine)
+	if err != nil {
+		return nil, err
+	}
+	switch e {
+	case "chai-native":
+		return nil, fmt.Errorf("%s uses chai's Go API, not database/sql", e)
+	case "badger", "pebble", "bbolt":
+		return nil, fmt.Errorf("%s is a key-value store, not database/sql", e)
+	case "generic":
+		return nil, fmt.Errorf("%s needs a driver name, see openGeneric", e)
+	case "chai":
+		p := strings.TrimPrefix(dsn, "file:")
+		if p != "" && p != "." {
+			_ = os.MkdirAll(filepath.Dir(filepath.FromSlash(p)), 0755)
+		}
+		return sql.Open(e, p)
+	case "sqlite":
+		p := strings.TrimPrefix(dsn, "file:")
+		if p != "" && p != "." {
+			_ = os.MkdirAll(filepath.Dir(filepath.FromSlash(p)), 0755)
+		}
+		return sql.Open(e, dsn)
+	case "mariadb", "tidb":
+		c, err := mysqlConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.Open("mysql", c.FormatDSN())
+	}
+	return sql.Open(e, dsn)
+}
+
+// mysqlConfig parses the DSN of a MySQL-protocol engine. Schemas are
+// executed as one multi-statement Exec and typed workloads scan DATETIME
+// columns into time.Time.
+func mysqlConfig(dsn string) (*mysql.Config, error) {
+	c, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.MultiStatements = true
+	c.ParseTime = true
+	return c, nil
+}
+
+// openGeneric opens dsn with any database/sql driver registered in this
+// binary, e.g. one added through a blank import in a custom build.
+func openGeneric(driver, dsn string) (*sql.DB, error) {
+	if driver == "" {
+		return nil, fmt.Errorf("generic engine needs a driver name")
+	}
+	if drivers := sql.Drivers(); !slices.Contains(drivers, driver) {
+		return nil, fmt.Errorf("driver %q is not registered (registered: %s)", driver, strings.Join(drivers, ", "))
+	}
+	return sql.Open(driver, dsn)
+}
+
+// dataPath returns the on-disk database file (or directory, for key-value
+// stores) of embedded engines, or "" when the engine is a server or the
+// DSN does not name a file.
+func dataPath(engine, dsn string) string {
+	switch engine {
+	case "badger", "pebble", "bbolt":
+		p := strings.TrimPrefix(dsn, "file:")
+		if p == "" || p == "." {
+			return ""
+		}
+		return filepath.FromSlash(p)
+	case "chai", "chai-native", "sqlite":
+		p, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+		if p == "" || p == "." || p == ":memory:" {
+			return ""
+		}
+		return filepath.FromSlash(p)
+	}
+	return ""
+}
+
+var (
+	// scheme://user:password@ (postgres URLs, ClickHouse, ...); the
+	// password runs to the last @ before the query string
+	urlPassword = regexp.MustCompile(`(://[^:/@]*:)[^?]*@`)
+	// user:password@tcp(...)/db (go-sql-driver/mysql); like the driver,
+	// the password runs to the last @ before the last /
+	mysqlPassword = regexp.MustCompile(`^([^:/@?=\s]+:).*@(.*/)`)
+	// password=... in query strings and libpq key=value DSNs
+	paramPassword = regexp.MustCompile(`(?i)\b((?:ssl)?password|passwd|pwd)=('[^']*'|[^&;\s]*)`)
+)
+
+// SanitizeDSN masks the passwords in dsn so it can be logged and written
+// to reports. Everything that outputs a DSN goes through it.
+func SanitizeDSN(dsn string) string {
+	const mask = "xxxxx"
+	dsn = urlPassword.ReplaceAllString(dsn, "${1}"+mask+"@")
+	if !strings.Contains(dsn, "://") {
+		dsn = mysqlPassword.ReplaceAllString(dsn, "${1}"+mask+"@${2}")
+	}
+	return paramPassword.ReplaceAllString(dsn, "${1}="+mask)
+}
+
+// bind rewrites ? placeholders to $1, $2, ... when dollar is set, for
+// engines that only accept numbered parameters (see dollarPlaceholders).
+// Queries must not contain literal question marks.
+func bind(dollar bool, q string) string {
+	if !dollar {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
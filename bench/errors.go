@@ -0,0 +1,96 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai"
+	"github.com/go-sql-driver/mysql"
+)
+
+// errClass buckets operation errors so engine-specific failure modes (lock
+// contention, constraint violations, ...) are visible instead of being
+// folded into a single error count.
+type errClass int
+
+const (
+	errOther     errClass = iota
+	errBusy               // SQLITE_BUSY: another connection holds the write lock
+	errLocked             // SQLITE_LOCKED: shared-cache table lock, busy_timeout does not apply
+	errDeadlock           // postgres 40P01, MySQL 1213: the deadlock detector aborted the transaction
+	errRetryable          // MySQL 1205 lock wait timeout, TiDB 8002/8022/9007 write conflicts: retry the transaction
+	errTimeout            // the operation outlived Phase.OpTimeout
+	errUnique             // duplicate primary or unique key: sqlite UNIQUE, postgres 23505, MySQL 1062, chai PRIMARY KEY/UNIQUE
+	numErrClasses
+)
+
+var errClassNames = [numErrClasses]string{
+	errOther:     "other",
+	errBusy:      "busy",
+	errLocked:    "locked",
+	errDeadlock:  "deadlock",
+	errRetryable: "retryable",
+	errTimeout:   "timeout",
+	errUnique:    "unique",
+}
+
+func (c errClass) String() string { return errClassNames[c] }
+
+func classifyError(err error) errClass {
+	if isOpTimeout(err) {
+		return errTimeout
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "SQLITE_BUSY"), strings.Contains(msg, "database is locked"):
+		return errBusy
+	case strings.Contains(msg, "SQLITE_LOCKED"), strings.Contains(msg, "database table is locked"):
+		return errLocked
+	case strings.Contains(msg, "40P01"), strings.Contains(msg, "deadlock detected"):
+		return errDeadlock
+	case strings.Contains(msg, "UNIQUE constraint failed"), strings.Contains(msg, "23505"),
+		strings.Contains(msg, "PRIMARY KEY constraint error"), strings.Contains(msg, "UNIQUE constraint error"):
+		return errUnique
+	}
+	if chai.IsAlreadyExistsError(err) {
+		return errUnique
+	}
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		switch me.Number {
+		case 1213:
+			return errDeadlock
+		case 1205, 8002, 8022, 9007:
+			return errRetryable
+		case 1062:
+			return errUnique
+		}
+	}
+	return errOther
+}
+
+// opTimeoutError replaces the error of an operation that ran into its own
+// deadline (Phase.OpTimeout). It does not wrap the context error, so the
+// operation counts as a timeout rather than as canceled by the phase end.
+type opTimeoutError struct{ after time.Duration }
+
+func (e *opTimeoutError) Error() string { return "operation timed out after " + e.after.String() }
+
+func isOpTimeout(err error) bool {
+	var te *opTimeoutError
+	return errors.As(err, &te)
+}
+
+// isCanceled reports whether err only says the operation's context ended
+// (phase deadline, error-budget abort), i.e. the operation was cut short
+// rather than failed by the engine. Not every driver wraps the context
+// error, so the message is checked as well.
+func isCanceled(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "context canceled") || strings.Contains(msg, "context deadline exceeded")
+}
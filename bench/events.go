@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Event is one line of the run's event log: a lifecycle step (schema init,
+// phase start/end, key snapshot, abort, ...) with a wall-clock timestamp
+// that lines up with the per-second timeline of the results.
+type Event struct {
+	Time     time.Time      `json:"time"`
+	RunID    string         `json:"run_id"`
+	Type     string         `json:"type"`
+	Workload string         `json:"workload,omitempty"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
+// eventLog appends events to a JSONL file. A nil *eventLog discards them,
+// so callers never check whether the log is enabled.
+type eventLog struct {
+	mu    sync.Mutex
+	f     *os.File
+	enc   *json.Encoder
+	runID string
+}
+
+func openEventLog(path, runID string) (*eventLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLog{f: f, enc: json.NewEncoder(f), runID: runID}, nil
+}
+
+func (l *eventLog) emit(typ, workload string, fields map[string]any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ev := Event{Time: time.Now(), RunID: l.runID, Type: typ, Workload: workload, Fields: fields}
+	if err := l.enc.Encode(ev); err != nil {
+		log.Warn().Err(err).Str("event", typ).Msg("failed to write event")
+	}
+}
+
+func (l *eventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,156 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Executor is the part of *sql.DB the workloads use, so they can run
+// against a fake (see fakeExecutor) as well as a database.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+var _ Executor = (*sql.DB)(nil)
+
+// fakeExecutor stands in for a database when exercising the workload
+// scheduling, metrics and error accounting: every statement (and commit)
+// takes Latency, then fails with Fail's error, if any, or succeeds; queries
+// return Rows rows of Columns. database/sql types such as *sql.Rows cannot
+// be built by hand, so the fake is a driver under a real *sql.DB.
+type fakeExecutor struct {
+	Latency time.Duration
+	Fail    func(query string) error // nil never fails
+	Rows    int
+	Columns []string // k is a key, anything else a value; nil = k, v
+	// Trace, if set, sees every statement as it starts, COMMIT and
+	// ROLLBACK included.
+	Trace func(query string)
+
+	// Statements counts the statements run, commits included.
+	Statements atomic.Int64
+}
+
+// newFakeExecutor returns the Executor backed by f.
+func newFakeExecutor(f *fakeExecutor) Executor { return sql.OpenDB(f) }
+
+func (f *fakeExecutor) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeExecutor) Driver() driver.Driver                        { return f }
+func (f *fakeExecutor) Open(string) (driver.Conn, error)             { return fakeConn{f}, nil }
+
+// run simulates one statement.
+func (f *fakeExecutor) run(ctx context.Context, query string) error {
+	f.Statements.Add(1)
+	if f.Trace != nil {
+		f.Trace(query)
+	}
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if f.Fail != nil {
+		return f.Fail(query)
+	}
+	return nil
+}
+
+type fakeConn struct{ f *fakeExecutor }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.f, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{c.f}, nil }
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{c.f}, nil
+}
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	return fakeStmt{c.f, query}.ExecContext(ctx, nil)
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return fakeStmt{c.f, query}.QueryContext(ctx, nil)
+}
+
+type fakeStmt struct {
+	f     *fakeExecutor
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), nil)
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), nil)
+}
+
+func (s fakeStmt) ExecContext(ctx context.Context, _ []driver.NamedValue) (driver.Result, error) {
+	if err := s.f.run(ctx, s.query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) QueryContext(ctx context.Context, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := s.f.run(ctx, s.query); err != nil {
+		return nil, err
+	}
+	cols := s.f.Columns
+	if cols == nil {
+		cols = []string{"k", "v"}
+	}
+	return &fakeRows{cols: cols, left: s.f.Rows}, nil
+}
+
+type fakeTx struct{ f *fakeExecutor }
+
+func (t fakeTx) Commit() error { return t.f.run(context.Background(), "COMMIT") }
+func (t fakeTx) Rollback() error {
+	if t.f.Trace != nil {
+		t.f.Trace("ROLLBACK")
+	}
+	return nil
+}
+
+type fakeRows struct {
+	cols    []string
+	n, left int
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	r.left--
+	r.n++
+	for i, c := range r.cols {
+		if c == "k" {
+			dest[i] = fmt.Sprintf("k%08d", r.n)
+		} else {
+			dest[i] = []byte("v")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// filterBucket is a nominal selectivity and the predicates that hit it on
+// the typed table. Selectivities are derived from how typed is generated:
+// i is uniform over [0, 1e6), t is "<word>-<0..999>" over 16 words, and
+// each column is NULL 10% of the time.
+type filterBucket struct {
+	selectivity float64
+	likeDigits  int // digits appended to the word in the LIKE prefix
+}
+
+// Each extra LIKE digit narrows the prefix match by ~10x.
+var filterBuckets = []filterBucket{
+	{0.05, 0},    // 'alpha-%'
+	{0.005, 1},   // 'alpha-1%'
+	{0.0005, 2},  // 'alpha-12%'
+	{0.00005, 3}, // 'alpha-123%'
+}
+
+func (b filterBucket) name() string {
+	return "filter-" + strconv.FormatFloat(b.selectivity*100, 'f', -1, 64) + "pct"
+}
+
+// queries returns a LIKE prefix match, an indexed range and a compound
+// predicate, each selecting roughly b.selectivity of typed.
+func (b filterBucket) queries() []readQuery {
+	const (
+		iSpan   = 1_000_000
+		nonNull = 0.9
+		// share of rows in an i range that also pass b = ? and f < 1000:
+		// b is non-NULL and matches half the time, f just has to be non-NULL
+		compoundShare = nonNull * (nonNull / 2) * nonNull
+	)
+	width := int64(b.selectivity / nonNull * iSpan)
+	compoundWidth := min(int64(b.selectivity/compoundShare*iSpan), iSpan)
+
+	return []readQuery{
+		{`SELECT id FROM typed WHERE t LIKE ?`, func(rnd *rand.Rand) []any {
+			prefix := typedWords[rnd.Intn(len(typedWords))] + "-"
+			if b.likeDigits > 0 {
+				// leading digit 1-9 so every digit count matches the same share
+				prefix += strconv.Itoa(1 + rnd.Intn(9))
+				for range b.likeDigits - 1 {
+					prefix += strconv.Itoa(rnd.Intn(10))
+				}
+			}
+			return []any{prefix + "%"}
+		}},
+		{`SELECT id FROM typed WHERE i >= ? AND i < ?`, func(rnd *rand.Rand) []any {
+			lo := rnd.Int63n(iSpan - width + 1)
+			return []any{lo, lo + width}
+		}},
+		{`SELECT id FROM typed WHERE i >= ? AND i < ? AND b = ? AND f < ?`, func(rnd *rand.Rand) []any {
+			lo := rnd.Int63n(iSpan - compoundWidth + 1)
+			return []any{lo, lo + compoundWidth, rnd.Intn(2) == 1, 1000.0}
+		}},
+	}
+}
@@ -0,0 +1,226 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+)
+
+// FKStats is what the foreign key workloads saw of the constraint:
+// inserts of children without a parent must be rejected, and deleting a
+// parent must take its children with it.
+type FKStats struct {
+	Probes     int64 `json:"probes,omitempty"`     // inserts with a missing parent
+	Unenforced int64 `json:"unenforced,omitempty"` // of those, accepted
+	// Orphans are the children left without a parent after the cascading
+	// deletes of fk-delete.
+	Orphans *int64 `json:"orphans,omitempty"`
+}
+
+func (s FKStats) pretty() string {
+	var parts []string
+	if s.Probes > 0 {
+		verdict := "enforced"
+		if s.Unenforced > 0 {
+			verdict = "NOT ENFORCED"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s/%s inserts without a parent accepted)", verdict, commaI(s.Unenforced), commaI(s.Probes)))
+	}
+	if s.Orphans != nil {
+		verdict := "cascade complete"
+		if *s.Orphans > 0 {
+			verdict = "cascade INCOMPLETE"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s orphaned children)", verdict, commaI(*s.Orphans)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fkProbeEvery is how often, in ops per worker, fk-insert tries a child
+// of a missing parent.
+const fkProbeEvery = 100
+
+// fkSchema creates kv_child, whose rows reference a kv row each and are
+// deleted with it. Chai and ClickHouse have no foreign keys.
+func fkSchema(engine string) (string, error) {
+	switch engine {
+	case "chai", "chai-native":
+		return "", &errSkip{"chai has no foreign keys"}
+	case "clickhouse":
+		return "", &errSkip{"clickhouse has no foreign keys"}
+	}
+	return schemaFor(engine, embed.FkPgSchema, embed.FkSqliteSchema, "", embed.FkMysqlSchema, "")
+}
+
+// fkConn returns a connection with foreign keys enforced, which sqlite
+// only does when asked to, per connection.
+func fkConn(ctx context.Context, db Executor, engine string) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if engine == "sqlite" {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// fkInsertWorkload inserts child rows of random snapshot keys, so every
+// insert checks its parent exists; against insert that is the cost of the
+// constraint. Every fkProbeEvery ops a worker also inserts, untimed, a
+// child of a missing parent, which the engine must reject.
+func fkInsertWorkload(engine string, dollar bool, keys []string, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv_child(id, k, v) VALUES(?, ?, ?)`)
+	undo := bind(dollar, `DELETE FROM kv_child WHERE id = ?`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("fk-insert", ph)
+		if len(keys) == 0 {
+			return res.finalize()
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var probes, unenforced atomic.Int64
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				conn, err := fkConn(ctx, db, engine)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				defer conn.Close()
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+
+				for n := 1; ; n++ {
+					if res.stopped() {
+						return
+					}
+					id, err := gen.next()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					if n%fkProbeEvery == 0 { // probeKey is never in kv
+						_, err := conn.ExecContext(ctx, q, id, probeKey, pl.pick(rnd))
+						release()
+						if ctx.Err() != nil {
+							return
+						}
+						probes.Add(1)
+						if err == nil {
+							unenforced.Add(1)
+							_, _ = conn.ExecContext(ctx, undo, id)
+						}
+						continue
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					_, err = conn.ExecContext(octx, q, id, keys[rnd.Intn(len(keys))], pl.pick(rnd))
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		res.FK = &FKStats{Probes: probes.Load(), Unenforced: unenforced.Load()}
+		return res.finalize()
+	}
+}
+
+// fkDeleteWorkload deletes snapshot keys from kv like delete, each taking
+// the children fk-insert gave it along (ON DELETE CASCADE). Afterwards the
+// children left without a parent are counted, which must be none.
+func fkDeleteWorkload(engine string, dollar bool, keys []string) WorkloadFunc {
+	q := bind(dollar, `DELETE FROM kv WHERE k = ?`)
+	orphansQ := `SELECT COUNT(*) FROM kv_child c WHERE NOT EXISTS (SELECT 1 FROM kv WHERE kv.k = c.k)`
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("fk-delete", ph)
+		if len(keys) == 0 {
+			return res.finalize()
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				conn, err := fkConn(ctx, db, engine)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				defer conn.Close()
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+
+				for {
+					if res.stopped() {
+						return
+					}
+					k := keys[rnd.Intn(len(keys))]
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					r, err := conn.ExecContext(octx, q, k)
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+					res.addDeleted(k)
+					if n, err := r.RowsAffected(); err == nil {
+						res.addRowsDeleted(n)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		var orphans int64
+		if err := db.QueryRowContext(context.WithoutCancel(ctx), orphansQ).Scan(&orphans); err != nil {
+			res.logError(err, "failed to count orphaned children")
+		} else {
+			res.FK = &FKStats{Orphans: &orphans}
+		}
+		return res.finalize()
+	}
+}
@@ -0,0 +1,47 @@
+package bench
+
+import (
+	"context"
+	"testing"
+)
+
+// RunBenchmarks runs cfg's suite once and reports every phase as a
+// sub-benchmark of b, so the workloads can be driven from go test -bench
+// and compared with benchstat:
+//
+//	func BenchmarkChai(b *testing.B) {
+//		bench.RunBenchmarks(b, bench.Config{Engine: "chai", DSN: b.TempDir() + "/chai.db", Concurrency: 4, Duration: 5 * time.Second})
+//	}
+//
+// Phases stay time-bound (Config.Duration) rather than running b.N
+// operations, so their ns/op, B/op and allocs/op are the phase's
+// duration and the process's heap allocations divided by its ops;
+// p50-ns, p99-ns and errors are reported alongside. Allocations count
+// everything the process did meanwhile, driver and embedded engine
+// included. Skipped phases and phases without ops are skipped; the
+// iteration count go test prints carries no meaning.
+func RunBenchmarks(b *testing.B, cfg Config) {
+	b.Helper()
+	rep, err := Run(context.Background(), cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, res := range rep.Results {
+		b.Run(res.Workload, func(b *testing.B) {
+			switch {
+			case res.Skipped:
+				b.Skip(res.SkipReason)
+			case res.Ops == 0:
+				b.Skip("no operations")
+			}
+			b.ReportAllocs()
+			ops := float64(res.Ops)
+			b.ReportMetric(float64(res.Duration.Nanoseconds())/ops, "ns/op")
+			b.ReportMetric(float64(res.allocBytes)/ops, "B/op")
+			b.ReportMetric(float64(res.mallocs)/ops, "allocs/op")
+			b.ReportMetric(float64(res.P50.Nanoseconds()), "p50-ns")
+			b.ReportMetric(float64(res.P99.Nanoseconds()), "p99-ns")
+			b.ReportMetric(float64(res.Errors), "errors")
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package bench
+
+import "math/rand"
+
+// groupQueries aggregate typed by g (typedGroups distinct values, plus a
+// NULL group) with COUNT/SUM/AVG, so engines without an index on g have to
+// build the groups in a hash table or by sorting.
+var groupQueries = []readQuery{
+	{`SELECT g, COUNT(*), SUM(i), AVG(f) FROM typed GROUP BY g`, func(*rand.Rand) []any { return nil }},
+	{`SELECT g, COUNT(*), SUM(f) FROM typed WHERE i >= ? AND i < ? GROUP BY g`, func(rnd *rand.Rand) []any {
+		lo := rnd.Int63n(900_000)
+		return []any{lo, lo + 100_000}
+	}},
+	{`SELECT g, b, COUNT(*), AVG(i) FROM typed GROUP BY g, b`, func(*rand.Rand) []any { return nil }},
+}
@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const groupCommitMaxRows = 10_000 // flush early once this many rows are pending
+
+// groupRow is one worker's row waiting for the committer; done receives the
+// outcome of the transaction it ended up in.
+type groupRow struct {
+	k    string
+	v    []byte
+	done chan error
+}
+
+// groupCommitWorkload is the insert workload with commit batching: workers
+// hand their rows to a single committer goroutine, which writes everything
+// that arrived within one flush interval in a single transaction. Workers
+// block until their row is committed, so the latency is what a caller
+// would observe, trading per-row latency for fewer commits.
+func groupCommitWorkload(dollar bool, name string, interval time.Duration, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		rows := make(chan groupRow)
+		committed := make(chan struct{})
+		go func() {
+			defer close(committed)
+			defer res.recoverWorker()
+			groupCommitter(ctx, db, q, interval, rows, res)
+		}()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				done := make(chan error, 1)
+				for {
+					k, err := gen.next()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					start := res.clock.Now()
+					select {
+					case rows <- groupRow{k, pl.pick(rnd), done}:
+					case <-ctx.Done():
+						return
+					}
+					if err := <-done; err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+					res.addInserted(1)
+				}
+			}(w)
+		}
+		wg.Wait()
+		<-committed
+		return res.finalize()
+	}
+}
+
+// groupCommitter collects rows until interval has passed since the first
+// one of a batch (or groupCommitMaxRows are pending) and commits them
+// together. Once ctx is done it answers the rows still pending and returns.
+func groupCommitter(ctx context.Context, db Executor, q string, interval time.Duration, rows <-chan groupRow, res *Result) {
+	var (
+		pending []groupRow
+		flush   <-chan time.Time
+	)
+	commit := func() {
+		err := groupCommitTx(ctx, db, q, pending, res)
+		for _, r := range pending {
+			r.done <- err
+		}
+		pending, flush = pending[:0], nil
+	}
+	for {
+		select {
+		case r := <-rows:
+			if len(pending) == 0 {
+				flush = time.After(interval)
+			}
+			if pending = append(pending, r); len(pending) >= groupCommitMaxRows {
+				commit()
+			}
+		case <-flush:
+			commit()
+		case <-ctx.Done():
+			for _, r := range pending {
+				r.done <- ctx.Err()
+			}
+			return
+		}
+	}
+}
+
+func groupCommitTx(ctx context.Context, db Executor, q string, batch []groupRow, res *Result) error {
+	release, err := res.acquireWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range batch {
+		if _, err := stmt.ExecContext(ctx, r.k, r.v); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,158 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthTimeout bounds a health check, so a wedged database fails the
+// check instead of hanging the suite between phases.
+const healthTimeout = 30 * time.Second
+
+// HealthCheck is a cheap verification of the database run after a phase,
+// so corruption or a wedged engine shows up at the phase boundary instead
+// of as unexplained errors in the next workload.
+type HealthCheck struct {
+	Rows      int64  `json:"rows"`                // rows in kv
+	Integrity string `json:"integrity,omitempty"` // first line of sqlite's PRAGMA quick_check
+	// Expected is the row count kv should have after the rows the phase
+	// inserted and deleted (nil when not tracked); Drift is Rows-Expected,
+	// where anything but 0 means lost writes or phantom rows.
+	Expected *int64        `json:"expected_rows,omitempty"`
+	Drift    int64         `json:"drift,omitempty"`
+	Took     time.Duration `json:"took"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// OK reports whether the check ran and found nothing wrong.
+func (h *HealthCheck) OK() bool {
+	return h.Error == "" && (h.Integrity == "" || h.Integrity == "ok") && h.Drift == 0
+}
+
+func (h *HealthCheck) String() string {
+	switch {
+	case h.Error != "":
+		return "FAILED: " + h.Error
+	case h.Integrity != "" && h.Integrity != "ok":
+		return "FAILED: integrity check: " + h.Integrity
+	case h.Drift != 0:
+		return fmt.Sprintf("FAILED: %s rows in kv, expected %s (drift %+d)", commaI(h.Rows), commaI(*h.Expected), h.Drift)
+	}
+	return fmt.Sprintf("ok, %s rows in kv (%s)", commaI(h.Rows), h.Took.Round(time.Microsecond))
+}
+
+// checkHealth counts the rows of kv and, on sqlite, runs PRAGMA
+// quick_check. Key-value stores have no cheap count and are not checked.
+func (s *suite) checkHealth() *HealthCheck {
+	if s.kv != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, healthTimeout)
+	defer cancel()
+
+	h := &HealthCheck{}
+	start := time.Now()
+	err := func() error {
+		if s.chai != nil {
+			row, err := s.chai.WithContext(ctx).QueryRow(`SELECT COUNT(*) FROM kv`)
+			if err != nil {
+				return err
+			}
+			return row.Scan(&h.Rows)
+		}
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM kv`).Scan(&h.Rows); err != nil {
+			return err
+		}
+		if s.cfg.Engine == "sqlite" {
+			return s.db.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&h.Integrity)
+		}
+		return nil
+	}()
+	h.Took = time.Since(start)
+	if err != nil {
+		h.Error = err.Error()
+	}
+	return h
+}
+
+// rowChanges are a phase's changes to the row count of kv: the rows it
+// committed and the snapshot keys it deleted. Deleting a key twice removes
+// one row, so deletes are kept as keys; there are at most as many as the
+// key snapshot holds.
+type rowChanges struct {
+	inserted atomic.Int64
+	mu       sync.Mutex
+	deleted  map[string]struct{}
+	// untracked phases remove rows they cannot name (range and bulk
+	// deletes), which ends drift tracking for the rest of the suite
+	untracked bool
+}
+
+func newRowChanges() *rowChanges { return &rowChanges{deleted: make(map[string]struct{})} }
+
+// addInserted records n rows committed to kv.
+func (r *Result) addInserted(n int64) { r.rows.inserted.Add(n) }
+
+// addDeleted records that key was deleted from kv.
+func (r *Result) addDeleted(key string) {
+	r.rows.mu.Lock()
+	r.rows.deleted[key] = struct{}{}
+	r.rows.mu.Unlock()
+}
+
+// merge adds the changes of o, e.g. of the phase's warmup run.
+func (c *rowChanges) merge(o *rowChanges) {
+	c.inserted.Add(o.inserted.Load())
+	c.untracked = c.untracked || o.untracked
+	for k := range o.deleted {
+		c.deleted[k] = struct{}{}
+	}
+}
+
+// rowLedger tracks how many rows kv should hold: its count before the
+// first phase plus every phase's inserts minus its deletes.
+type rowLedger struct {
+	known    bool
+	expected int64
+	deleted  map[string]bool // keys deleted so far in the suite
+	// off is set once a phase removed rows not in deleted; a later key
+	// delete may then hit a row already gone, so nothing is compared
+	off bool
+}
+
+// start takes the count kv begins the suite with from h.
+func (l *rowLedger) start(h *HealthCheck) {
+	l.deleted = make(map[string]bool)
+	l.known = h != nil && h.Error == ""
+	if l.known {
+		l.expected = h.Rows
+	}
+}
+
+// settle applies the row changes of a phase and records in h how far the
+// actual count drifted from the expected one. untracked phases change kv
+// without accounting for it, so they only resync the ledger. The ledger
+// then follows the actual count, so a discrepancy is flagged once rather
+// than by every later phase.
+func (l *rowLedger) settle(c *rowChanges, h *HealthCheck, tracked bool) {
+	gone := int64(0)
+	for k := range c.deleted {
+		if !l.deleted[k] {
+			l.deleted[k] = true
+			gone++
+		}
+	}
+	if h == nil || h.Error != "" {
+		l.known = false
+		return
+	}
+	l.off = l.off || c.untracked
+	if l.known && tracked && !l.off {
+		expected := l.expected + c.inserted.Load() - gone
+		h.Expected, h.Drift = &expected, h.Rows-expected
+	}
+	l.known, l.expected = true, h.Rows
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hotspotRetries is how many times the hotspot workload retries a
+// transaction that failed on contention before counting it as an error.
+const hotspotRetries = 10
+
+// HotspotStats is what contention on the hotspot workload's hot keys cost:
+// transactions retried after busy, locked, deadlock, retryable or
+// serialization errors, and the ones still failing after hotspotRetries.
+type HotspotStats struct {
+	HotKeys int   `json:"hot_keys"`
+	HotPct  int   `json:"hot_pct"`
+	Retries int64 `json:"retries"`
+	GaveUp  int64 `json:"gave_up,omitempty"`
+}
+
+func (s HotspotStats) pretty() string {
+	out := fmt.Sprintf("%d%% of updates on %d hot key(s), %s retries", s.HotPct, s.HotKeys, commaI(s.Retries))
+	if s.GaveUp > 0 {
+		out += fmt.Sprintf(", %s given up", commaI(s.GaveUp))
+	}
+	return out
+}
+
+// contended reports whether err is the engine turning a transaction away
+// over a conflicting one, which a client would retry.
+func contended(err error) bool {
+	switch classifyError(err) {
+	case errBusy, errLocked, errDeadlock, errRetryable:
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "40001") || strings.Contains(msg, "could not serialize access")
+}
+
+// hotspotWorkload updates one snapshot key per transaction, hotPct percent
+// of them one of the first hotKeys keys and the rest any other key.
+// Transactions failing on contention are retried up to hotspotRetries
+// times, their latency covering every attempt. Besides the combined
+// numbers it reports hotspot/hot and hotspot/cold, so writers queueing on
+// the hot rows (lock convoys) show as the gap between the two.
+func hotspotWorkload(dollar bool, keys []string, hotKeys, hotPct int, pl payloads) WorkloadFunc {
+	q := bind(dollar, `UPDATE kv SET v = ? WHERE k = ?`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("hotspot", ph)
+		hotPart, coldPart := res.split("hot"), res.split("cold")
+		if len(keys) == 0 {
+			return res.finalize()
+		}
+		hot, cold := keys[:min(hotKeys, len(keys))], keys[min(hotKeys, len(keys)):]
+		if len(cold) == 0 {
+			cold = hot
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var retries, gaveUp atomic.Int64
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					part, k := coldPart, cold[rnd.Intn(len(cold))]
+					if rnd.Intn(100) < hotPct {
+						part, k = hotPart, hot[rnd.Intn(len(hot))]
+					}
+					v := pl.pick(rnd)
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					for attempt := 0; ; attempt++ {
+						octx, done := res.op(ctx)
+						err = done(hotspotTx(octx, db, q, k, v))
+						if err == nil || !contended(err) || ctx.Err() != nil {
+							break
+						}
+						if attempt == hotspotRetries {
+							gaveUp.Add(1)
+							break
+						}
+						retries.Add(1)
+					}
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						part.addErrorCnt(err)
+						continue
+					}
+					d := res.clock.Since(start)
+					res.addLatency(worker, d)
+					part.addLatency(worker, d)
+				}
+			}(w)
+		}
+		wg.Wait()
+		res.Hotspot = &HotspotStats{HotKeys: len(hot), HotPct: hotPct, Retries: retries.Load(), GaveUp: gaveUp.Load()}
+		return res.finalize()
+	}
+}
+
+func hotspotTx(ctx context.Context, db Executor, q, k string, v []byte) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, q, v, k); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,205 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pgLogLine matches a postgres stderr log line under the default
+// log_line_prefix '%m [%p] ' (or '%t [%p] '): timestamp, time zone,
+// process id, level and message.
+var pgLogLine = regexp.MustCompile(`^(\d{4}-\d\d-\d\d \d\d:\d\d:\d\d(?:\.\d+)?) (\S+) \[(\d+)[^\]]*\] (\w+):\s+(.*)$`)
+
+// pgLogMsg is one message of a postgres log, continuation lines included.
+type pgLogMsg struct {
+	at    time.Time
+	pid   string
+	level string
+	text  string
+}
+
+// ImportPostgresLog converts a postgres log written with
+// log_statement=all into an operation log of one phase: every backend
+// becomes a worker that issues its statements, transaction control
+// included, at the offsets they were logged at. Statements of the extended
+// protocol get their logged parameters substituted as the literals
+// postgres printed. It returns the number of statements written.
+func ImportPostgresLog(r io.Reader, w io.Writer, phase string) (int, error) {
+	var (
+		msgs []*pgLogMsg
+		cur  *pgLogMsg
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 64<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		m := pgLogLine.FindStringSubmatch(line)
+		if m == nil {
+			// a multi-line statement goes on without the prefix
+			if cur != nil {
+				cur.text += "\n" + line
+			}
+			continue
+		}
+		at, err := time.Parse("2006-01-02 15:04:05.999999999", m[1])
+		if err != nil {
+			return 0, fmt.Errorf("log timestamp %q: %w", m[1], err)
+		}
+		cur = &pgLogMsg{at: at, pid: m[3], level: m[4], text: m[5]}
+		msgs = append(msgs, cur)
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+
+	var entries []OpEntry
+	workers := make(map[string]int)
+	var start time.Time
+	for i, m := range msgs {
+		if m.level != "LOG" {
+			continue
+		}
+		var q string
+		switch {
+		case strings.HasPrefix(m.text, "statement: "):
+			q = strings.TrimPrefix(m.text, "statement: ")
+		case strings.HasPrefix(m.text, "execute "):
+			_, q, _ = strings.Cut(m.text, ": ")
+			// the parameters follow as the DETAIL of the same backend
+			for _, d := range msgs[i+1:] {
+				if d.pid != m.pid {
+					continue
+				}
+				if d.level == "DETAIL" && strings.HasPrefix(d.text, "parameters: ") {
+					params, err := parsePgParams(strings.TrimPrefix(d.text, "parameters: "))
+					if err != nil {
+						return 0, fmt.Errorf("parameters of %q: %w", q, err)
+					}
+					q = bindPgParams(q, params)
+				}
+				break
+			}
+		default:
+			continue // durations, errors, connections, ...
+		}
+		if strings.TrimSpace(q) == "" {
+			continue
+		}
+		worker, ok := workers[m.pid]
+		if !ok {
+			worker = len(workers)
+			workers[m.pid] = worker
+		}
+		if start.IsZero() {
+			start = m.at
+		}
+		entries = append(entries, OpEntry{Phase: phase, Worker: worker, Op: "sql", SQL: q, Offset: max(0, m.at.Sub(start))})
+	}
+	return len(entries), writeOpLog(w, OpLogHeader{Version: opLogVersion, Engine: "pgx"}, entries)
+}
+
+// parsePgParams parses the DETAIL of an execute, e.g.
+// `$1 = '42', $2 = NULL`, into the literal of each parameter number.
+func parsePgParams(s string) (map[int]string, error) {
+	params := make(map[int]string)
+	for s != "" {
+		name, rest, ok := strings.Cut(s, " = ")
+		if !ok || !strings.HasPrefix(name, "$") {
+			return nil, fmt.Errorf("malformed parameter list at %q", s)
+		}
+		n, err := strconv.Atoi(name[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed parameter %q", name)
+		}
+		var lit string
+		if strings.HasPrefix(rest, "NULL") {
+			lit, rest = "NULL", rest[len("NULL"):]
+		} else if strings.HasPrefix(rest, "'") {
+			// a quoted literal, with '' for a quote
+			end := 1
+			for {
+				i := strings.IndexByte(rest[end:], '\'')
+				if i < 0 {
+					return nil, fmt.Errorf("unterminated literal at %q", rest)
+				}
+				end += i + 1
+				if !strings.HasPrefix(rest[end:], "'") {
+					break
+				}
+				end++
+			}
+			lit, rest = rest[:end], rest[end:]
+		} else {
+			return nil, fmt.Errorf("malformed value at %q", rest)
+		}
+		params[n] = lit
+		s = strings.TrimPrefix(rest, ", ")
+	}
+	return params, nil
+}
+
+// bindPgParams replaces the $n placeholders of q with params.
+func bindPgParams(q string, params map[int]string) string {
+	var b strings.Builder
+	for i := 0; i < len(q); i++ {
+		j := i + 1
+		for j < len(q) && q[j] >= '0' && q[j] <= '9' {
+			j++
+		}
+		if q[i] == '$' && j > i+1 {
+			n, _ := strconv.Atoi(q[i+1 : j])
+			if lit, ok := params[n]; ok {
+				b.WriteString(lit)
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(q[i])
+	}
+	return b.String()
+}
+
+// ImportSQLiteTrace converts the output of the sqlite3 shell's .trace
+// into an operation log of one phase, one worker issuing the statements
+// back to back: every line is a statement, lines starting with whitespace
+// continue the one before and "--" lines (trigger markers) are skipped.
+// It returns the number of statements written.
+func ImportSQLiteTrace(r io.Reader, w io.Writer, phase string) (int, error) {
+	var entries []OpEntry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 64<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "", strings.HasPrefix(line, "--"):
+		case (line[0] == ' ' || line[0] == '\t') && len(entries) > 0:
+			entries[len(entries)-1].SQL += "\n" + line
+		default:
+			entries = append(entries, OpEntry{Phase: phase, Op: "sql", SQL: line})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return len(entries), writeOpLog(w, OpLogHeader{Version: opLogVersion, Engine: "sqlite"}, entries)
+}
+
+func writeOpLog(w io.Writer, hdr OpLogHeader, entries []OpEntry) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(hdr); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
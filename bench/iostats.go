@@ -0,0 +1,115 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IOStats is the I/O performed by the process (and, when it could be
+// resolved, the block device holding the database) during one phase.
+type IOStats struct {
+	ReadChars     int64      `json:"rchar"`       // bytes passed to read syscalls
+	WriteChars    int64      `json:"wchar"`       // bytes passed to write syscalls
+	ReadSyscalls  int64      `json:"syscr"`       // read-like syscalls
+	WriteSyscalls int64      `json:"syscw"`       // write-like syscalls
+	ReadBytes     int64      `json:"read_bytes"`  // bytes fetched from storage
+	WriteBytes    int64      `json:"write_bytes"` // bytes sent to storage
+	Device        *DiskStats `json:"device,omitempty"`
+}
+
+// DiskStats is the /proc/diskstats delta of a single block device.
+type DiskStats struct {
+	Name       string `json:"name"`
+	Reads      int64  `json:"reads"`
+	Writes     int64  `json:"writes"`
+	ReadBytes  int64  `json:"read_bytes"`
+	WriteBytes int64  `json:"write_bytes"`
+}
+
+func (s IOStats) sub(o IOStats) IOStats {
+	d := IOStats{
+		ReadChars:     s.ReadChars - o.ReadChars,
+		WriteChars:    s.WriteChars - o.WriteChars,
+		ReadSyscalls:  s.ReadSyscalls - o.ReadSyscalls,
+		WriteSyscalls: s.WriteSyscalls - o.WriteSyscalls,
+		ReadBytes:     s.ReadBytes - o.ReadBytes,
+		WriteBytes:    s.WriteBytes - o.WriteBytes,
+	}
+	if s.Device != nil && o.Device != nil && s.Device.Name == o.Device.Name {
+		d.Device = &DiskStats{
+			Name:       s.Device.Name,
+			Reads:      s.Device.Reads - o.Device.Reads,
+			Writes:     s.Device.Writes - o.Device.Writes,
+			ReadBytes:  s.Device.ReadBytes - o.Device.ReadBytes,
+			WriteBytes: s.Device.WriteBytes - o.Device.WriteBytes,
+		}
+	}
+	return d
+}
+
+// add sums s and o, e.g. the I/O of shards of one phase; the device delta
+// survives only when both measured the same device.
+func (s IOStats) add(o IOStats) IOStats {
+	d := IOStats{
+		ReadChars:     s.ReadChars + o.ReadChars,
+		WriteChars:    s.WriteChars + o.WriteChars,
+		ReadSyscalls:  s.ReadSyscalls + o.ReadSyscalls,
+		WriteSyscalls: s.WriteSyscalls + o.WriteSyscalls,
+		ReadBytes:     s.ReadBytes + o.ReadBytes,
+		WriteBytes:    s.WriteBytes + o.WriteBytes,
+	}
+	if s.Device != nil && o.Device != nil && s.Device.Name == o.Device.Name {
+		d.Device = &DiskStats{
+			Name:       s.Device.Name,
+			Reads:      s.Device.Reads + o.Device.Reads,
+			Writes:     s.Device.Writes + o.Device.Writes,
+			ReadBytes:  s.Device.ReadBytes + o.Device.ReadBytes,
+			WriteBytes: s.Device.WriteBytes + o.Device.WriteBytes,
+		}
+	}
+	return d
+}
+
+// measureIO snapshots I/O counters and returns a func yielding the delta
+// since the snapshot, or nil when counters are unavailable on this platform.
+func measureIO(path string) func() *IOStats {
+	before, ok := readIOStats(path)
+	if !ok {
+		return func() *IOStats { return nil }
+	}
+	return func() *IOStats {
+		after, ok := readIOStats(path)
+		if !ok {
+			return nil
+		}
+		d := after.sub(before)
+		return &d
+	}
+}
+
+func (s IOStats) pretty() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "read %s (%s syscalls, %s storage)  write %s (%s syscalls, %s storage)",
+		fBytes(s.ReadChars), commaI(s.ReadSyscalls), fBytes(s.ReadBytes),
+		fBytes(s.WriteChars), commaI(s.WriteSyscalls), fBytes(s.WriteBytes))
+	if d := s.Device; d != nil {
+		fmt.Fprintf(&b, "\n\t\t\t  %s: %s reads %s, %s writes %s",
+			d.Name, commaI(d.Reads), fBytes(d.ReadBytes), commaI(d.Writes), fBytes(d.WriteBytes))
+	}
+	return b.String()
+}
+
+func fBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	f := float64(n)
+	for _, u := range []string{"KiB", "MiB", "GiB"} {
+		f /= unit
+		if f < unit && f > -unit {
+			return fmt.Sprintf("%.1f%s", f, u)
+		}
+	}
+	return fmt.Sprintf("%.1fTiB", f/unit)
+}
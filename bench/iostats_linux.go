@@ -0,0 +1,88 @@
+//go:build linux
+
+package bench
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const sectorSize = 512 // /proc/diskstats always counts 512-byte sectors
+
+// readIOStats reads /proc/self/io and, if path resolves to a block device
+// listed in /proc/diskstats, that device's counters.
+func readIOStats(path string) (IOStats, bool) {
+	f, err := os.Open("/proc/self/io")
+	if err != nil {
+		return IOStats{}, false
+	}
+	defer f.Close()
+
+	var s IOStats
+	fields := map[string]*int64{
+		"rchar":       &s.ReadChars,
+		"wchar":       &s.WriteChars,
+		"syscr":       &s.ReadSyscalls,
+		"syscw":       &s.WriteSyscalls,
+		"read_bytes":  &s.ReadBytes,
+		"write_bytes": &s.WriteBytes,
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), ":")
+		if dst, known := fields[k]; ok && known {
+			*dst, _ = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+	}
+	if sc.Err() != nil {
+		return IOStats{}, false
+	}
+
+	if path != "" {
+		s.Device = readDiskStats(path)
+	}
+	return s, true
+}
+
+func readDiskStats(path string) *DiskStats {
+	var st unix.Stat_t
+	// the database file may not exist yet before the first phase
+	if err := unix.Stat(path, &st); err != nil {
+		if err := unix.Stat(filepath.Dir(path), &st); err != nil {
+			return nil
+		}
+	}
+	major, minor := unix.Major(st.Dev), unix.Minor(st.Dev)
+
+	f, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// major minor name reads merged sectors ms writes merged sectors ms ...
+		fs := strings.Fields(sc.Text())
+		if len(fs) < 10 {
+			continue
+		}
+		if fs[0] != strconv.FormatUint(uint64(major), 10) || fs[1] != strconv.FormatUint(uint64(minor), 10) {
+			continue
+		}
+		n := func(i int) int64 { v, _ := strconv.ParseInt(fs[i], 10, 64); return v }
+		return &DiskStats{
+			Name:       fs[2],
+			Reads:      n(3),
+			ReadBytes:  n(5) * sectorSize,
+			Writes:     n(7),
+			WriteBytes: n(9) * sectorSize,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+//go:build !linux
+
+package bench
+
+func readIOStats(_ string) (IOStats, bool) { return IOStats{}, false }
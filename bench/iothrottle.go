@@ -0,0 +1,125 @@
+package bench
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// IOThrottle slows down the storage under an embedded engine, so
+// storage-bound behaviour can be studied on a fast NVMe dev machine.
+type IOThrottle struct {
+	// FsyncDelay is added to every fsync. Only the pebble baseline's
+	// storage can be wrapped for it: chai and sqlite open their files
+	// themselves, without a hook for a VFS.
+	FsyncDelay time.Duration `json:"fsync_delay,omitempty"`
+	// cgroup v2 io.max limits of the whole process on the device holding
+	// the data path; 0 = unlimited. Linux only, and the io controller must
+	// be delegated to the process's cgroup.
+	ReadBPS   int64 `json:"read_bps,omitempty"`
+	WriteBPS  int64 `json:"write_bps,omitempty"`
+	ReadIOPS  int64 `json:"read_iops,omitempty"`
+	WriteIOPS int64 `json:"write_iops,omitempty"`
+}
+
+func (t IOThrottle) limited() bool {
+	return t.ReadBPS > 0 || t.WriteBPS > 0 || t.ReadIOPS > 0 || t.WriteIOPS > 0
+}
+
+// ioMax renders the limits as an io.max line without the device.
+func (t IOThrottle) ioMax() string {
+	v := func(n int64) string {
+		if n <= 0 {
+			return "max"
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	return fmt.Sprintf("rbps=%s wbps=%s riops=%s wiops=%s", v(t.ReadBPS), v(t.WriteBPS), v(t.ReadIOPS), v(t.WriteIOPS))
+}
+
+func (t IOThrottle) pretty() string {
+	var parts []string
+	if t.FsyncDelay > 0 {
+		parts = append(parts, "fsync +"+t.FsyncDelay.String())
+	}
+	if t.ReadBPS > 0 {
+		parts = append(parts, "read "+fBytes(t.ReadBPS)+"/s")
+	}
+	if t.WriteBPS > 0 {
+		parts = append(parts, "write "+fBytes(t.WriteBPS)+"/s")
+	}
+	if t.ReadIOPS > 0 {
+		parts = append(parts, fmt.Sprintf("%d read IOPS", t.ReadIOPS))
+	}
+	if t.WriteIOPS > 0 {
+		parts = append(parts, fmt.Sprintf("%d write IOPS", t.WriteIOPS))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// throttleIO applies t for a run of engine on dsn and returns the func
+// lifting it again.
+func throttleIO(engine, dsn string, t IOThrottle) (func(), error) {
+	if t.FsyncDelay > 0 && engine != "pebble" {
+		return nil, fmt.Errorf("an fsync delay is only supported with pebble; limit %s's device with the io.max options instead", engine)
+	}
+	if !t.limited() {
+		return func() {}, nil
+	}
+	path := dataPath(engine, dsn)
+	if path == "" {
+		return nil, fmt.Errorf("IO limits need an embedded engine with a data path")
+	}
+	return applyIOMax(path, t)
+}
+
+// slowSyncFS delays every sync of the files it opens by delay.
+type slowSyncFS struct {
+	vfs.FS
+	delay time.Duration
+}
+
+func (fs slowSyncFS) wrap(f vfs.File, err error) (vfs.File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return slowSyncFile{f, fs.delay}, nil
+}
+
+func (fs slowSyncFS) Create(name string) (vfs.File, error)  { return fs.wrap(fs.FS.Create(name)) }
+func (fs slowSyncFS) OpenDir(name string) (vfs.File, error) { return fs.wrap(fs.FS.OpenDir(name)) }
+
+func (fs slowSyncFS) Open(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	return fs.wrap(fs.FS.Open(name, opts...))
+}
+
+func (fs slowSyncFS) OpenReadWrite(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	return fs.wrap(fs.FS.OpenReadWrite(name, opts...))
+}
+
+func (fs slowSyncFS) ReuseForWrite(oldname, newname string) (vfs.File, error) {
+	return fs.wrap(fs.FS.ReuseForWrite(oldname, newname))
+}
+
+type slowSyncFile struct {
+	vfs.File
+	delay time.Duration
+}
+
+func (f slowSyncFile) Sync() error {
+	time.Sleep(f.delay)
+	return f.File.Sync()
+}
+
+func (f slowSyncFile) SyncData() error {
+	time.Sleep(f.delay)
+	return f.File.SyncData()
+}
+
+func (f slowSyncFile) SyncTo(length int64) (bool, error) {
+	time.Sleep(f.delay)
+	return f.File.SyncTo(length)
+}
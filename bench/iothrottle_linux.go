@@ -0,0 +1,74 @@
+//go:build linux
+
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// applyIOMax writes t's limits into the io.max of this process's cgroup
+// for the disk holding path, and returns the func restoring the previous
+// limits.
+func applyIOMax(path string, t IOThrottle) (func(), error) {
+	var st unix.Stat_t
+	// the database file may not exist yet before the first phase
+	if err := unix.Stat(path, &st); err != nil {
+		if err := unix.Stat(filepath.Dir(path), &st); err != nil {
+			return nil, err
+		}
+	}
+	if unix.Major(st.Dev) == 0 {
+		return nil, fmt.Errorf("%s is not on a block device", path)
+	}
+	dev := wholeDisk(fmt.Sprintf("%d:%d", unix.Major(st.Dev), unix.Minor(st.Dev)))
+
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	var cgroup string
+	for _, line := range strings.Split(string(b), "\n") {
+		if p, ok := strings.CutPrefix(line, "0::"); ok {
+			cgroup = p
+		}
+	}
+	if cgroup == "" {
+		return nil, fmt.Errorf("not in a cgroup v2 hierarchy")
+	}
+	file := filepath.Join("/sys/fs/cgroup", cgroup, "io.max")
+
+	prev := dev + " rbps=max wbps=max riops=max wiops=max"
+	if b, err := os.ReadFile(file); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(line, dev+" ") {
+				prev = line
+			}
+		}
+	}
+	if err := os.WriteFile(file, []byte(dev+" "+t.ioMax()), 0); err != nil {
+		return nil, fmt.Errorf("set %s (is the io controller delegated to %s?): %w", file, cgroup, err)
+	}
+	return func() { _ = os.WriteFile(file, []byte(prev), 0) }, nil
+}
+
+// wholeDisk returns the device number of the disk a partition dev
+// (major:minor) belongs to, which is what io.max takes, or dev itself.
+func wholeDisk(dev string) string {
+	sys, err := filepath.EvalSymlinks("/sys/dev/block/" + dev)
+	if err != nil {
+		return dev
+	}
+	if _, err := os.Stat(filepath.Join(sys, "partition")); err != nil {
+		return dev
+	}
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(sys), "dev"))
+	if err != nil {
+		return dev
+	}
+	return strings.TrimSpace(string(b))
+}
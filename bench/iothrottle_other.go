@@ -0,0 +1,9 @@
+//go:build !linux
+
+package bench
+
+import "fmt"
+
+func applyIOMax(_ string, _ IOThrottle) (func(), error) {
+	return nil, fmt.Errorf("IO limits need cgroup v2 io.max, which is Linux only")
+}
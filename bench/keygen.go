@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// keyGen produces the keys of inserted rows. Each worker has its own.
+type keyGen interface {
+	next() (string, error)
+}
+
+// keyGens returns the key generator of a worker.
+type keyGens func(worker int) (keyGen, error)
+
+// newKeyGens returns the key generators for mode, which decides where new
+// keys land in the index:
+//
+//	randflake: snowflake ids encrypted with secret, uniformly spread (the historical behaviour)
+//	uuidv7:    time-ordered UUIDs, appended near the end with some jitter
+//	seq:       a counter shared by all workers, strictly appended
+//	hex:       32 random hex digits, uniformly spread
+func newKeyGens(mode, secret string) (keyGens, error) {
+	switch mode {
+	case "", "randflake":
+		if secret == "" {
+			secret = DefaultRandflakeSecret
+		}
+		if len(secret) != 16 {
+			return nil, fmt.Errorf("randflake secret must be 16 bytes, got %d", len(secret))
+		}
+		return func(worker int) (keyGen, error) { return newRandflakeKeys(worker, []byte(secret)) }, nil
+	case "uuidv7":
+		return func(worker int) (keyGen, error) { return &uuidV7Keys{rnd: workerRand(worker)}, nil }, nil
+	case "seq":
+		// seeded from the clock, so later phases and runs on the same
+		// table continue after the keys already there
+		seqNext.CompareAndSwap(0, time.Now().UnixNano())
+		return func(int) (keyGen, error) { return seqKeys{}, nil }, nil
+	case "hex":
+		return func(worker int) (keyGen, error) { return &hexKeys{rnd: workerRand(worker)}, nil }, nil
+	}
+	return nil, fmt.Errorf("unknown key generator: %s", mode)
+}
+
+func workerRand(worker int) *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+}
+
+// uuidV7Keys follows RFC 9562: a 48-bit millisecond timestamp, then random
+// bits. Workers interleave within a millisecond.
+type uuidV7Keys struct{ rnd *rand.Rand }
+
+func (k *uuidV7Keys) next() (string, error) {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16|uint64(k.rnd.Intn(1<<12)))
+	binary.BigEndian.PutUint64(b[8:], k.rnd.Uint64())
+	b[6] |= 0x70            // version 7
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// seqNext is the next key of the seq generator, shared by every phase.
+var seqNext atomic.Int64
+
+type seqKeys struct{}
+
+// next zero-pads the counter so the text keys sort numerically.
+func (seqKeys) next() (string, error) { return fmt.Sprintf("%020d", seqNext.Add(1)), nil }
+
+type hexKeys struct {
+	rnd *rand.Rand
+	buf [16]byte
+}
+
+func (k *hexKeys) next() (string, error) {
+	binary.LittleEndian.PutUint64(k.buf[:8], k.rnd.Uint64())
+	binary.LittleEndian.PutUint64(k.buf[8:], k.rnd.Uint64())
+	return hex.EncodeToString(k.buf[:]), nil
+}
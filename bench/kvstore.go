@@ -0,0 +1,376 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/dgraph-io/badger/v4"
+	bolt "go.etcd.io/bbolt"
+)
+
+// kvStore is the thin adapter the raw key-value baselines run the core kv
+// workloads through. Every write is synced before it returns, matching
+// the durability of the SQL engines' default settings.
+type kvStore interface {
+	// Put writes all pairs in one transaction.
+	Put(pairs [][2][]byte) error
+	Get(k []byte) ([]byte, error)
+	// Scan reads up to limit pairs with lo <= k <= hi and returns how many
+	// it read.
+	Scan(lo, hi []byte, limit int) (int, error)
+	Delete(k []byte) error
+	// LastKeys returns the n greatest keys, in descending order.
+	LastKeys(n int) ([]string, error)
+	Close() error
+}
+
+// isKVEngine reports whether engine is a raw key-value baseline rather
+// than an SQL engine.
+func isKVEngine(engine string) bool {
+	return engine == "badger" || engine == "pebble" || engine == "bbolt"
+}
+
+// openKV opens the key-value store of engine at dsn, a data directory
+// (or, for bbolt, a database file). fsyncDelay slows down pebble's syncs
+// (see IOThrottle).
+func openKV(engine, dsn string, fsyncDelay time.Duration) (kvStore, error) {
+	p := filepath.FromSlash(strings.TrimPrefix(dsn, "file:"))
+	if p == "" || p == "." {
+		return nil, fmt.Errorf("%s needs a data path as DSN", engine)
+	}
+	dir := p
+	if engine == "bbolt" {
+		dir = filepath.Dir(p)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	switch engine {
+	case "bbolt":
+		db, err := bolt.Open(p, 0600, &bolt.Options{Timeout: time.Second})
+		if err != nil {
+			return nil, err
+		}
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(boltBucket)
+			return err
+		})
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		return boltStore{db}, nil
+	case "badger":
+		db, err := badger.Open(badger.DefaultOptions(dir).WithSyncWrites(true).WithLogger(nil))
+		if err != nil {
+			return nil, err
+		}
+		return badgerStore{db}, nil
+	case "pebble":
+		opts := &pebble.Options{}
+		if fsyncDelay > 0 {
+			opts.FS = slowSyncFS{vfs.Default, fsyncDelay}
+		}
+		db, err := pebble.Open(dir, opts)
+		if err != nil {
+			return nil, err
+		}
+		return pebbleStore{db}, nil
+	}
+	return nil, fmt.Errorf("%s is not a key-value engine", engine)
+}
+
+// kvKeySnapshot is FetchKeySnapshot on a key-value store.
+func kvKeySnapshot(kv kvStore, n int) ([]string, error) {
+	keys, err := kv.LastKeys(n)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("snapshot is empty: no keys fetched")
+	}
+	return keys, nil
+}
+
+// kvPhase returns the key-value variant of a core kv workload; ok is false
+// for workloads without one. Inserts write TxBatch pairs per transaction
+// and spread its latency evenly over them, like pgx-native batches.
+func kvPhase(name string, cfg Config) (spec phaseSpec, ok bool, err error) {
+	var pl payloads
+	switch name {
+	case "insert", "update":
+		fixed := map[string]string{"insert": "payload", "update": "updated"}[name]
+		if pl, err = newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, fixed); err != nil {
+			return phaseSpec{}, false, err
+		}
+	case "select", "range", "delete":
+	default:
+		return phaseSpec{}, false, nil
+	}
+
+	if name == "insert" {
+		kg, err := newKeyGens(cfg.KeyGen, cfg.RandflakeSecret)
+		if err != nil {
+			return phaseSpec{}, false, err
+		}
+		batch := max(1, cfg.TxBatch)
+		return phaseSpec{name, func(s *suite) (WorkloadFunc, error) {
+			return opWorkload(name, true, func(_ context.Context, worker int) (opFunc, error) {
+				gen, err := kg(worker)
+				if err != nil {
+					return nil, err
+				}
+				pairs := make([][2][]byte, batch)
+				return func(_ context.Context, rnd *rand.Rand, clk Clock, observe func(time.Duration)) error {
+					for i := range pairs {
+						k, err := gen.next()
+						if err != nil {
+							return err
+						}
+						pairs[i] = [2][]byte{[]byte(k), pl.pick(rnd)}
+					}
+					start := clk.Now()
+					if err := s.kv.Put(pairs); err != nil {
+						return err
+					}
+					per := clk.Since(start) / time.Duration(batch)
+					for range batch {
+						observe(per)
+					}
+					return nil
+				}, nil
+			}), nil
+		}}, true, nil
+	}
+
+	return phaseSpec{name, func(s *suite) (WorkloadFunc, error) {
+		keys, err := s.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		write := name == "update" || name == "delete"
+		return opWorkload(name, write, func(context.Context, int) (opFunc, error) {
+			return kvKeyOp(s.kv, name, keys, pl), nil
+		}), nil
+	}}, true, nil
+}
+
+func kvKeyOp(kv kvStore, name string, keys []string, pl payloads) opFunc {
+	key := func(rnd *rand.Rand) []byte { return []byte(keys[rnd.Intn(len(keys))]) }
+
+	return func(_ context.Context, rnd *rand.Rand, clk Clock, observe func(time.Duration)) error {
+		start := clk.Now()
+		switch name {
+		case "select":
+			if _, err := kv.Get(key(rnd)); err != nil {
+				return err
+			}
+		case "range":
+			lo, hi := key(rnd), key(rnd)
+			if bytes.Compare(lo, hi) > 0 {
+				lo, hi = hi, lo
+			}
+			if _, err := kv.Scan(lo, hi, 100); err != nil {
+				return err
+			}
+		case "update":
+			if err := kv.Put([][2][]byte{{key(rnd), pl.pick(rnd)}}); err != nil {
+				return err
+			}
+		default: // delete
+			if err := kv.Delete(key(rnd)); err != nil {
+				return err
+			}
+		}
+		observe(clk.Since(start))
+		return nil
+	}
+}
+
+type badgerStore struct{ db *badger.DB }
+
+func (s badgerStore) Put(pairs [][2][]byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		for _, p := range pairs {
+			if err := txn.Set(p[0], p[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s badgerStore) Get(k []byte) (v []byte, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(k)
+		if err != nil {
+			return err
+		}
+		v, err = item.ValueCopy(nil)
+		return err
+	})
+	return v, err
+}
+
+func (s badgerStore) Scan(lo, hi []byte, limit int) (n int, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchSize = limit
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Seek(lo); it.Valid() && n < limit; it.Next() {
+			item := it.Item()
+			if bytes.Compare(item.Key(), hi) > 0 {
+				break
+			}
+			if err := item.Value(func([]byte) error { return nil }); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (s badgerStore) Delete(k []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error { return txn.Delete(k) })
+}
+
+func (s badgerStore) LastKeys(n int) (keys []string, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Reverse = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid() && len(keys) < n; it.Next() {
+			keys = append(keys, string(it.Item().Key()))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (s badgerStore) Close() error { return s.db.Close() }
+
+type pebbleStore struct{ db *pebble.DB }
+
+func (s pebbleStore) Put(pairs [][2][]byte) error {
+	b := s.db.NewBatch()
+	defer b.Close()
+	for _, p := range pairs {
+		if err := b.Set(p[0], p[1], nil); err != nil {
+			return err
+		}
+	}
+	return b.Commit(pebble.Sync)
+}
+
+func (s pebbleStore) Get(k []byte) ([]byte, error) {
+	v, closer, err := s.db.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return bytes.Clone(v), nil
+}
+
+func (s pebbleStore) Scan(lo, hi []byte, limit int) (int, error) {
+	it, err := s.db.NewIter(&pebble.IterOptions{LowerBound: lo, UpperBound: append(bytes.Clone(hi), 0)})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for valid := it.First(); valid && n < limit; valid = it.Next() {
+		if _, err := it.ValueAndErr(); err != nil {
+			_ = it.Close()
+			return n, err
+		}
+		n++
+	}
+	return n, it.Close()
+}
+
+func (s pebbleStore) Delete(k []byte) error { return s.db.Delete(k, pebble.Sync) }
+
+func (s pebbleStore) LastKeys(n int) ([]string, error) {
+	it, err := s.db.NewIter(nil)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for valid := it.Last(); valid && len(keys) < n; valid = it.Prev() {
+		keys = append(keys, string(it.Key()))
+	}
+	return keys, it.Close()
+}
+
+func (s pebbleStore) Close() error { return s.db.Close() }
+
+// boltBucket holds the kv pairs; bbolt has no keyspace outside buckets.
+var boltBucket = []byte("kv")
+
+// boltStore runs every write in its own read-write transaction; bbolt
+// serializes those, so concurrent writers queue on its single writer lock.
+type boltStore struct{ db *bolt.DB }
+
+func (s boltStore) Put(pairs [][2][]byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for _, p := range pairs {
+			if err := b.Put(p[0], p[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s boltStore) Get(k []byte) (v []byte, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		got := tx.Bucket(boltBucket).Get(k)
+		if got == nil {
+			return fmt.Errorf("key not found")
+		}
+		v = bytes.Clone(got)
+		return nil
+	})
+	return v, err
+}
+
+func (s boltStore) Scan(lo, hi []byte, limit int) (n int, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, _ := c.Seek(lo); k != nil && n < limit && bytes.Compare(k, hi) <= 0; k, _ = c.Next() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (s boltStore) Delete(k []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error { return tx.Bucket(boltBucket).Delete(k) })
+}
+
+func (s boltStore) LastKeys(n int) (keys []string, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, _ := c.Last(); k != nil && len(keys) < n; k, _ = c.Prev() {
+			keys = append(keys, string(k))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (s boltStore) Close() error { return s.db.Close() }
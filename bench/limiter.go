@@ -0,0 +1,50 @@
+package bench
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// writeLimiter bounds how many write operations run at once, independent of
+// the number of workers, and measures how many actually were in flight on
+// average. A nil sem means no bound; the measurement still happens.
+type writeLimiter struct {
+	sem   chan struct{}
+	held  int64 // total time writes held a slot, ns
+	clock Clock
+}
+
+func newWriteLimiter(limit int, clock Clock) *writeLimiter {
+	l := &writeLimiter{clock: clock}
+	if limit > 0 {
+		l.sem = make(chan struct{}, limit)
+	}
+	return l
+}
+
+// acquire waits for a write slot and returns the func that gives it back.
+func (l *writeLimiter) acquire(ctx context.Context) (func(), error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	start := l.clock.Now()
+	return func() {
+		atomic.AddInt64(&l.held, int64(l.clock.Since(start)))
+		if l.sem != nil {
+			<-l.sem
+		}
+	}, nil
+}
+
+// effective is the average number of writes in flight over elapsed.
+func (l *writeLimiter) effective(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&l.held)) / float64(elapsed)
+}
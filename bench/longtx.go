@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// longTxModes are the ways withLongTx keeps its transaction busy: idle
+// holds the snapshot open without doing anything (idle in transaction),
+// scan reads the whole kv table over and over inside it.
+var longTxModes = []string{"idle", "scan"}
+
+// withLongTx runs wf while one transaction, opened before the phase starts,
+// stays open for its whole duration. The snapshot (and on sqlite the WAL
+// read mark) it pins keeps MVCC garbage and WAL frames from being
+// reclaimed, so comparing against a plain run of wf shows what a forgotten
+// transaction costs each engine.
+func withLongTx(name, mode string, wf WorkloadFunc) WorkloadFunc {
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			res := newResult(name, ph)
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer tx.Rollback()
+		// a first read takes the snapshot; BEGIN alone is lazy on sqlite
+		// and postgres
+		var n int64
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM kv`).Scan(&n); err != nil {
+			res := newResult(name, ph)
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+
+		held, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if mode != "scan" {
+				<-held.Done()
+				return
+			}
+			for held.Err() == nil {
+				rows, err := tx.QueryContext(held, `SELECT k, v FROM kv`)
+				if err == nil {
+					_, err = drainRows(rows, nil)
+				}
+				if err != nil && held.Err() == nil {
+					log.Warn().Err(err).Msg("long transaction scan failed")
+					return
+				}
+			}
+		}()
+
+		res := wf(ctx, db, ph)
+		stop()
+		<-done
+		res.Workload = name
+		return res
+	}
+}
+
+func validLongTxMode(mode string) error {
+	for _, m := range longTxModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown long transaction mode %q (want idle|scan)", mode)
+}
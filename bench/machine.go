@@ -0,0 +1,130 @@
+package bench
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Machine fingerprints the host a run was measured on, so results from
+// different machines are flagged instead of compared as equals (see
+// Incomparable).
+type Machine struct {
+	CPUModel string `json:"cpu_model,omitempty"`
+	CPUs     int    `json:"cpus"`             // logical CPUs
+	Memory   int64  `json:"memory,omitempty"` // bytes of RAM
+	Disk     string `json:"disk,omitempty"`   // model of the disk holding the data path
+	// Fingerprint hashes the hardware fields above.
+	Fingerprint string `json:"fingerprint"`
+	// FsyncP50 and SeqWrite are a quick calibration of the data path's
+	// disk (embedded engines only): the median of calibrationSyncs 4 KiB
+	// writes each with its fsync (see timeFsyncs), and MiB/s of a
+	// calibrationSeqBytes write including its fsync.
+	FsyncP50 time.Duration `json:"fsync_p50,omitempty"`
+	SeqWrite float64       `json:"seq_write_mibps,omitempty"`
+}
+
+const (
+	calibrationSyncs    = 16
+	calibrationSeqBytes = 32 << 20
+	// calibrationSpread is how far apart two machines' calibrations may
+	// be, as a ratio, before their disks count as different.
+	calibrationSpread = 2.0
+)
+
+// newMachine fingerprints this host. With a data path it also names the
+// disk holding it and, if calibrate is set, times that disk.
+func newMachine(path string, calibrate bool) *Machine {
+	m := &Machine{CPUModel: cpuModel(), CPUs: runtime.NumCPU(), Memory: memoryTotal()}
+	if path != "" {
+		m.Disk = diskModel(path)
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%d|%s", m.CPUModel, m.CPUs, m.Memory, m.Disk))
+	m.Fingerprint = hex.EncodeToString(sum[:6])
+	if path != "" && calibrate {
+		m.FsyncP50, m.SeqWrite, _ = calibrateDisk(calibrationDir(path))
+	}
+	return m
+}
+
+// calibrateDisk times fsyncs and a sequential write in a scratch file in
+// dir.
+func calibrateDisk(dir string) (fsyncP50 time.Duration, seqMiBps float64, err error) {
+	f, err := os.CreateTemp(dir, ".calibrate-*")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	syncs, err := timeFsyncs(f, calibrationSyncs)
+	if err != nil {
+		return 0, 0, err
+	}
+	fsyncP50 = syncs.quantile(0.50)
+
+	chunk := make([]byte, 1<<20)
+	start := time.Now()
+	for range calibrationSeqBytes / len(chunk) {
+		if _, err := f.Write(chunk); err != nil {
+			return fsyncP50, 0, err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return fsyncP50, 0, err
+	}
+	return fsyncP50, float64(calibrationSeqBytes>>20) / time.Since(start).Seconds(), nil
+}
+
+func (m Machine) pretty() string {
+	out := fmt.Sprintf("%s, %d CPUs", m.CPUModel, m.CPUs)
+	if m.CPUModel == "" {
+		out = fmt.Sprintf("%d CPUs", m.CPUs)
+	}
+	if m.Memory > 0 {
+		out += ", " + fBytes(m.Memory) + " RAM"
+	}
+	if m.Disk != "" {
+		out += ", " + m.Disk
+	}
+	if m.FsyncP50 > 0 {
+		out += fmt.Sprintf(" (fsync P50=%s, seq write %.0fMiB/s)", fDur(m.FsyncP50), m.SeqWrite)
+	}
+	return out + " [" + m.Fingerprint + "]"
+}
+
+// Incomparable lists why results measured on machines a and b should not
+// be compared as equals: differing hardware, or disk calibrations more
+// than calibrationSpread apart. It is empty when they match or either is
+// unknown (reports from before fingerprinting).
+func Incomparable(a, b *Machine) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+	var out []string
+	if a.CPUModel != b.CPUModel {
+		out = append(out, fmt.Sprintf("CPU %q vs %q", a.CPUModel, b.CPUModel))
+	}
+	if a.CPUs != b.CPUs {
+		out = append(out, fmt.Sprintf("%d vs %d CPUs", a.CPUs, b.CPUs))
+	}
+	if a.Memory != b.Memory {
+		out = append(out, fmt.Sprintf("%s vs %s RAM", fBytes(a.Memory), fBytes(b.Memory)))
+	}
+	if a.Disk != b.Disk {
+		out = append(out, fmt.Sprintf("disk %q vs %q", a.Disk, b.Disk))
+	}
+	apart := func(x, y float64) bool {
+		return x > 0 && y > 0 && max(x, y)/min(x, y) > calibrationSpread
+	}
+	if apart(float64(a.FsyncP50), float64(b.FsyncP50)) {
+		out = append(out, fmt.Sprintf("fsync P50 %s vs %s", fDur(a.FsyncP50), fDur(b.FsyncP50)))
+	}
+	if apart(a.SeqWrite, b.SeqWrite) {
+		out = append(out, fmt.Sprintf("seq write %.0f vs %.0f MiB/s", a.SeqWrite, b.SeqWrite))
+	}
+	return out
+}
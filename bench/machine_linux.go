@@ -0,0 +1,66 @@
+//go:build linux
+
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuModel is the first "model name" of /proc/cpuinfo.
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), ":")
+		if ok && strings.TrimSpace(k) == "model name" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// memoryTotal is MemTotal of /proc/meminfo in bytes.
+func memoryTotal() int64 {
+	b, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(line, "MemTotal:"); ok {
+			kb, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(v), " kB"), 10, 64)
+			return kb << 10
+		}
+	}
+	return 0
+}
+
+// diskModel is the model of the disk holding path, from sysfs.
+func diskModel(path string) string {
+	var st unix.Stat_t
+	// the database file may not exist yet before the first phase
+	if err := unix.Stat(path, &st); err != nil {
+		if err := unix.Stat(filepath.Dir(path), &st); err != nil {
+			return ""
+		}
+	}
+	if unix.Major(st.Dev) == 0 {
+		return "" // tmpfs, overlay, ...
+	}
+	dev := wholeDisk(fmt.Sprintf("%d:%d", unix.Major(st.Dev), unix.Minor(st.Dev)))
+	b, err := os.ReadFile(filepath.Join("/sys/dev/block", dev, "device", "model"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
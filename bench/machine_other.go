@@ -0,0 +1,9 @@
+//go:build !linux
+
+package bench
+
+func cpuModel() string { return "" }
+
+func memoryTotal() int64 { return 0 }
+
+func diskModel(_ string) string { return "" }
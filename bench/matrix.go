@@ -0,0 +1,351 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math/rand"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Matrix is an experiment over the cartesian product of configuration
+// dimensions. Dims maps a Config field, named in snake_case (tx_batch for
+// TxBatch, value_size for ValueSize), to the values it takes; any field
+// of a string, bool, number, duration or string list type can vary.
+type Matrix struct {
+	Dims   map[string][]string
+	Sample int // run this many cells drawn at random; 0 = all of them
+	Seed   int64
+	// ForEngine, if set, completes a cell's config for the engine the
+	// engine dimension set (its DSN, ...) before the other dimensions
+	// apply.
+	ForEngine func(cfg *Config) error
+	// State is a file the report is saved to after every cell. If it
+	// exists, the run resumes it: cells it holds a report of are not run
+	// again.
+	State string
+}
+
+// MatrixCell is one combination of dimension values and its run.
+type MatrixCell struct {
+	Params map[string]string `json:"params"`
+	Config string            `json:"config,omitempty"` // hash of the cell's Config, to resume only what did not change
+	Report *Report           `json:"report,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// MatrixReport consolidates the cells of a matrix run.
+type MatrixReport struct {
+	Dims  []string     `json:"dims"`  // in column order
+	Total int          `json:"total"` // cells in the full product; Cells may be a sample
+	Cells []MatrixCell `json:"cells"`
+}
+
+// RunMatrix runs the suite of base once per cell of m. A failed cell is
+// recorded with its error and the experiment goes on; only a canceled ctx
+// stops it early. With m.State set, a rerun after an interruption picks up
+// where the last one stopped, running the failed and missing cells only.
+func RunMatrix(ctx context.Context, base Config, m Matrix) (*MatrixReport, error) {
+	dims := m.dims()
+	cells, total, err := m.cells(dims)
+	if err != nil {
+		return nil, err
+	}
+	done, err := loadMatrixState(m.State, dims)
+	if err != nil {
+		return nil, err
+	}
+	rep := &MatrixReport{Dims: dims, Total: total}
+	for i, params := range cells {
+		if ctx.Err() != nil {
+			return rep, ctx.Err()
+		}
+		cell := MatrixCell{Params: params}
+		cfg, err := m.apply(base, params)
+		if err == nil {
+			cell.Config = configHash(cfg)
+			if prev, ok := done[cell.label(dims)]; ok {
+				if prev.Config != cell.Config {
+					return nil, fmt.Errorf("matrix state %s was saved with a different configuration for %s; remove it to start over", m.State, cell.label(dims))
+				}
+				log.Info().Int("cell", i+1).Int("cells", len(cells)).Str("params", cell.label(dims)).Msg("matrix cell already done")
+				rep.Cells = append(rep.Cells, prev)
+				continue
+			}
+			log.Info().Int("cell", i+1).Int("cells", len(cells)).Str("params", cell.label(dims)).Msg("matrix cell start")
+			cell.Report, err = Run(ctx, cfg)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("params", cell.label(dims)).Msg("matrix cell failed")
+			cell.Error = err.Error()
+		}
+		rep.Cells = append(rep.Cells, cell)
+		if err := rep.save(m.State); err != nil {
+			return rep, fmt.Errorf("saving matrix state: %w", err)
+		}
+	}
+	return rep, nil
+}
+
+// loadMatrixState reads the completed cells of the matrix state file path,
+// by label, if there is one; failed cells are left to run again.
+func loadMatrixState(path string, dims []string) (map[string]MatrixCell, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var prev MatrixReport
+	if err := json.Unmarshal(b, &prev); err != nil {
+		return nil, fmt.Errorf("matrix state %s: %w", path, err)
+	}
+	if !slices.Equal(prev.Dims, dims) {
+		return nil, fmt.Errorf("matrix state %s has dimensions %s, not %s; remove it to start over",
+			path, strings.Join(prev.Dims, ","), strings.Join(dims, ","))
+	}
+	done := make(map[string]MatrixCell, len(prev.Cells))
+	for _, c := range prev.Cells {
+		if c.Report != nil && c.Error == "" {
+			done[c.label(dims)] = c
+		}
+	}
+	log.Info().Str("path", path).Int("cells", len(done)).Msg("resuming matrix")
+	return done, nil
+}
+
+// save writes r to path, if set, through a temporary file, so an
+// interrupted write leaves the previous state intact.
+func (r MatrixReport) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(r.JSON()+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// dims orders the dimensions: engine first, the rest by name.
+func (m Matrix) dims() []string {
+	dims := slices.Sorted(maps.Keys(m.Dims))
+	if i := slices.Index(dims, "engine"); i > 0 {
+		dims = append([]string{"engine"}, slices.Delete(dims, i, i+1)...)
+	}
+	return dims
+}
+
+// cells expands the product of dims in order, the last dimension varying
+// fastest, and samples it down to m.Sample cells (kept in that order).
+func (m Matrix) cells(dims []string) (cells []map[string]string, total int, err error) {
+	total = 1
+	for _, d := range dims {
+		if len(m.Dims[d]) == 0 {
+			return nil, 0, fmt.Errorf("matrix dimension %s has no values", d)
+		}
+		total *= len(m.Dims[d])
+	}
+	idx := make([]int, total)
+	for i := range idx {
+		idx[i] = i
+	}
+	if m.Sample > 0 && m.Sample < total {
+		idx = rand.New(rand.NewSource(m.Seed)).Perm(total)[:m.Sample]
+		slices.Sort(idx)
+	}
+	for _, n := range idx {
+		params := make(map[string]string, len(dims))
+		for i := len(dims) - 1; i >= 0; i-- {
+			vals := m.Dims[dims[i]]
+			params[dims[i]] = vals[n%len(vals)]
+			n /= len(vals)
+		}
+		cells = append(cells, params)
+	}
+	return cells, total, nil
+}
+
+// apply returns base with the cell's params set, labeled with them as
+// tags.
+func (m Matrix) apply(base Config, params map[string]string) (Config, error) {
+	cfg := base
+	cfg.Tags = maps.Clone(base.Tags)
+	if cfg.Tags == nil {
+		cfg.Tags = make(map[string]string, len(params))
+	}
+	if e, ok := params["engine"]; ok {
+		engine, err := NormalizeEngine(e)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Engine = engine
+		if m.ForEngine != nil {
+			if err := m.ForEngine(&cfg); err != nil {
+				return cfg, err
+			}
+		}
+	}
+	for key, val := range params {
+		if key != "engine" {
+			if err := setConfigField(&cfg, key, val); err != nil {
+				return cfg, err
+			}
+		}
+		cfg.Tags[key] = val
+	}
+	return cfg, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setConfigField sets the Config field key names in snake_case to val.
+func setConfigField(cfg *Config, key, val string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	name := strings.ReplaceAll(key, "_", "")
+	f := v.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+	if !f.IsValid() || !f.CanSet() {
+		return fmt.Errorf("matrix dimension %s: no such config field", key)
+	}
+	var err error
+	switch {
+	case f.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(val)
+		f.SetInt(int64(d))
+	case f.Kind() == reflect.String:
+		f.SetString(val)
+	case f.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(val)
+		f.SetBool(b)
+	case f.CanInt():
+		var n int64
+		n, err = strconv.ParseInt(val, 10, 64)
+		f.SetInt(n)
+	case f.Kind() == reflect.Float64:
+		var x float64
+		x, err = strconv.ParseFloat(val, 64)
+		f.SetFloat(x)
+	case f.Type() == reflect.TypeOf([]string(nil)):
+		f.Set(reflect.ValueOf(strings.Split(val, ",")))
+	default:
+		return fmt.Errorf("matrix dimension %s: cannot vary a %s", key, f.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("matrix dimension %s: %w", key, err)
+	}
+	return nil
+}
+
+// label renders the cell's params as key=value pairs in dims order.
+func (c MatrixCell) label(dims []string) string {
+	parts := make([]string, len(dims))
+	for i, d := range dims {
+		parts[i] = d + "=" + c.Params[d]
+	}
+	return strings.Join(parts, " ")
+}
+
+// Pretty tabulates every workload across the cells: ops/s, p50 and p99
+// per combination, with the cell of the most ops/s marked.
+func (r MatrixReport) Pretty() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Matrix\t\t: %d of %d cells (%s)\n", len(r.Cells), r.Total, strings.Join(r.Dims, " × "))
+
+	widths := make([]int, len(r.Dims))
+	for i, d := range r.Dims {
+		widths[i] = len(d)
+		for _, c := range r.Cells {
+			widths[i] = max(widths[i], len(c.Params[d]))
+		}
+	}
+	row := func(vals []string) string {
+		var s strings.Builder
+		for i, v := range vals {
+			fmt.Fprintf(&s, "%-*s  ", widths[i], v)
+		}
+		return s.String()
+	}
+
+	var workloads []string
+	for _, c := range r.Cells {
+		if c.Report == nil {
+			continue
+		}
+		for _, res := range c.Report.Results {
+			if !slices.Contains(workloads, res.Workload) {
+				workloads = append(workloads, res.Workload)
+			}
+		}
+	}
+	for _, w := range workloads {
+		fmt.Fprintf(&b, "\nWorkload\t: %s\n", w)
+		fmt.Fprintf(&b, "  %s%12s %10s %10s\n", row(r.Dims), "ops/s", "p50", "p99")
+		best, bestOps := -1, 0.0
+		lines := make([]string, len(r.Cells))
+		for i, c := range r.Cells {
+			vals := make([]string, len(r.Dims))
+			for j, d := range r.Dims {
+				vals[j] = c.Params[d]
+			}
+			res := c.result(w)
+			switch {
+			case c.Error != "":
+				lines[i] = fmt.Sprintf("  %serror: %s", row(vals), c.Error)
+			case res == nil:
+				lines[i] = fmt.Sprintf("  %s(not run)", row(vals))
+			case res.Skipped:
+				lines[i] = fmt.Sprintf("  %sskipped: %s", row(vals), res.SkipReason)
+			default:
+				ops := opsPerSec(*res)
+				lines[i] = fmt.Sprintf("  %s%12.1f %10s %10s", row(vals), ops, fDur(res.P50), fDur(res.P99))
+				if ops > bestOps {
+					best, bestOps = i, ops
+				}
+			}
+		}
+		for i, l := range lines {
+			if i == best {
+				l += "  *"
+			}
+			b.WriteString(l + "\n")
+		}
+		if best >= 0 {
+			fmt.Fprintf(&b, "Best\t\t: %s (%.1f ops/s)\n", r.Cells[best].label(r.Dims), bestOps)
+		}
+	}
+	return b.String()
+}
+
+// result is the cell's result of workload, nil if it has none.
+func (c MatrixCell) result(workload string) *Result {
+	if c.Report == nil {
+		return nil
+	}
+	for i := range c.Report.Results {
+		if c.Report.Results[i].Workload == workload {
+			return &c.Report.Results[i]
+		}
+	}
+	return nil
+}
+
+func (r MatrixReport) JSON() string {
+	j, _ := json.MarshalIndent(r, "", "  ")
+	return string(j)
+}
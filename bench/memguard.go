@@ -0,0 +1,45 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"time"
+)
+
+// memCheckEvery is how often a phase with Phase.MemLimit looks at the
+// memory of the process.
+const memCheckEvery = 250 * time.Millisecond
+
+// watchMemory samples the process's resident memory until ctx ends and
+// calls cancel once it exceeds limit. Latency samples are kept in full, so
+// a long phase grows the process; stopping there keeps a CI machine from
+// killing the whole run instead.
+func (r *Result) watchMemory(ctx context.Context, cancel context.CancelFunc, limit int64) {
+	t := r.clock.NewTicker(memCheckEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C():
+		}
+		if rss := processMemory(); rss > limit {
+			r.memReason = fmt.Sprintf("process memory %s exceeded the limit of %s", fBytes(rss), fBytes(limit))
+			if r.phase.shared {
+				r.memReason += " (with the parallel suites' memory)"
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// goMemory is the memory the Go runtime holds from the OS, for platforms
+// without a resident set size; unlike it, memory allocated by C code (the
+// sqlite driver) is left out.
+func goMemory() int64 {
+	s := []metrics.Sample{{Name: "/memory/classes/total:bytes"}, {Name: "/memory/classes/heap/released:bytes"}}
+	metrics.Read(s)
+	return int64(s[0].Value.Uint64() - s[1].Value.Uint64())
+}
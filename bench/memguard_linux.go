@@ -0,0 +1,26 @@
+//go:build linux
+
+package bench
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processMemory is the resident set size from /proc/self/statm.
+func processMemory() int64 {
+	b, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return goMemory()
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return goMemory()
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return goMemory()
+	}
+	return pages * int64(os.Getpagesize())
+}
@@ -0,0 +1,5 @@
+//go:build !linux
+
+package bench
+
+func processMemory() int64 { return goMemory() }
@@ -0,0 +1,340 @@
+package bench
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// MergeResults combines results of one workload measured side by side,
+// such as the shards of a phase run by several agents, into the result of
+// the whole: counts and workers add up, latencies are pooled and the
+// duration is the longest one, so a shard that stopped early lowers the
+// combined ops/s instead of inflating it. Results of this process merge
+// exactly; decoded ones only keep percentiles and the CDF, which are
+// pooled weighted by ops, and per-second p50/p99 become upper bounds.
+func MergeResults(rs []Result) Result { return mergeResults(rs, false) }
+
+// MergeRepeats combines repeated runs of one workload, which ran one after
+// another: like MergeResults, but durations, timelines and stalls follow
+// each other and concurrency is that of a single run, so ops/s is the
+// average over all repeats.
+func MergeRepeats(rs []Result) Result { return mergeResults(rs, true) }
+
+// pooledSample is an operation latency and the (renumbered) worker that
+// measured it.
+type pooledSample struct {
+	d      time.Duration
+	worker int32
+}
+
+func mergeResults(rs []Result, sequential bool) Result {
+	var live []Result
+	for _, r := range rs {
+		if !r.Skipped {
+			live = append(live, r)
+		}
+	}
+	if len(live) == 0 {
+		if len(rs) == 0 {
+			return Result{}
+		}
+		return rs[0]
+	}
+
+	m := Result{Workload: live[0].Workload, Parent: live[0].Parent, Partitioned: live[0].Partitioned, ReadDSN: live[0].ReadDSN}
+	exact := true // every result still has its latencies
+	var (
+		secs    [][]pooledSample
+		cdfs    []Result
+		workers []WorkerStats
+		writers float64         // EffectiveWriters × Duration, for repeats
+		kept    []time.Duration // latencies after each result's trimmed start
+		offset  time.Duration
+		secOff  int
+	)
+	for _, r := range live {
+		exact = exact && (r.Ops == 0 || len(r.hist.samples) > 0)
+
+		m.Ops += r.Ops
+		m.Errors += r.Errors
+		m.Canceled += r.Canceled
+		m.CommitErrors += r.CommitErrors
+		m.Timeouts += r.Timeouts
+		m.RowsRead += r.RowsRead
+		m.RowsDeleted += r.RowsDeleted
+		for k, n := range r.ErrorKinds {
+			if m.ErrorKinds == nil {
+				m.ErrorKinds = make(map[string]int64)
+			}
+			m.ErrorKinds[k] += n
+		}
+		if r.Aborted && !m.Aborted {
+			m.Aborted, m.AbortReason = true, r.AbortReason
+		}
+		if r.IO != nil {
+			io := *r.IO
+			if m.IO != nil {
+				io = m.IO.add(io)
+			}
+			m.IO = &io
+		}
+		if s := r.Snapshot; s != nil {
+			if m.Snapshot == nil {
+				m.Snapshot = &SnapshotStats{Isolation: s.Isolation}
+			}
+			m.Snapshot.Txns += s.Txns
+			m.Snapshot.Drifted += s.Drifted
+			m.Snapshot.Errors += s.Errors
+			m.Snapshot.MaxDrift = max(m.Snapshot.MaxDrift, s.MaxDrift)
+		}
+		if s := r.Savepoints; s != nil {
+			if m.Savepoints == nil {
+				m.Savepoints = &SavepointStats{}
+			}
+			m.Savepoints.Checks += s.Checks
+			m.Savepoints.Violations += s.Violations
+			m.Savepoints.Errors += s.Errors
+		}
+		if c := r.Conflicts; c != nil {
+			if m.Conflicts == nil {
+				m.Conflicts = &ConflictStats{}
+			}
+			m.Conflicts.Duplicates += c.Duplicates
+			m.Conflicts.Detected += c.Detected
+			m.Conflicts.Unclassed += c.Unclassed
+			m.Conflicts.Accepted += c.Accepted
+		}
+		if h := r.Hotspot; h != nil {
+			if m.Hotspot == nil {
+				m.Hotspot = &HotspotStats{HotKeys: h.HotKeys, HotPct: h.HotPct}
+			}
+			m.Hotspot.Retries += h.Retries
+			m.Hotspot.GaveUp += h.GaveUp
+		}
+		if c := r.Cancel; c != nil {
+			if m.Cancel == nil {
+				m.Cancel = &CancelStats{}
+			}
+			m.Cancel.Fired += c.Fired
+			m.Cancel.Honored += c.Honored
+			m.Cancel.Finished += c.Finished
+			m.Cancel.Latency = mergeStats(m.Cancel.Latency, c.Latency)
+			m.Cancel.LeakedConns += c.LeakedConns
+			m.Cancel.LeakedGoroutines += c.LeakedGoroutines
+		}
+		if f := r.FK; f != nil {
+			if m.FK == nil {
+				m.FK = &FKStats{}
+			}
+			m.FK.Probes += f.Probes
+			m.FK.Unenforced += f.Unenforced
+			if f.Orphans != nil {
+				n := *f.Orphans
+				if m.FK.Orphans != nil {
+					n += *m.FK.Orphans
+				}
+				m.FK.Orphans = &n
+			}
+		}
+		m.Samples = append(m.Samples, r.Samples...)
+		if len(r.CDF) > 0 {
+			cdfs = append(cdfs, r)
+		}
+		m.hist.samples = append(m.hist.samples, r.hist.samples...)
+		m.prepHist.samples = append(m.prepHist.samples, r.prepHist.samples...)
+		m.deadHist.samples = append(m.deadHist.samples, r.deadHist.samples...)
+		m.firstHist.samples = append(m.firstHist.samples, r.firstHist.samples...)
+		if t := r.Trimmed; t != nil {
+			trimmed := *t
+			if m.Trimmed != nil {
+				trimmed.Dropped = max(trimmed.Dropped, m.Trimmed.Dropped)
+				trimmed.LatencyStats = *mergeStats(&m.Trimmed.LatencyStats, &t.LatencyStats)
+			}
+			m.Trimmed = &trimmed
+			if n := int(t.Dropped / time.Second); n < len(r.secStarts) {
+				kept = append(kept, r.hist.samples[r.secStarts[n]:]...)
+			}
+		}
+		m.Prepare = mergeStats(m.Prepare, r.Prepare)
+		m.Deadlock = mergeStats(m.Deadlock, r.Deadlock)
+		m.FirstRow = mergeStats(m.FirstRow, r.FirstRow)
+
+		// a sample's second and worker within the merged phase
+		wOff := int32(m.Concurrency)
+		if sequential {
+			wOff = 0
+		}
+		for i, lo := range r.secStarts {
+			hi := len(r.hist.samples)
+			if i+1 < len(r.secStarts) {
+				hi = r.secStarts[i+1]
+			}
+			for len(secs) <= secOff+i {
+				secs = append(secs, nil)
+			}
+			for j := lo; j < hi; j++ {
+				secs[secOff+i] = append(secs[secOff+i], pooledSample{r.hist.samples[j], r.sampleWorker[j] + wOff})
+			}
+		}
+		for i, s := range r.Timeline {
+			if sequential {
+				m.Timeline = append(m.Timeline, s)
+				continue
+			}
+			if i == len(m.Timeline) {
+				m.Timeline = append(m.Timeline, SecondStats{})
+			}
+			t := &m.Timeline[i]
+			t.Ops += s.Ops
+			t.P50, t.P99 = max(t.P50, s.P50), max(t.P99, s.P99)
+		}
+		if f := r.Fairness; f != nil {
+			if sequential {
+				for i, w := range f.Workers {
+					if i == len(workers) {
+						workers = append(workers, WorkerStats{})
+					}
+					workers[i].Ops += w.Ops
+					workers[i].P50, workers[i].P99 = max(workers[i].P50, w.P50), max(workers[i].P99, w.P99)
+				}
+			} else {
+				workers = append(workers, f.Workers...)
+			}
+		}
+		for _, s := range r.Stalls {
+			s.Offset += offset
+			m.Stalls = append(m.Stalls, s)
+		}
+
+		if sequential {
+			m.Concurrency = max(m.Concurrency, r.Concurrency)
+			m.WriteLimit = max(m.WriteLimit, r.WriteLimit)
+			m.PeakHeap = max(m.PeakHeap, r.PeakHeap)
+			writers += r.EffectiveWriters * r.Duration.Seconds()
+			m.Duration += r.Duration
+			m.Extension += r.Extension
+			offset += r.Duration
+			secOff += len(r.secStarts)
+		} else {
+			m.Concurrency += r.Concurrency
+			m.WriteLimit += r.WriteLimit
+			m.PeakHeap += r.PeakHeap
+			m.EffectiveWriters += r.EffectiveWriters
+			m.Duration = max(m.Duration, r.Duration)
+			m.Extension = max(m.Extension, r.Extension)
+		}
+	}
+	if sequential && m.Duration > 0 {
+		m.EffectiveWriters = writers / m.Duration.Seconds()
+	}
+	if len(workers) > 0 {
+		m.Fairness = fairnessOf(workers)
+	}
+
+	if exact {
+		m.hist.samples, m.sampleWorker = nil, nil
+		for _, s := range secs {
+			m.secStarts = append(m.secStarts, len(m.hist.samples))
+			for _, p := range s {
+				m.hist.add(p.d)
+				m.sampleWorker = append(m.sampleWorker, p.worker)
+			}
+		}
+		m.P50 = m.hist.quantile(0.50)
+		m.P95 = m.hist.quantile(0.95)
+		m.P99 = m.hist.quantile(0.99)
+		m.CDF = m.hist.cdf(cdfQuantiles)
+		if s := (&histogram{kept}).stats(); m.Trimmed != nil && s != nil {
+			m.Trimmed.LatencyStats = *s
+		}
+		m.Timeline = m.timeline()
+		m.Fairness = m.fairness()
+		m.Prepare = m.prepHist.stats()
+		m.Deadlock = m.deadHist.stats()
+		m.FirstRow = m.firstHist.stats()
+	} else {
+		m.hist, m.prepHist, m.deadHist, m.firstHist = histogram{}, histogram{}, histogram{}, histogram{}
+		if m.CDF = pooledCDF(cdfs, cdfQuantiles); m.CDF != nil {
+			m.P50, m.P95, m.P99 = pooledQuantile(m.CDF, 0.50), pooledQuantile(m.CDF, 0.95), pooledQuantile(m.CDF, 0.99)
+		} else {
+			for _, r := range live {
+				m.P50, m.P95, m.P99 = max(m.P50, r.P50), max(m.P95, r.P95), max(m.P99, r.P99)
+			}
+		}
+	}
+
+	// per-operation-type parts merge by name
+	var names []string
+	parts := make(map[string][]Result)
+	for _, r := range live {
+		for _, p := range r.byOp {
+			if _, ok := parts[p.Workload]; !ok {
+				names = append(names, p.Workload)
+			}
+			parts[p.Workload] = append(parts[p.Workload], p)
+		}
+	}
+	for _, n := range names {
+		m.byOp = append(m.byOp, mergeResults(parts[n], sequential))
+	}
+	return m
+}
+
+// mergeStats pools two secondary latency summaries; without the latencies
+// themselves the percentiles are upper bounds.
+func mergeStats(a, b *LatencyStats) *LatencyStats {
+	if a == nil || b == nil {
+		if a == nil {
+			a = b
+		}
+		if a == nil {
+			return nil
+		}
+		c := *a
+		return &c
+	}
+	return &LatencyStats{Count: a.Count + b.Count, P50: max(a.P50, b.P50), P95: max(a.P95, b.P95), P99: max(a.P99, b.P99)}
+}
+
+// pooledCDF is the CDF, at n quantiles, of the operations of rs, each
+// point of a result's CDF standing for an equal share of its ops.
+func pooledCDF(rs []Result, n int) []time.Duration {
+	type point struct {
+		d time.Duration
+		w float64
+	}
+	var (
+		points []point
+		total  float64
+	)
+	for _, r := range rs {
+		w := float64(r.Ops) / float64(len(r.CDF))
+		for _, d := range r.CDF {
+			points = append(points, point{d, w})
+		}
+		total += float64(r.Ops)
+	}
+	if total == 0 {
+		return nil
+	}
+	slices.SortFunc(points, func(a, b point) int { return cmp.Compare(a.d, b.d) })
+	out := make([]time.Duration, n)
+	var cum float64
+	j := 0
+	for i := range out {
+		want := total * float64(i+1) / float64(n)
+		for j < len(points)-1 && cum+points[j].w < want {
+			cum += points[j].w
+			j++
+		}
+		out[i] = points[j].d
+	}
+	return out
+}
+
+// pooledQuantile reads quantile q off a CDF as built by histogram.cdf.
+func pooledQuantile(cdf []time.Duration, q float64) time.Duration {
+	i := int(q*float64(len(cdf))+0.5) - 1
+	return cdf[max(0, min(i, len(cdf)-1))]
+}
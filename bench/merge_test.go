@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// spread is secs seconds of n operations each, from base up by step.
+func spread(secs, n int, base, step time.Duration) [][]time.Duration {
+	out := make([][]time.Duration, secs)
+	for s := range out {
+		for i := range n {
+			out[s] = append(out[s], base+time.Duration(s*n+i)*step)
+		}
+	}
+	return out
+}
+
+// decoded is r as read back from a report, without its latencies.
+func decoded(t *testing.T, r Result) Result {
+	t.Helper()
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Result
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMergeOpsPerSec(t *testing.T) {
+	long := SyntheticResult("insert", 2, spread(3, 100, time.Millisecond, time.Microsecond))
+	short := SyntheticResult("insert", 2, spread(1, 100, 2*time.Millisecond, time.Microsecond))
+
+	for _, tc := range []struct {
+		name        string
+		merge       func([]Result) Result
+		concurrency int
+		duration    time.Duration
+		opsPerSec   float64
+		seconds     int
+	}{
+		// side by side: the short shard lowers ops/s over the longest duration
+		{"results", MergeResults, 4, 3 * time.Second, 400.0 / 3, 3},
+		// one after another: ops/s is the average over all repeats
+		{"repeats", MergeRepeats, 2, 4 * time.Second, 100, 4},
+	} {
+		for _, in := range [][]Result{{long, short}, {decoded(t, long), decoded(t, short)}} {
+			m := tc.merge(in)
+			if m.Ops != 400 || m.Concurrency != tc.concurrency || m.Duration != tc.duration {
+				t.Errorf("%s: Ops, Concurrency, Duration = %d, %d, %s; want 400, %d, %s",
+					tc.name, m.Ops, m.Concurrency, m.Duration, tc.concurrency, tc.duration)
+			}
+			if got := opsPerSec(m); math.Abs(got-tc.opsPerSec) > 1e-9 {
+				t.Errorf("%s: ops/s = %.2f, want %.2f", tc.name, got, tc.opsPerSec)
+			}
+			if len(m.Timeline) != tc.seconds {
+				t.Errorf("%s: %d seconds in the timeline, want %d", tc.name, len(m.Timeline), tc.seconds)
+			}
+			var ops int64
+			for _, s := range m.Timeline {
+				ops += s.Ops
+			}
+			if ops != m.Ops {
+				t.Errorf("%s: the timeline counts %d ops, want %d", tc.name, ops, m.Ops)
+			}
+		}
+	}
+}
+
+func TestMergePercentiles(t *testing.T) {
+	// 300 fast operations and 100 slow ones, far apart
+	fastSecs, slowSecs := spread(3, 100, time.Microsecond, time.Microsecond), spread(1, 100, time.Millisecond, time.Microsecond)
+	fast, slow := SyntheticResult("select", 2, fastSecs), SyntheticResult("select", 2, slowSecs)
+	want := histogram{slices.Concat(slices.Concat(fastSecs...), slices.Concat(slowSecs...))}
+
+	for _, merge := range []func([]Result) Result{MergeResults, MergeRepeats} {
+		exact := merge([]Result{fast, slow})
+		for _, c := range []struct {
+			q   float64
+			got time.Duration
+		}{{0.50, exact.P50}, {0.95, exact.P95}, {0.99, exact.P99}} {
+			if w := want.quantile(c.q); c.got != w {
+				t.Errorf("exact p%.0f = %s, want %s", c.q*100, c.got, w)
+			}
+		}
+
+		// decoded results only keep their CDFs, pooled weighted by ops
+		m := merge([]Result{decoded(t, fast), decoded(t, slow)})
+		if len(m.hist.samples) != 0 || len(m.CDF) != cdfQuantiles {
+			t.Fatalf("decoded: %d latencies and %d CDF points, want 0 and %d", len(m.hist.samples), len(m.CDF), cdfQuantiles)
+		}
+		for _, c := range []struct {
+			name      string
+			got, want time.Duration
+		}{{"p50", m.P50, exact.P50}, {"p95", m.P95, exact.P95}, {"p99", m.P99, exact.P99}} {
+			// a CDF point stands for up to 3 of the 400 operations
+			if d := c.got - c.want; d < -3*time.Microsecond || d > 3*time.Microsecond {
+				t.Errorf("decoded %s = %s, want %s within 3µs", c.name, c.got, c.want)
+			}
+		}
+	}
+
+	// without CDFs, the percentiles are the slowest result's
+	a, b := decoded(t, fast), decoded(t, slow)
+	a.CDF, b.CDF = nil, nil
+	if m := MergeResults([]Result{a, b}); m.P50 != slow.P50 || m.P99 != slow.P99 {
+		t.Errorf("without CDFs: p50, p99 = %s, %s; want %s, %s", m.P50, m.P99, slow.P50, slow.P99)
+	}
+}
+
+func TestMergeByOp(t *testing.T) {
+	part := func(name string, n int) Result {
+		return SyntheticResult(name, 1, spread(1, n, time.Millisecond, time.Microsecond))
+	}
+	a := SyntheticResult("mixed", 1, spread(1, 30, time.Millisecond, time.Microsecond))
+	a.byOp = []Result{part("read", 20), part("write", 10)}
+	b := SyntheticResult("mixed", 1, spread(1, 12, time.Millisecond, time.Microsecond))
+	b.byOp = []Result{part("write", 5), part("scan", 7)}
+
+	for _, merge := range []func([]Result) Result{MergeResults, MergeRepeats} {
+		m := merge([]Result{a, b})
+		var got []string
+		for _, p := range m.byOp {
+			got = append(got, p.Workload)
+		}
+		if want := []string{"read", "write", "scan"}; !slices.Equal(got, want) {
+			t.Fatalf("parts = %v, want %v", got, want)
+		}
+		for i, want := range []int64{20, 15, 7} {
+			if p := m.byOp[i]; p.Ops != want {
+				t.Errorf("%s: Ops = %d, want %d", p.Workload, p.Ops, want)
+			}
+		}
+	}
+}
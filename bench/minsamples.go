@@ -0,0 +1,56 @@
+package bench
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// sampleCheckEvery is how often an extended phase checks whether it has
+// reached Phase.MinSamples.
+const sampleCheckEvery = 100 * time.Millisecond
+
+// maxDuration is how long a phase with MinSamples may run in all.
+func (ph Phase) maxDuration() time.Duration {
+	if ph.MaxDuration > 0 {
+		return ph.MaxDuration
+	}
+	return 5 * ph.Duration
+}
+
+// watchSamples ends the phase at Phase.Duration if it has Phase.MinSamples
+// operations by then, and otherwise as soon as it has them; the phase's
+// deadline, Phase.maxDuration, ends it short of them. It sets r.extended
+// when the phase ran past Duration.
+func (r *Result) watchSamples(ctx context.Context, cancel context.CancelFunc) {
+	enough := func() bool { return atomic.LoadInt64(&r.Ops) >= int64(r.phase.MinSamples) }
+
+	due := make(chan struct{})
+	stop := r.clock.AfterFunc(r.phase.Duration, func() { close(due) })
+	defer stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-due:
+	}
+	if enough() {
+		cancel()
+		return
+	}
+	r.extended = true
+	r.log.Info().Int64("ops", atomic.LoadInt64(&r.Ops)).Int("min_samples", r.phase.MinSamples).Msg("extending phase until min samples")
+
+	t := r.clock.NewTicker(sampleCheckEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C():
+		}
+		if enough() {
+			cancel()
+			return
+		}
+	}
+}
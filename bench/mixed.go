@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// mixedWorkload interleaves point reads and updates on the snapshot keys;
+// each operation is a read with probability readPct/100. Besides the
+// combined numbers it reports mixed/read and mixed/write on their own, so
+// one slow operation class cannot hide inside the aggregate p99.
+func mixedWorkload(dollar bool, keys []string, readPct int, pl payloads) WorkloadFunc {
+	readQ := bind(dollar, `SELECT v FROM kv WHERE k = ?`)
+	writeQ := bind(dollar, `UPDATE kv SET v = ? WHERE k = ?`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("mixed", ph)
+		reads, writes := res.split("read"), res.split("write")
+		if len(keys) == 0 {
+			return res.finalize()
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		readStmt, err := db.PrepareContext(ctx, readQ)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer readStmt.Close()
+		writeStmt, err := db.PrepareContext(ctx, writeQ)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer writeStmt.Close()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				keys := ph.workerKeys(keys, worker)
+				record := func(part *Result, start time.Time, err error) {
+					if err != nil {
+						res.addErrorCnt(err)
+						part.addErrorCnt(err)
+						return
+					}
+					d := res.clock.Since(start)
+					res.addLatency(worker, d)
+					part.addLatency(worker, d)
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					key := keys[rnd.Intn(len(keys))]
+					if rnd.Intn(100) < readPct {
+						start := res.clock.Now()
+						var v []byte
+						octx, done := res.op(ctx)
+						record(reads, start, done(readStmt.QueryRowContext(octx, key).Scan(&v)))
+						continue
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					_, err = writeStmt.ExecContext(octx, pl.pick(rnd), key)
+					err = done(err)
+					release()
+					record(writes, start, err)
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
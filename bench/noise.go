@@ -0,0 +1,136 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// noiseFloorDuration is how long the noise floor phase runs before the
+// suite.
+const noiseFloorDuration = time.Second
+
+// NoiseFloor is the benchmark's own cost per operation, measured by the
+// noop workload before the suite: latencies below P50 are the harness
+// (clock reads, deadline and channel bookkeeping, database/sql's pool)
+// rather than the engine.
+type NoiseFloor struct {
+	Concurrency int           `json:"concurrency"`
+	OpsPerSec   float64       `json:"ops_per_sec"` // the most any workload can reach at this concurrency
+	P50         time.Duration `json:"p50"`
+	P99         time.Duration `json:"p99"`
+}
+
+func (n NoiseFloor) pretty() string {
+	return fmt.Sprintf("P50=%s  P99=%s per op, at most %s ops/s with %d workers",
+		fDur(n.P50), fDur(n.P99), commaI(int64(n.OpsPerSec)), n.Concurrency)
+}
+
+// NetStats are a phase's operation latencies less Overhead, the noise
+// floor's P50, floored at zero.
+type NetStats struct {
+	Overhead time.Duration `json:"overhead"`
+	P50      time.Duration `json:"p50"`
+	P95      time.Duration `json:"p95"`
+	P99      time.Duration `json:"p99"`
+}
+
+func (s NetStats) pretty() string {
+	return fmt.Sprintf("P50=%s  P95=%s  P99=%s  (less %s harness overhead)", fDur(s.P50), fDur(s.P95), fDur(s.P99), fDur(s.Overhead))
+}
+
+// measureNoiseFloor runs the noop workload with concurrency workers.
+func measureNoiseFloor(ctx context.Context, cfg Config, concurrency int) *NoiseFloor {
+	res := noopWorkload()(ctx, nil, Phase{Concurrency: concurrency, Duration: noiseFloorDuration, CPUSet: cfg.CPUSet, Clock: cfg.Clock})
+	if res.Ops == 0 {
+		return nil
+	}
+	return &NoiseFloor{
+		Concurrency: res.Concurrency,
+		OpsPerSec:   float64(res.Ops) / res.Duration.Seconds(),
+		P50:         res.P50,
+		P99:         res.P99,
+	}
+}
+
+// noiseFloors are the noise floors of a suite by concurrency: the
+// harness costs more per operation the more workers share the CPUs.
+type noiseFloors struct {
+	ctx    context.Context
+	cfg    Config
+	floors map[int]*NoiseFloor
+}
+
+// at returns the noise floor at concurrency, measuring it on first use;
+// nil if it could not be measured.
+func (f *noiseFloors) at(concurrency int) *NoiseFloor {
+	n, ok := f.floors[concurrency]
+	if !ok {
+		n = measureNoiseFloor(f.ctx, f.cfg, concurrency)
+		f.floors[concurrency] = n
+	}
+	return n
+}
+
+// subtractNoise fills in r.Net from the noise floor n, if any, measured
+// at r's concurrency.
+func (r *Result) subtractNoise(n *NoiseFloor) {
+	if n == nil || r.Ops == 0 || r.Skipped {
+		return
+	}
+	net := func(d time.Duration) time.Duration { return max(0, d-n.P50) }
+	r.Net = &NetStats{Overhead: n.P50, P50: net(r.P50), P95: net(r.P95), P99: net(r.P99)}
+}
+
+// noopWorkload is the select workload's loop, statement, row and Scan
+// included, against a stub executor that answers every query at once
+// with one row, so all it times is the harness. The database it is given
+// is ignored.
+func noopWorkload() WorkloadFunc {
+	return func(ctx context.Context, _ Executor, ph Phase) Result {
+		res := newResult("noop", ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		db := newFakeExecutor(&fakeExecutor{Rows: 1, Columns: []string{"v"}})
+		stmt, err := db.PrepareContext(ctx, `SELECT v FROM kv WHERE k = ?`)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer stmt.Close()
+		keys := make([]string, 1024)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("k%08d", i)
+		}
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					key := keys[rnd.Intn(len(keys))]
+					start := res.clock.Now()
+					var v []byte
+					octx, done := res.op(ctx)
+					if err := done(stmt.QueryRowContext(octx, key).Scan(&v)); err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the exported latency
+// histograms: 1-2.5-5 steps from 10µs to 10s, like the defaults of
+// Prometheus client libraries but reaching down to embedded-engine speeds.
+var latencyBuckets = []float64{
+	0.00001, 0.000025, 0.00005,
+	0.0001, 0.00025, 0.0005,
+	0.001, 0.0025, 0.005,
+	0.01, 0.025, 0.05,
+	0.1, 0.25, 0.5,
+	1, 2.5, 5, 10,
+}
+
+// WriteOpenMetrics writes the latency histogram, operation and error
+// counts of every measured phase in the OpenMetrics text format, labelled
+// by engine, workload and run id, so dashboards built for services can
+// show benchmark runs. Only reports of this process carry every latency;
+// decoded ones fall back to their raw samples.
+func (r Report) WriteOpenMetrics(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# TYPE sqlbench_operation_latency_seconds histogram")
+	fmt.Fprintln(bw, "# HELP sqlbench_operation_latency_seconds Latency of benchmark operations.")
+	for _, res := range r.Results {
+		if res.Skipped {
+			continue
+		}
+		lat := res.hist.samples
+		if len(lat) == 0 {
+			lat = res.Samples
+		}
+		counts := make([]int64, len(latencyBuckets))
+		var sum time.Duration
+		for _, d := range lat {
+			sum += d
+			for i, le := range latencyBuckets {
+				if d.Seconds() <= le {
+					counts[i]++
+				}
+			}
+		}
+		labels := r.metricLabels(res)
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(bw, "sqlbench_operation_latency_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(le, 'g', -1, 64), counts[i])
+		}
+		fmt.Fprintf(bw, "sqlbench_operation_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, len(lat))
+		fmt.Fprintf(bw, "sqlbench_operation_latency_seconds_count{%s} %d\n", labels, len(lat))
+		fmt.Fprintf(bw, "sqlbench_operation_latency_seconds_sum{%s} %g\n", labels, sum.Seconds())
+	}
+	for _, c := range []struct {
+		name, help string
+		value      func(Result) int64
+	}{
+		{"sqlbench_operations", "Operations completed in the measured phase.", func(res Result) int64 { return res.Ops }},
+		{"sqlbench_errors", "Operations that failed in the measured phase.", func(res Result) int64 { return res.Errors }},
+	} {
+		fmt.Fprintf(bw, "# TYPE %s counter\n", c.name)
+		fmt.Fprintf(bw, "# HELP %s %s\n", c.name, c.help)
+		for _, res := range r.Results {
+			if !res.Skipped {
+				fmt.Fprintf(bw, "%s_total{%s} %d\n", c.name, r.metricLabels(res), c.value(res))
+			}
+		}
+	}
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+func (r Report) metricLabels(res Result) string {
+	return fmt.Sprintf(`engine="%s",workload="%s",run_id="%s"`, escapeLabel(r.Meta.Engine), escapeLabel(res.Workload), escapeLabel(r.RunID))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
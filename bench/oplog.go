@@ -0,0 +1,127 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// opLogVersion is written to and checked against the header of every
+// operation log.
+const opLogVersion = 1
+
+// OpLogHeader is the first line of an operation log: what a replay needs to
+// rebuild the written values from their hashes.
+type OpLogHeader struct {
+	Version            int    `json:"version"`
+	Engine             string `json:"engine"` // the engine recorded against
+	Payload            string `json:"payload"`
+	PayloadCardinality int    `json:"payload_cardinality"`
+	ValueSize          int    `json:"value_size,omitempty"`
+}
+
+// OpEntry is one operation of a recorded phase, as issued by its worker.
+// Inserts run in transactions that a commit or rollback entry of the same
+// worker ends; the other operations run on their own. Imported query logs
+// (see ImportPostgresLog) consist of sql operations: statements run as is
+// on a connection of the worker's own.
+type OpEntry struct {
+	Phase   string        `json:"phase"`
+	Warmup  bool          `json:"warmup,omitempty"`
+	Worker  int           `json:"worker"`
+	Op      string        `json:"op"` // insert|select|range|update|delete|commit|rollback|sql
+	Key     string        `json:"key,omitempty"`
+	End     string        `json:"end,omitempty"`     // range: upper bound
+	Limit   int           `json:"limit,omitempty"`   // range: row limit
+	Payload string        `json:"payload,omitempty"` // payloadHash of the written value
+	SQL     string        `json:"sql,omitempty"`     // sql: the statement
+	Offset  time.Duration `json:"offset"`            // since the phase start
+}
+
+// opRecorder appends the operations of the kv workloads (insert, select,
+// range, update, delete) to a JSONL file for replay. A nil *opRecorder
+// records nothing. Every operation takes the recorder's lock, which the
+// recorded run pays for.
+type opRecorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+	err error // first write error; recording stops there
+}
+
+func openOpRecorder(path string, cfg Config) (*opRecorder, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriterSize(f, 64<<10)
+	rec := &opRecorder{f: f, w: w, enc: json.NewEncoder(w)}
+	payload := cfg.Payload
+	if payload == "" {
+		payload = "fixed"
+	}
+	rec.write(OpLogHeader{Version: opLogVersion, Engine: cfg.Engine, Payload: payload, PayloadCardinality: cfg.PayloadCardinality, ValueSize: cfg.ValueSize})
+	return rec, rec.err
+}
+
+func (rec *opRecorder) write(v any) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.err != nil {
+		return
+	}
+	if rec.err = rec.enc.Encode(v); rec.err != nil {
+		log.Warn().Err(rec.err).Msg("failed to record operation; recording stopped")
+	}
+}
+
+func (rec *opRecorder) Close() error {
+	if rec == nil {
+		return nil
+	}
+	err := rec.w.Flush()
+	if cerr := rec.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// payloadHash identifies a written value in an operation log; a replay
+// maps it back through the payload pools.
+func payloadHash(v []byte) string {
+	h := fnv.New64a()
+	h.Write(v)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// record logs an operation worker is about to issue, if the phase is
+// recorded. Range operations log their bounds with recordRange.
+func (r *Result) record(worker int, op, key string, payload []byte) {
+	rec := r.phase.record
+	if rec == nil {
+		return
+	}
+	e := OpEntry{Phase: r.Workload, Warmup: r.phase.Warmup, Worker: worker, Op: op, Key: key, Offset: r.clock.Since(r.startedAt)}
+	if payload != nil {
+		e.Payload = payloadHash(payload)
+	}
+	rec.write(e)
+}
+
+func (r *Result) recordRange(worker int, lo, hi string, limit int) {
+	rec := r.phase.record
+	if rec == nil {
+		return
+	}
+	rec.write(OpEntry{Phase: r.Workload, Warmup: r.phase.Warmup, Worker: worker, Op: "range", Key: lo, End: hi, Limit: limit, Offset: r.clock.Since(r.startedAt)})
+}
@@ -0,0 +1,145 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RunEngines runs the suite once per configuration, typically the same
+// one for different engines, one after the other or, with parallel, all
+// at once. Parallel suites must target independent databases; they still
+// share the host's CPU caches, memory bandwidth, disks and network, so
+// each report lists the engines that ran alongside it in Meta.Parallel.
+// Without a CPU set of their own they are pinned to disjoint CPUs. The
+// process is shared as well: CPU profiles cannot be taken, GOMAXPROCS
+// must be the same for all, and the phases leave out the process's I/O
+// counters and allocations.
+//
+// reports[i] is the report of cfgs[i], nil if that suite failed; err
+// joins the failures.
+func RunEngines(ctx context.Context, cfgs []Config, parallel bool) (reports []*Report, err error) {
+	if err := ownFiles(cfgs); err != nil {
+		return nil, err
+	}
+	reports = make([]*Report, len(cfgs))
+	errs := make([]error, len(cfgs))
+	run := func(i int, cfg Config) {
+		if reports[i], errs[i] = Run(ctx, cfg); errs[i] != nil {
+			errs[i] = fmt.Errorf("%s: %w", cfg.Engine, errs[i])
+		}
+	}
+	if !parallel || len(cfgs) < 2 {
+		for i, cfg := range cfgs {
+			run(i, cfg)
+		}
+		return reports, errors.Join(errs...)
+	}
+
+	if err := independent(cfgs); err != nil {
+		return nil, err
+	}
+	for _, cfg := range cfgs {
+		if cfg.PprofDir != "" {
+			return nil, errors.New("CPU profiles are process-wide and cannot be taken of engines run in parallel")
+		}
+		if cfg.GOMAXPROCS != cfgs[0].GOMAXPROCS {
+			return nil, errors.New("GOMAXPROCS is process-wide and must be the same for engines run in parallel")
+		}
+	}
+	if n := cfgs[0].GOMAXPROCS; n > 0 {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+	}
+	log.Warn().Int("engines", len(cfgs)).Msg("running engines in parallel; they compete for CPU caches, memory bandwidth, disks and network, so their results may interfere")
+	cpus := splitCPUs(cfgs)
+	var wg sync.WaitGroup
+	for i, cfg := range cfgs {
+		if cpus != nil {
+			cfg.CPUSet = cpus[i]
+		}
+		cfg.parallel = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(i, cfg)
+		}()
+	}
+	wg.Wait()
+
+	for i, rep := range reports {
+		if rep == nil {
+			continue
+		}
+		for j, cfg := range cfgs {
+			if j != i {
+				rep.Meta.Parallel = append(rep.Meta.Parallel, cfg.Engine)
+			}
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// independent rejects parallel suites sharing a database: the same DSN or
+// the same embedded data path.
+func independent(cfgs []Config) error {
+	seen := make(map[string]string, len(cfgs))
+	for _, cfg := range cfgs {
+		target := cfg.Engine + " " + cfg.DSN
+		if p := dataPath(cfg.Engine, cfg.DSN); p != "" {
+			target = p
+		}
+		if other, ok := seen[target]; ok {
+			return fmt.Errorf("%s and %s would run on the same database in parallel (%s)", other, cfg.Engine, SanitizeDSN(cfg.DSN))
+		}
+		seen[target] = cfg.Engine
+	}
+	return nil
+}
+
+// ownFiles rejects suites writing the same operation log, which each
+// would truncate in turn.
+func ownFiles(cfgs []Config) error {
+	seen := make(map[string]string, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Record == "" {
+			continue
+		}
+		path := filepath.Clean(cfg.Record)
+		if other, ok := seen[path]; ok {
+			return fmt.Errorf("%s and %s would record their operations to the same file (%s)", other, cfg.Engine, cfg.Record)
+		}
+		seen[path] = cfg.Engine
+	}
+	return nil
+}
+
+// splitCPUs gives each of the parallel suites an equal, disjoint share of
+// the CPUs, or returns nil where pinning does not apply: unsupported, a
+// suite with a CPU set of its own, or fewer CPUs than suites.
+func splitCPUs(cfgs []Config) [][]int {
+	if !pinSupported {
+		return nil
+	}
+	for _, cfg := range cfgs {
+		if len(cfg.CPUSet) > 0 {
+			return nil
+		}
+	}
+	n := runtime.NumCPU()
+	if n < len(cfgs) {
+		log.Warn().Int("cpus", n).Int("engines", len(cfgs)).Msg("fewer CPUs than parallel engines; not pinning them")
+		return nil
+	}
+	out := make([][]int, len(cfgs))
+	for i := range cfgs {
+		for c := i * n / len(cfgs); c < (i+1)*n/len(cfgs); c++ {
+			out[i] = append(out[i], c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+// payloadSeed keeps faker output identical across runs and engines, so every
+// engine stores exactly the same values.
+const payloadSeed = 1
+
+// payloads is the pool of values the write workloads draw kv.v from.
+type payloads [][]byte
+
+func (p payloads) pick(rnd *rand.Rand) []byte { return p[rnd.Intn(len(p))] }
+
+// newPayloads builds the value pool for mode:
+//
+//	fixed: the literal fixed (the historical behaviour), repeated to size
+//	       bytes if size is positive
+//	faker: cardinality distinct JSON documents with realistic person data
+func newPayloads(mode string, cardinality, size int, fixed string) (payloads, error) {
+	switch mode {
+	case "", "fixed":
+		if size > 0 {
+			v := make([]byte, size)
+			for i := range v {
+				v[i] = fixed[i%len(fixed)]
+			}
+			return payloads{v}, nil
+		}
+		return payloads{[]byte(fixed)}, nil
+	case "faker":
+		if size > 0 {
+			return nil, fmt.Errorf("a value size applies to fixed payloads only")
+		}
+		f := gofakeit.New(payloadSeed)
+		pool := make(payloads, max(1, cardinality))
+		for i := range pool {
+			doc, err := json.Marshal(fakePerson(f))
+			if err != nil {
+				return nil, err
+			}
+			pool[i] = doc
+		}
+		return pool, nil
+	}
+	return nil, fmt.Errorf("unknown payload mode: %s", mode)
+}
+
+type fakeAddress struct {
+	Street  string `json:"street"`
+	City    string `json:"city"`
+	Zip     string `json:"zip"`
+	Country string `json:"country"`
+}
+
+type fakeDoc struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Username  string      `json:"username"`
+	Email     string      `json:"email"`
+	Phone     string      `json:"phone"`
+	Company   string      `json:"company"`
+	JobTitle  string      `json:"job_title"`
+	Address   fakeAddress `json:"address"`
+	Bio       string      `json:"bio"`
+	CreatedAt string      `json:"created_at"`
+}
+
+func fakePerson(f *gofakeit.Faker) fakeDoc {
+	return fakeDoc{
+		ID:       f.UUID(),
+		Name:     f.Name(),
+		Username: f.Username(),
+		Email:    f.Email(),
+		Phone:    f.Phone(),
+		Company:  f.Company(),
+		JobTitle: f.JobTitle(),
+		Address: fakeAddress{
+			Street:  f.Street(),
+			City:    f.City(),
+			Zip:     f.Zip(),
+			Country: f.Country(),
+		},
+		Bio:       f.Sentence(12 + f.Rand.Intn(24)),
+		CreatedAt: f.Date().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+}
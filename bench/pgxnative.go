@@ -0,0 +1,171 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pgxOp is one measured operation on a native pgx connection; it returns
+// how many logical operations (rows) it covered.
+type pgxOp func(ctx context.Context, conn *pgx.Conn, rnd *rand.Rand) (int, error)
+
+// pgxNativeWorkload runs op in a loop on one pgx.Conn per worker, bypassing
+// database/sql and its connection pool entirely. Queries use pgx's
+// extended protocol with binary encoding and its statement cache. When an
+// op covers several rows (a batch), its latency is spread evenly over
+// them so ops and percentiles stay comparable with the database/sql path.
+// Write ops go through the phase's write limiter.
+func pgxNativeWorkload(name, dsn string, write bool, newOp func(worker int) (pgxOp, error)) WorkloadFunc {
+	return func(ctx context.Context, _ Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				op, err := newOp(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				conn, err := pgx.Connect(ctx, dsn)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				defer conn.Close(context.Background())
+
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					release := func() {}
+					if write {
+						if release, err = res.acquireWrite(ctx); err != nil {
+							return
+						}
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					n, err := op(octx, conn, rnd)
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					if n > 0 {
+						per := res.clock.Since(start) / time.Duration(n)
+						for range n {
+							res.addLatency(worker, per)
+						}
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+// pgxNativePhase returns the native variant of a core kv workload; ok is
+// false for workloads without one.
+func pgxNativePhase(name string, cfg Config) (spec phaseSpec, ok bool, err error) {
+	dsn := cfg.DSN
+	switch name {
+	case "insert":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return phaseSpec{}, false, err
+		}
+		kg, err := newKeyGens(cfg.KeyGen, cfg.RandflakeSecret)
+		if err != nil {
+			return phaseSpec{}, false, err
+		}
+		batch := max(1, cfg.TxBatch)
+		return phaseSpec{name, func(*suite) (WorkloadFunc, error) {
+			return pgxNativeWorkload(name, dsn, true, func(worker int) (pgxOp, error) {
+				gen, err := kg(worker)
+				if err != nil {
+					return nil, err
+				}
+				return func(ctx context.Context, conn *pgx.Conn, rnd *rand.Rand) (int, error) {
+					// one round trip for the whole transaction
+					b := &pgx.Batch{}
+					b.Queue(`BEGIN`)
+					for range batch {
+						k, err := gen.next()
+						if err != nil {
+							return 0, err
+						}
+						b.Queue(`INSERT INTO kv(k, v) VALUES($1, $2)`, k, pl.pick(rnd))
+					}
+					b.Queue(`COMMIT`)
+					return batch, conn.SendBatch(ctx, b).Close()
+				}, nil
+			}), nil
+		}}, true, nil
+	case "select", "range", "update", "delete":
+		var pl payloads
+		if name == "update" {
+			if pl, err = newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "updated"); err != nil {
+				return phaseSpec{}, false, err
+			}
+		}
+		return phaseSpec{name, func(s *suite) (WorkloadFunc, error) {
+			keys, err := s.snapshot()
+			if err != nil {
+				return nil, err
+			}
+			if len(keys) == 0 {
+				return nil, &errSkip{"no keys in kv; run insert first"}
+			}
+			op := pgxKeyOp(name, keys, pl)
+			write := name == "update" || name == "delete"
+			return pgxNativeWorkload(name, dsn, write, func(int) (pgxOp, error) { return op, nil }), nil
+		}}, true, nil
+	}
+	return phaseSpec{}, false, nil
+}
+
+func pgxKeyOp(name string, keys []string, pl payloads) pgxOp {
+	key := func(rnd *rand.Rand) string { return keys[rnd.Intn(len(keys))] }
+	return func(ctx context.Context, conn *pgx.Conn, rnd *rand.Rand) (int, error) {
+		switch name {
+		case "select":
+			var v []byte
+			return 1, conn.QueryRow(ctx, `SELECT v FROM kv WHERE k = $1`, key(rnd)).Scan(&v)
+		case "range":
+			a, b := key(rnd), key(rnd)
+			if a > b {
+				a, b = b, a
+			}
+			rows, err := conn.Query(ctx, `SELECT k, v FROM kv WHERE k BETWEEN $1 AND $2 LIMIT 100`, a, b)
+			if err != nil {
+				return 0, err
+			}
+			for rows.Next() {
+				var k string
+				var v []byte
+				_ = rows.Scan(&k, &v)
+			}
+			return 1, rows.Err()
+		case "update":
+			_, err := conn.Exec(ctx, `UPDATE kv SET v = $1 WHERE k = $2`, pl.pick(rnd), key(rnd))
+			return 1, err
+		default: // delete
+			_, err := conn.Exec(ctx, `DELETE FROM kv WHERE k = $1`, key(rnd))
+			return 1, err
+		}
+	}
+}
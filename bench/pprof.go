@@ -0,0 +1,49 @@
+package bench
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/rs/zerolog/log"
+)
+
+// startProfiles begins a CPU profile for the named phase and returns a stop
+// func that finishes it and writes a heap snapshot next to it.
+// Only one CPU profile can be active per process, so /debug/pprof/profile
+// requests fail while a phase is being captured.
+func startProfiles(dir, name string) (func(), error) {
+	if dir == "" {
+		return func() {}, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	cpu, err := os.Create(filepath.Join(dir, name+".cpu.pprof"))
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		_ = cpu.Close()
+		return nil, err
+	}
+
+	return func() {
+		pprof.StopCPUProfile()
+		_ = cpu.Close()
+
+		heapPath := filepath.Join(dir, name+".heap.pprof")
+		heap, err := os.Create(heapPath)
+		if err != nil {
+			log.Error().Err(err).Str("path", heapPath).Msg("failed to create heap profile")
+			return
+		}
+		defer heap.Close()
+		runtime.GC() // up-to-date live heap
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			log.Error().Err(err).Str("path", heapPath).Msg("failed to write heap profile")
+		}
+	}, nil
+}
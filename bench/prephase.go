@@ -0,0 +1,44 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PrePhase is the outcome of the pre-phase SQL (Config.PrePhaseSQL) run
+// between a phase's warmup and its measurement.
+type PrePhase struct {
+	Statements int           `json:"statements"` // statements that ran
+	Took       time.Duration `json:"took"`
+	Error      string        `json:"error,omitempty"` // the failed statement and why; later ones did not run
+}
+
+func (p *PrePhase) String() string {
+	if p.Error != "" {
+		return "FAILED: " + p.Error
+	}
+	return fmt.Sprintf("%d statements (%s)", p.Statements, p.Took.Round(time.Microsecond))
+}
+
+// runPrePhase runs stmts in order, stopping at the first failure. They go
+// through Query rather than Exec and their rows are drained, so prewarm
+// SELECTs (and statements such as MySQL's ANALYZE TABLE that return a
+// result set) read everything they would.
+func runPrePhase(ctx context.Context, db Executor, stmts []string) *PrePhase {
+	p := &PrePhase{}
+	start := time.Now()
+	for _, q := range stmts {
+		rows, err := db.QueryContext(ctx, q)
+		if err == nil {
+			_, err = drainRows(rows, nil)
+		}
+		if err != nil {
+			p.Error = fmt.Sprintf("%s: %v", q, err)
+			break
+		}
+		p.Statements++
+	}
+	p.Took = time.Since(start)
+	return p
+}
@@ -0,0 +1,74 @@
+package bench
+
+import "strings"
+
+// PrettyOptions control the human-readable output. Width scales the
+// histogram and heatmap (the tab-aligned labels take about 20 columns);
+// ASCII keeps the output to 7-bit characters for terminals and log
+// collectors that mangle Unicode; Color highlights errors, aborts and
+// compare verdicts with ANSI colors.
+type PrettyOptions struct {
+	Width int
+	ASCII bool
+	Color bool
+	CDF   bool // plot each phase's latency CDF
+	// Markdown renders a report as a table of its phases, one row each,
+	// to paste into issues and pull requests.
+	Markdown bool
+}
+
+// DefaultPrettyOptions is what Pretty() uses.
+var DefaultPrettyOptions = PrettyOptions{Width: 80}
+
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+var (
+	sparkUnicode = []rune("▁▂▃▄▅▆▇█")
+	sparkASCII   = []rune("_.-~=+*#")
+)
+
+func (o PrettyOptions) sparkBins() int { return max(4, o.width()*14/80) }
+
+func (o PrettyOptions) heatmapCols() int { return max(10, o.width()-20) }
+
+func (o PrettyOptions) width() int {
+	if o.Width <= 0 {
+		return DefaultPrettyOptions.Width
+	}
+	return o.Width
+}
+
+func (o PrettyOptions) cdfFill() string {
+	if o.ASCII {
+		return "#"
+	}
+	return "█"
+}
+
+func (o PrettyOptions) sparkRunes() []rune {
+	if o.ASCII {
+		return sparkASCII
+	}
+	return sparkUnicode
+}
+
+// paint wraps s in color when colors are enabled.
+func (o PrettyOptions) paint(color, s string) string {
+	if !o.Color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// finish applies whole-output substitutions: in ASCII mode the micro sign
+// of latencies becomes "u".
+func (o PrettyOptions) finish(s string) string {
+	if o.ASCII {
+		s = strings.ReplaceAll(s, "µ", "u")
+	}
+	return s
+}
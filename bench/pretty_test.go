@@ -0,0 +1,109 @@
+package bench_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gosuda/chaisql-benchmark/bench"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the rendering tests")
+
+// golden compares got with testdata/<name>.golden, or rewrites the file
+// with -update.
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s (run go test -update to accept it):\n--- got\n%s\n--- want\n%s", name, path, got, want)
+	}
+}
+
+// latencies is secs seconds of n operations each, spread between base and
+// about 4 × base with a slow tail, in a fixed pattern.
+func latencies(secs, n int, base time.Duration) [][]time.Duration {
+	out := make([][]time.Duration, secs)
+	for s := range out {
+		for i := range n {
+			d := base + base*time.Duration((i*37+s*11)%300)/100
+			if i%50 == 49 {
+				d *= 5
+			}
+			out[s] = append(out[s], d)
+		}
+	}
+	return out
+}
+
+func flatten(secs [][]time.Duration) []time.Duration {
+	var out []time.Duration
+	for _, s := range secs {
+		out = append(out, s...)
+	}
+	return out
+}
+
+func syntheticReport(runID string, base time.Duration) *bench.Report {
+	insert := bench.SyntheticResult("insert", 4, latencies(3, 200, base))
+	insert.Samples = flatten(latencies(3, 200, base))
+
+	sel := bench.SyntheticResult("select", 4, latencies(3, 400, base/2))
+	sel.Samples = flatten(latencies(3, 400, base/2))
+	sel.Errors = 12
+	sel.Partitioned = true
+
+	update := bench.SyntheticResult("update", 4, latencies(1, 100, base))
+	update.Aborted, update.AbortReason = true, "error budget exceeded: 30% errors | 10s window"
+
+	skipped := bench.Result{Workload: "returning", Skipped: true, SkipReason: "engine lacks RETURNING"}
+
+	return &bench.Report{
+		SchemaVersion: bench.SchemaVersion,
+		RunID:         runID,
+		ConfigHash:    "0a1b2c3d4e5f",
+		Meta: bench.Metadata{
+			Engine:        "chai",
+			EngineVersion: "v1.1.0",
+			GoVersion:     "go1.24.0",
+			OS:            "linux",
+			Arch:          "amd64",
+			NumCPU:        8,
+			GOMAXPROCS:    8,
+			Tags:          map[string]string{"machine": "bench-01", "exp": "golden"},
+		},
+		Results: []bench.Result{insert, sel, update, skipped},
+	}
+}
+
+func TestResultPrettyGolden(t *testing.T) {
+	rep := syntheticReport("20250102T150405Z-1a2b3c", 200*time.Microsecond)
+	golden(t, "result_pretty", rep.Results[0].Pretty())
+	golden(t, "result_pretty_ascii", rep.Results[1].PrettyWith(bench.PrettyOptions{Width: 60, ASCII: true}))
+}
+
+func TestReportMarkdownGolden(t *testing.T) {
+	rep := syntheticReport("20250102T150405Z-1a2b3c", 200*time.Microsecond)
+	golden(t, "report_markdown", rep.PrettyWith(bench.PrettyOptions{Markdown: true}))
+}
+
+func TestComparePrettyGolden(t *testing.T) {
+	base := syntheticReport("20250102T150405Z-1a2b3c", 200*time.Microsecond)
+	cur := syntheticReport("20250103T150405Z-4d5e6f", 260*time.Microsecond)
+	golden(t, "compare", bench.ComparePretty(bench.Compare(base, cur, 0.05), bench.DefaultPrettyOptions))
+}
@@ -0,0 +1,82 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ProbeResult is the feature matrix of one engine: every capability the
+// suite probes for, supported or not.
+type ProbeResult struct {
+	Engine   string          `json:"engine"`
+	DSN      string          `json:"dsn"` // credentials masked
+	Version  string          `json:"version,omitempty"`
+	Features map[string]bool `json:"features"`
+}
+
+// Probe connects to cfg's engine, creates the kv table if needed and runs
+// the capability probes Run skips workloads by, each in a transaction that
+// is rolled back. Only database/sql engines have SQL features to probe.
+func Probe(ctx context.Context, cfg Config) (*ProbeResult, error) {
+	engine, err := NormalizeEngine(cfg.Engine)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Engine = engine
+	if engine == "chai-native" || isKVEngine(engine) {
+		return nil, fmt.Errorf("%s has no SQL features to probe", engine)
+	}
+	if err := setDriver(cfg); err != nil {
+		return nil, err
+	}
+
+	db, err := openDB(cfg, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := initSchema(ctx, db, engine); err != nil {
+		return nil, err
+	}
+
+	p := &ProbeResult{
+		Engine:   engine,
+		DSN:      SanitizeDSN(cfg.DSN),
+		Version:  engineVersion(ctx, db, engine),
+		Features: make(map[string]bool),
+	}
+	for c, ok := range detectCapabilities(ctx, db, dollarPlaceholders(cfg)) {
+		p.Features[string(c)] = ok
+	}
+	return p, nil
+}
+
+func (p ProbeResult) Pretty() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Engine\t\t: %s\n", p.Engine)
+	fmt.Fprintf(&b, "DSN\t\t: %s\n", p.DSN)
+	if p.Version != "" {
+		fmt.Fprintf(&b, "Version\t\t: %s\n", p.Version)
+	}
+	names := make([]string, 0, len(p.Features))
+	for f := range p.Features {
+		names = append(names, f)
+	}
+	slices.Sort(names)
+	for _, f := range names {
+		mark := "no"
+		if p.Features[f] {
+			mark = "yes"
+		}
+		fmt.Fprintf(&b, "  %-26s %s\n", f, mark)
+	}
+	return b.String()
+}
+
+func (p ProbeResult) JSON() string {
+	j, _ := json.MarshalIndent(p, "", "  ")
+	return string(j)
+}
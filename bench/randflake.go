@@ -1,16 +1,55 @@
 package bench
 
 import (
+	"fmt"
 	"time"
 
 	"gosuda.org/randflake"
 )
 
-func NewRandflake(worker int) (*randflake.Generator, error) {
-	now := time.Now().Unix()
+// DefaultRandflakeSecret is the randflake encryption key used when none is
+// configured. It only scrambles the ids; nothing secret is protected.
+const DefaultRandflakeSecret = "0123456789ABCDEF"
+
+// randflakeLease is the lease of a generator, in seconds. randflakeKeys
+// extends it randflakeRenew seconds before it ends, so runs can outlast it.
+const (
+	randflakeLease = 3600
+	randflakeRenew = 300
+)
+
+// NewRandflake returns worker's generator, leased from leaseStart (unix
+// seconds) for randflakeLease seconds. Renewals must pass the same
+// leaseStart.
+func NewRandflake(worker int, secret []byte, leaseStart int64) (*randflake.Generator, error) {
 	nodeID := int64(worker)
-	leaseStart := now
-	leaseEnd := now + 3600
-	secret := []byte("0123456789ABCDEF")
+	leaseEnd := leaseStart + randflakeLease
 	return randflake.NewGenerator(nodeID, leaseStart, leaseEnd, secret)
 }
+
+// randflakeKeys generates a worker's randflake ids and renews the
+// generator's lease as it nears its end.
+type randflakeKeys struct {
+	gen        *randflake.Generator
+	start, end int64 // current lease, unix seconds
+}
+
+func newRandflakeKeys(worker int, secret []byte) (*randflakeKeys, error) {
+	now := time.Now().Unix()
+	gen, err := NewRandflake(worker, secret, now)
+	if err != nil {
+		return nil, err
+	}
+	return &randflakeKeys{gen: gen, start: now, end: now + randflakeLease}, nil
+}
+
+func (k *randflakeKeys) next() (string, error) {
+	if now := time.Now().Unix(); now >= k.end-randflakeRenew {
+		end := now + randflakeLease
+		if !k.gen.UpdateLease(k.start, end) {
+			return "", fmt.Errorf("randflake: lease renewal until %s refused", time.Unix(end, 0).UTC().Format(time.RFC3339))
+		}
+		k.end = end
+	}
+	return k.gen.GenerateString()
+}
@@ -0,0 +1,416 @@
+package bench
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+)
+
+// opPhase is one phase of an operation log, its operations split by worker
+// in the order they were issued.
+type opPhase struct {
+	name     string
+	warmup   [][]OpEntry
+	measured [][]OpEntry
+	warmedUp bool // the replay's warmup ran the recorded one
+}
+
+// readOpLog reads the operation log at path: its header and its phases in
+// recorded order.
+func readOpLog(path string) (OpLogHeader, []*opPhase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return OpLogHeader{}, nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	var hdr OpLogHeader
+	if err := dec.Decode(&hdr); err != nil {
+		return hdr, nil, fmt.Errorf("%s: header: %w", path, err)
+	}
+	if hdr.Version != opLogVersion {
+		return hdr, nil, fmt.Errorf("%s: operation log version %d, want %d", path, hdr.Version, opLogVersion)
+	}
+	var phases []*opPhase
+	byName := make(map[string]*opPhase)
+	for n := 2; ; n++ {
+		var e OpEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return hdr, nil, fmt.Errorf("%s: line %d: %w", path, n, err)
+		}
+		if e.Phase == "" || e.Worker < 0 {
+			return hdr, nil, fmt.Errorf("%s: line %d: operation without phase or worker", path, n)
+		}
+		p := byName[e.Phase]
+		if p == nil {
+			p = &opPhase{name: e.Phase}
+			byName[e.Phase] = p
+			phases = append(phases, p)
+		}
+		by := &p.measured
+		if e.Warmup {
+			by = &p.warmup
+		}
+		for len(*by) <= e.Worker {
+			*by = append(*by, nil)
+		}
+		(*by)[e.Worker] = append((*by)[e.Worker], e)
+	}
+	return hdr, phases, nil
+}
+
+// has reports whether p issues op, in its warmup or measurement.
+func (p *opPhase) has(op string) bool {
+	for _, workers := range [][][]OpEntry{p.warmup, p.measured} {
+		for _, ops := range workers {
+			if slices.ContainsFunc(ops, func(e OpEntry) bool { return e.Op == op }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// span is the offset of the last of ops.
+func span(workers [][]OpEntry) time.Duration {
+	var d time.Duration
+	for _, ops := range workers {
+		if len(ops) > 0 {
+			d = max(d, ops[len(ops)-1].Offset)
+		}
+	}
+	return d
+}
+
+// replayPhases are the phases of the operation log cfg.Replay, under their
+// recorded names so reports of the recording and the replay compare. The
+// written values come back from their hashes through the payload pools of
+// the recording's payload settings.
+func replayPhases(cfg Config) ([]phaseSpec, error) {
+	if nativePhase(cfg) != nil {
+		return nil, fmt.Errorf("replay needs a database/sql engine, not %s", nativeEngine(cfg))
+	}
+	hdr, phases, err := readOpLog(cfg.Replay)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte)
+	for _, fixed := range []string{"payload", "updated"} {
+		pl, err := newPayloads(hdr.Payload, hdr.PayloadCardinality, hdr.ValueSize, fixed)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range pl {
+			values[payloadHash(v)] = v
+		}
+	}
+	for _, p := range phases {
+		for _, workers := range [][][]OpEntry{p.warmup, p.measured} {
+			for _, ops := range workers {
+				for _, e := range ops {
+					if _, ok := values[e.Payload]; e.Payload != "" && !ok {
+						return nil, fmt.Errorf("%s: %s value %s is not in the %s payload pool", cfg.Replay, e.Phase, e.Payload, hdr.Payload)
+					}
+				}
+			}
+		}
+	}
+	log.Info().Str("path", cfg.Replay).Str("recorded_on", hdr.Engine).Int("phases", len(phases)).Msg("replaying operation log")
+
+	specs := make([]phaseSpec, 0, len(phases))
+	for _, p := range phases {
+		specs = append(specs, phaseSpec{p.name, func(s *suite) (WorkloadFunc, error) {
+			if p.has("update") {
+				if err := s.require(capPointUpdate); err != nil {
+					return nil, err
+				}
+			}
+			if p.has("delete") {
+				if err := s.require(capPointDelete); err != nil {
+					return nil, err
+				}
+			}
+			return replayWorkload(dollarPlaceholders(cfg), p, values), nil
+		}})
+	}
+	return specs, nil
+}
+
+// replayWorkload issues the recorded operations of p, one worker per
+// recorded worker, each operation at its recorded offset or, once the
+// engine falls behind, right after the previous one. The phase lasts as
+// long as the recording plus Phase.Duration of slack; its Duration is the
+// time the replay took. The warmup replays the recorded warmup.
+func replayWorkload(dollar bool, p *opPhase, values map[string][]byte) WorkloadFunc {
+	queries := map[string]string{
+		"insert": bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`),
+		"select": bind(dollar, `SELECT v FROM kv WHERE k = ?`),
+		"range":  bind(dollar, `SELECT k,v FROM kv WHERE k BETWEEN ? AND ? LIMIT ?`),
+		"update": bind(dollar, `UPDATE kv SET v = ? WHERE k = ?`),
+		"delete": bind(dollar, `DELETE FROM kv WHERE k = ?`),
+	}
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		workers := p.measured
+		if ph.Warmup {
+			workers, p.warmedUp = p.warmup, true
+		} else if len(p.warmup) > 0 && !p.warmedUp {
+			log.Warn().Str("workload", p.name).Msg("the recorded warmup was not replayed (no --warmup); the data diverges from the recording")
+		}
+		ph.Concurrency = len(workers)
+		ph.Duration += span(workers)
+		ph.MinSamples, ph.record = 0, nil
+		res := newResult(p.name, ph)
+		if len(workers) == 0 {
+			return res.finalize()
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		stmts := make(map[string]*sql.Stmt)
+		for op, q := range queries {
+			if !p.has(op) {
+				continue
+			}
+			stmt, err := db.PrepareContext(ctx, q)
+			if err != nil {
+				res.addErrorCnt(err)
+				return res.finalize()
+			}
+			defer stmt.Close()
+			stmts[op] = stmt
+		}
+
+		raw := p.has("sql")
+		var (
+			wg     sync.WaitGroup
+			missed atomic.Int64 // operations the phase deadline left unreplayed
+		)
+		for w, ops := range workers {
+			wg.Add(1)
+			go func(worker int, ops []OpEntry) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				// imported statements may open and end transactions
+				// themselves, so they stay on one connection
+				var conn *sql.Conn
+				if raw {
+					c, err := db.Conn(ctx)
+					if err != nil {
+						res.addErrorCnt(err)
+						missed.Add(int64(len(ops)))
+						return
+					}
+					defer func() {
+						// a transaction the log left open ends here
+						_, _ = c.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+						_ = c.Close()
+					}()
+					conn = c
+				}
+				txOps := res.txOps(worker)
+				var (
+					tx      *sql.Tx
+					txStmt  *sql.Stmt
+					release func()
+				)
+				// endTx commits or rolls back the open insert transaction
+				endTx := func(commit bool) {
+					if tx == nil {
+						return
+					}
+					txStmt.Close()
+					if commit {
+						txOps.end(res.commit(tx) == nil)
+					} else {
+						_ = tx.Rollback()
+						txOps.end(false)
+					}
+					release()
+					tx = nil
+				}
+				defer endTx(false)
+
+				for i, e := range ops {
+					if wait := e.Offset - res.clock.Since(res.startedAt); wait > 0 {
+						select {
+						case <-ctx.Done():
+						case <-time.After(wait):
+						}
+					}
+					if ctx.Err() != nil {
+						missed.Add(int64(len(ops) - i))
+						return
+					}
+
+					switch e.Op {
+					case "sql":
+						if err := replaySQL(ctx, res, worker, conn, e.SQL); err != nil {
+							res.addErrorCnt(err)
+						}
+						continue
+					case "commit", "rollback":
+						endTx(e.Op == "commit")
+						continue
+					case "insert":
+						if tx == nil {
+							var err error
+							if release, err = res.acquireWrite(ctx); err != nil {
+								continue
+							}
+							// like insert, the transaction is ended by us, not by the deadline
+							if tx, err = db.BeginTx(context.WithoutCancel(ctx), nil); err != nil {
+								release()
+								res.addErrorCnt(err)
+								continue
+							}
+							txStmt = tx.StmtContext(ctx, stmts["insert"])
+						}
+						start := res.clock.Now()
+						octx, done := res.op(ctx)
+						_, err := txStmt.ExecContext(octx, e.Key, values[e.Payload])
+						if err = done(err); err != nil {
+							res.addErrorCnt(err)
+							if isCanceled(err) || isOpTimeout(err) {
+								endTx(false)
+							}
+							continue
+						}
+						txOps.add(res.clock.Since(start))
+						continue
+					}
+
+					if err := replayOp(ctx, res, worker, stmts[e.Op], e, values); err != nil {
+						res.addErrorCnt(err)
+					}
+				}
+			}(w, ops)
+		}
+		wg.Wait()
+		elapsed := res.clock.Since(res.startedAt)
+		out := res.finalize()
+		if !out.Aborted {
+			out.Duration = elapsed
+		}
+		if n := missed.Load(); n > 0 {
+			log.Warn().Str("workload", p.name).Int64("operations", n).Msg("replay fell behind the recording and ran out of time")
+		}
+		return out
+	}
+}
+
+// replaySQL runs an imported statement on the worker's connection,
+// reading every row it returns, and records its latency.
+func replaySQL(ctx context.Context, res *Result, worker int, conn *sql.Conn, q string) error {
+	start := res.clock.Now()
+	octx, done := res.op(ctx)
+	if returnsRows(q) {
+		rows, err := conn.QueryContext(octx, q)
+		if err != nil {
+			return done(err)
+		}
+		for rows.Next() {
+		}
+		_ = rows.Close()
+		if err := done(rows.Err()); err != nil {
+			return err
+		}
+	} else {
+		_, err := conn.ExecContext(octx, q)
+		if err = done(err); err != nil {
+			return err
+		}
+	}
+	res.addLatency(worker, res.clock.Since(start))
+	return nil
+}
+
+// returnsRows reports whether statement q is a query, by its first keyword
+// or a RETURNING clause.
+func returnsRows(q string) bool {
+	q = strings.TrimLeft(q, "( \t\r\n")
+	end := strings.IndexFunc(q, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(q)
+	}
+	switch strings.ToUpper(q[:end]) {
+	case "SELECT", "WITH", "VALUES", "TABLE", "SHOW", "EXPLAIN", "PRAGMA":
+		return true
+	}
+	return strings.Contains(strings.ToUpper(q), "RETURNING ")
+}
+
+// replayOp runs a recorded operation outside of a transaction, recording
+// its latency.
+func replayOp(ctx context.Context, res *Result, worker int, stmt *sql.Stmt, e OpEntry, values map[string][]byte) error {
+	if stmt == nil {
+		return fmt.Errorf("unknown operation %q", e.Op)
+	}
+	write := e.Op == "update" || e.Op == "delete"
+	if write {
+		release, err := res.acquireWrite(ctx)
+		if err != nil {
+			return nil
+		}
+		defer release()
+	}
+
+	start := res.clock.Now()
+	octx, done := res.op(ctx)
+	switch e.Op {
+	case "select":
+		var v []byte
+		if err := done(stmt.QueryRowContext(octx, e.Key).Scan(&v)); err != nil {
+			return err
+		}
+	case "range":
+		rows, err := stmt.QueryContext(octx, e.Key, e.End, e.Limit)
+		if err != nil {
+			return done(err)
+		}
+		for first := true; rows.Next(); first = false {
+			if first {
+				res.addFirstRow(res.clock.Since(start))
+			}
+			var k string
+			var v []byte
+			_ = rows.Scan(&k, &v)
+		}
+		_ = rows.Close()
+		if err := done(rows.Err()); err != nil {
+			return err
+		}
+	case "update":
+		_, err := stmt.ExecContext(octx, values[e.Payload], e.Key)
+		if err = done(err); err != nil {
+			return err
+		}
+	case "delete":
+		r, err := stmt.ExecContext(octx, e.Key)
+		if err = done(err); err != nil {
+			return err
+		}
+		res.addDeleted(e.Key)
+		if n, err := r.RowsAffected(); err == nil {
+			res.addRowsDeleted(n)
+		}
+	}
+	res.addLatency(worker, res.clock.Since(start))
+	return nil
+}
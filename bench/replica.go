@@ -0,0 +1,28 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// readOnlyPhase reports whether the phase only reads kv or its side
+// tables, so a Config.ReadDSN can serve it.
+func readOnlyPhase(name string) bool {
+	switch name {
+	case "select", "range", "select-in", "stream", "types-read", "doc-query", "sort", "group":
+		return true
+	}
+	return strings.HasPrefix(name, "filter-") || strings.HasPrefix(name, "text-")
+}
+
+// onReadDB runs wf, warmup included, on rdb instead of the database the
+// phase was given and marks its result as such. Pre-phase SQL still runs
+// on the primary.
+func onReadDB(rdb *sql.DB, wf WorkloadFunc) WorkloadFunc {
+	return func(ctx context.Context, _ Executor, ph Phase) Result {
+		res := wf(ctx, rdb, ph)
+		res.ReadDSN = true
+		return res
+	}
+}
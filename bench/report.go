@@ -0,0 +1,356 @@
+package bench
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the Report envelope written by this build.
+// Bump it whenever a field changes meaning or is removed, and teach
+// DecodeReport how to read the previous layout.
+//
+//	0: bare Result object or []Result array (no envelope)
+//	1: Report envelope with run id and config hash
+const SchemaVersion = 1
+
+// Metadata describes the environment a run was measured in.
+type Metadata struct {
+	Engine        string            `json:"engine"`
+	EngineVersion string            `json:"engine_version,omitempty"`
+	DSN           string            `json:"dsn,omitempty"`     // passwords masked
+	Drivers       map[string]string `json:"drivers,omitempty"` // module path -> version
+	GoVersion     string            `json:"go_version"`
+	OS            string            `json:"os"`
+	Arch          string            `json:"arch"`
+	NumCPU        int               `json:"num_cpu"`
+	GOMAXPROCS    int               `json:"gomaxprocs"`
+	CPUSet        []int             `json:"cpuset,omitempty"`
+	Dataset       string            `json:"dataset,omitempty"`
+	DatasetRows   int               `json:"dataset_rows,omitempty"`
+	Capabilities  []string          `json:"capabilities,omitempty"` // detected, see capability
+	Tags          map[string]string `json:"tags,omitempty"`         // --tag key=value labels
+	ReadDSN       string            `json:"read_dsn,omitempty"`     // passwords masked
+	IOThrottle    *IOThrottle       `json:"io_throttle,omitempty"`  // storage slowed down on purpose
+	Machine       *Machine          `json:"machine,omitempty"`      // hardware fingerprint
+	Disk          *DiskCalibration  `json:"disk,omitempty"`         // raw fsync and O_DIRECT latency
+	NoiseFloor    *NoiseFloor       `json:"noise_floor,omitempty"`  // harness overhead per operation
+	Timing        string            `json:"timing,omitempty"`       // Config.Timing unless wall
+	Parallel      []string          `json:"parallel,omitempty"`     // engines run at the same time, see RunEngines
+}
+
+// TagList renders the tags as sorted key=value pairs.
+func (m Metadata) TagList() string {
+	tags := make([]string, 0, len(m.Tags))
+	for k, v := range m.Tags {
+		tags = append(tags, k+"="+v)
+	}
+	slices.Sort(tags)
+	return strings.Join(tags, " ")
+}
+
+func newMetadata(cfg Config) Metadata {
+	m := Metadata{
+		Engine:     cfg.Engine,
+		DSN:        SanitizeDSN(cfg.DSN),
+		ReadDSN:    SanitizeDSN(cfg.ReadDSN),
+		Tags:       cfg.Tags,
+		Drivers:    driverVersions(),
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+	if pinSupported {
+		m.CPUSet = cfg.CPUSet
+	}
+	if t := cfg.IOThrottle; t.FsyncDelay > 0 || t.limited() {
+		m.IOThrottle = &t
+	}
+	if cfg.Timing != "wall" {
+		m.Timing = cfg.Timing
+	}
+	if cfg.PgxNative {
+		m.Engine = "pgx-native"
+	}
+	if cfg.Engine == "generic" {
+		m.Engine = "generic:" + cfg.Driver
+	}
+	return m
+}
+
+// Report is the outcome of a whole suite run.
+type Report struct {
+	SchemaVersion int      `json:"schema_version"`
+	RunID         string   `json:"run_id"`
+	ConfigHash    string   `json:"config_hash"`
+	Meta          Metadata `json:"meta"`
+	Results       []Result `json:"results"`
+}
+
+func newReport(runID string, cfg Config, meta Metadata, results []Result) *Report {
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		RunID:         runID,
+		ConfigHash:    configHash(cfg),
+		Meta:          meta,
+		Results:       results,
+	}
+}
+
+// newRunID returns a sortable, practically unique id such as
+// 20250102T150405Z-1a2b3c.
+func newRunID() string {
+	var b [3]byte
+	_, _ = rand.Read(b[:])
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b[:])
+}
+
+// configHash fingerprints the benchmark parameters so results of identical
+// configurations can be grouped across runs. Where the run writes its
+// output is not a parameter: --out-dir puts it somewhere new every time.
+func configHash(cfg Config) string {
+	cfg.EventLog, cfg.PprofDir, cfg.Record, cfg.Samples = "", "", "", 0
+	j, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(j)
+	return hex.EncodeToString(sum[:6])
+}
+
+func (r Report) Pretty() string { return r.PrettyWith(DefaultPrettyOptions) }
+
+// PrettyWith renders r like Pretty, with the given output options.
+func (r Report) PrettyWith(o PrettyOptions) string {
+	if o.Markdown {
+		return o.finish(r.markdown())
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Run\t\t\t: %s (config %s)\n", r.RunID, r.ConfigHash)
+	fmt.Fprintf(&b, "Engine\t\t: %s", r.Meta.Engine)
+	if r.Meta.EngineVersion != "" {
+		fmt.Fprintf(&b, " %s", r.Meta.EngineVersion)
+	}
+	fmt.Fprintf(&b, " (%s %s/%s)\n", r.Meta.GoVersion, r.Meta.OS, r.Meta.Arch)
+	if r.Meta.DSN != "" {
+		fmt.Fprintf(&b, "DSN\t\t\t: %s\n", r.Meta.DSN)
+	}
+	if r.Meta.ReadDSN != "" {
+		fmt.Fprintf(&b, "Read DSN\t: %s\n", r.Meta.ReadDSN)
+	}
+	if m := r.Meta.Machine; m != nil {
+		fmt.Fprintf(&b, "Machine\t\t: %s\n", m.pretty())
+	}
+	if len(r.Meta.Parallel) > 0 {
+		fmt.Fprintf(&b, "Parallel\t: with %s (results may interfere)\n", strings.Join(r.Meta.Parallel, ", "))
+	}
+	if r.Meta.Timing != "" {
+		fmt.Fprintf(&b, "Timing\t\t: %s clock\n", r.Meta.Timing)
+	}
+	if n := r.Meta.NoiseFloor; n != nil {
+		fmt.Fprintf(&b, "Noise floor\t: %s\n", n.pretty())
+	}
+	if d := r.Meta.Disk; d != nil {
+		fmt.Fprintf(&b, "Disk\t\t: %s\n", d.pretty())
+	}
+	if t := r.Meta.IOThrottle; t != nil {
+		fmt.Fprintf(&b, "IO throttle\t: %s\n", t.pretty())
+	}
+	if len(r.Meta.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags\t\t: %s\n", r.Meta.TagList())
+	}
+	if len(r.Meta.Drivers) > 0 {
+		mods := make([]string, 0, len(r.Meta.Drivers))
+		for m, v := range r.Meta.Drivers {
+			mods = append(mods, m+"@"+v)
+		}
+		slices.Sort(mods)
+		fmt.Fprintf(&b, "Drivers\t\t: %s\n", strings.Join(mods, ", "))
+	}
+	if len(r.Meta.Capabilities) > 0 {
+		fmt.Fprintf(&b, "Features\t: %s\n", strings.Join(r.Meta.Capabilities, ", "))
+	}
+	fmt.Fprintf(&b, "CPU\t\t\t: GOMAXPROCS=%d NumCPU=%d", r.Meta.GOMAXPROCS, r.Meta.NumCPU)
+	if len(r.Meta.CPUSet) > 0 {
+		fmt.Fprintf(&b, " cpuset=%v", r.Meta.CPUSet)
+	}
+	if r.Meta.Dataset != "" {
+		fmt.Fprintf(&b, "\nDataset\t\t: %s (%s rows)", r.Meta.Dataset, commaI(int64(r.Meta.DatasetRows)))
+	}
+	b.WriteString("\n\n")
+	for _, res := range r.Results {
+		b.WriteString(res.pretty(o))
+		b.WriteString("\n")
+	}
+	return o.finish(b.String())
+}
+
+// markdown renders r as a heading line and a table of its phases.
+func (r Report) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s", r.Meta.Engine)
+	if r.Meta.EngineVersion != "" {
+		fmt.Fprintf(&b, " %s", r.Meta.EngineVersion)
+	}
+	fmt.Fprintf(&b, "** (%s %s/%s), run `%s`, config `%s`", r.Meta.GoVersion, r.Meta.OS, r.Meta.Arch, r.RunID, r.ConfigHash)
+	if len(r.Meta.Tags) > 0 {
+		fmt.Fprintf(&b, ", tags `%s`", r.Meta.TagList())
+	}
+	b.WriteString("\n\n")
+	b.WriteString("| Workload | Concurrency | Ops/s | P50 | P95 | P99 | Errors | Notes |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|---|\n")
+	for _, res := range r.Results {
+		if res.Skipped {
+			fmt.Fprintf(&b, "| %s | | | | | | | skipped: %s |\n", res.Workload, mdEscape(res.SkipReason))
+			continue
+		}
+		var notes []string
+		if res.Aborted {
+			notes = append(notes, "aborted: "+mdEscape(res.AbortReason))
+		}
+		if res.Partitioned {
+			notes = append(notes, "partitioned")
+		}
+		if res.ReadDSN {
+			notes = append(notes, "read DSN")
+		}
+		fmt.Fprintf(&b, "| %s | %d | %.1f | %s | %s | %s | %s | %s |\n", res.Workload, res.Concurrency, opsPerSec(res),
+			fDur(res.P50), fDur(res.P95), fDur(res.P99), commaI(res.Errors), strings.Join(notes, ", "))
+	}
+	return b.String()
+}
+
+// mdEscape keeps s from breaking out of a markdown table cell.
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// SummaryLine is the one-line outcome of a run for wrapper scripts, with
+// fixed keys in a fixed order:
+//
+//	sqlbench: status=pass ops=120345 errors=12 phases=5 aborted=0 skipped=0 wall=31.204s
+//
+// status is fail when the run itself failed (err != nil; r may be nil then)
+// or a phase was aborted. Per-operation-type parts are not counted again.
+func SummaryLine(r *Report, wall time.Duration, err error) string {
+	var ops, errs int64
+	var phases, aborted, skipped int
+	if r != nil {
+		for _, res := range r.Results {
+			if res.Parent != "" {
+				continue
+			}
+			phases++
+			ops += res.Ops
+			errs += res.Errors
+			if res.Aborted {
+				aborted++
+			}
+			if res.Skipped {
+				skipped++
+			}
+		}
+	}
+	status := "pass"
+	if err != nil || aborted > 0 {
+		status = "fail"
+	}
+	return fmt.Sprintf("sqlbench: status=%s ops=%d errors=%d phases=%d aborted=%d skipped=%d wall=%.3fs",
+		status, ops, errs, phases, aborted, skipped, wall.Seconds())
+}
+
+func (r Report) JSON() string {
+	j, _ := json.MarshalIndent(r, "", "  ")
+	return string(j)
+}
+
+var csvHeader = []string{
+	"schema_version", "run_id", "config_hash", "engine", "workload", "concurrency",
+	"duration_ns", "ops", "errors", "p50_ns", "p95_ns", "p99_ns",
+}
+
+// WriteCSV writes one row per result, repeating the envelope fields on
+// every row so each line stands on its own once files are concatenated.
+func (r Report) WriteCSV(w io.Writer) error { return WriteCSVs(w, []*Report{&r}) }
+
+// WriteCSVs writes the rows of several reports, such as those of
+// RunEngines, under one header.
+func WriteCSVs(w io.Writer, reps []*Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	i64 := func(v int64) string { return strconv.FormatInt(v, 10) }
+	for _, r := range reps {
+		for _, res := range r.Results {
+			rec := []string{
+				strconv.Itoa(r.SchemaVersion), r.RunID, r.ConfigHash, r.Meta.Engine,
+				res.Workload, strconv.Itoa(res.Concurrency), i64(int64(res.Duration)),
+				i64(res.Ops), i64(res.Errors), i64(int64(res.P50)), i64(int64(res.P95)), i64(int64(res.P99)),
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// DecodeReport reads a JSON report written by this or any earlier version.
+// SchemaVersion of the returned report is the version that was read, so
+// callers can tell upgraded archives apart.
+func DecodeReport(r io.Reader) (*Report, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("decode report: empty input")
+	}
+
+	// v0: a bare array of results
+	if data[0] == '[' {
+		var results []Result
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("decode report v0: %w", err)
+		}
+		return &Report{Results: results}, nil
+	}
+
+	var probe struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+
+	switch v := probe.SchemaVersion; {
+	case v == nil: // v0: a single Result.JSON() object
+		var res Result
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("decode report v0: %w", err)
+		}
+		return &Report{Results: []Result{res}}, nil
+	case *v > SchemaVersion:
+		return nil, fmt.Errorf("decode report: schema version %d is newer than supported %d", *v, SchemaVersion)
+	default:
+		var rep Report
+		if err := json.Unmarshal(data, &rep); err != nil {
+			return nil, fmt.Errorf("decode report v%d: %w", *v, err)
+		}
+		return &rep, nil
+	}
+}
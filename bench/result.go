@@ -1,30 +1,247 @@
 package bench
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"runtime/debug"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 type Result struct {
-	Workload    string        `json:"workload"`
-	Concurrency int           `json:"concurrency"`
-	Duration    time.Duration `json:"duration"`
-	Ops         int64         `json:"ops"`
-	Errors      int64         `json:"errors"`
-	P50         time.Duration `json:"p50"`
-	P95         time.Duration `json:"p95"`
-	P99         time.Duration `json:"p99"`
+	Workload    string           `json:"workload"`
+	Parent      string           `json:"parent,omitempty"` // combined result this per-operation-type part belongs to
+	Concurrency int              `json:"concurrency"`
+	Duration    time.Duration    `json:"duration"`
+	Extension   time.Duration    `json:"extension,omitempty"` // part of Duration spent past Phase.Duration reaching Phase.MinSamples
+	Ops         int64            `json:"ops"`
+	Errors      int64            `json:"errors"`
+	ErrorKinds  map[string]int64 `json:"error_kinds,omitempty"`
+	// Canceled counts operations cut short by the phase ending under them;
+	// they are neither errors nor ops.
+	Canceled int64 `json:"canceled,omitempty"`
+	// CommitErrors counts failed commits (also counted in Errors).
+	CommitErrors int64 `json:"commit_errors,omitempty"`
+	// Timeouts counts operations that outlived Phase.OpTimeout (also
+	// counted in Errors, as kind "timeout").
+	Timeouts    int64         `json:"timeouts,omitempty"`
+	RowsRead    int64         `json:"rows_read,omitempty"`
+	RowsDeleted int64         `json:"rows_deleted,omitempty"`
+	Prepare     *LatencyStats `json:"prepare,omitempty"`
+	Deadlock    *LatencyStats `json:"deadlock,omitempty"`  // transaction start to deadlock error
+	FirstRow    *LatencyStats `json:"first_row,omitempty"` // query start to first row; ops time the last row
+	// PeakHeap is how far the live Go heap grew over its size at the phase
+	// start, i.e. what the driver (and an embedded engine) held in memory.
+	PeakHeap int64 `json:"peak_heap,omitempty"`
+	// WriteLimit is the configured bound on simultaneous writes (0 = none);
+	// EffectiveWriters the average number actually in flight.
+	WriteLimit       int     `json:"write_limit,omitempty"`
+	EffectiveWriters float64 `json:"effective_writers,omitempty"`
+	// Partitioned is set when each worker drew from a key range of its
+	// own rather than the whole key snapshot.
+	Partitioned bool `json:"partitioned,omitempty"`
+	// ReadDSN is set when the phase ran on Config.ReadDSN (a replica)
+	// rather than the primary.
+	ReadDSN bool `json:"read_dsn,omitempty"`
+	// Samples are raw operation latencies (thinned to Config.Samples) for
+	// significance tests between runs; omitted unless requested.
+	Samples []time.Duration `json:"samples,omitempty"`
+	// CDF is the empirical latency distribution at cdfQuantiles evenly
+	// spaced quantiles: CDF[i] is the (i+1)/cdfQuantiles quantile, the
+	// last one the maximum. Unlike p50/p95/p99 it shows bimodal latencies
+	// (e.g. WAL checkpoints) as a plateau.
+	CDF []time.Duration `json:"cdf,omitempty"`
+	// Timeline holds per-second operation counts and latency percentiles,
+	// so spikes (checkpoints, compaction) are not averaged away.
+	Timeline []SecondStats `json:"timeline,omitempty"`
+	// Fairness spreads the operations over workers, to spot connections
+	// starved by others (e.g. sqlite's write lock).
+	Fairness *Fairness      `json:"fairness,omitempty"`
+	Snapshot *SnapshotStats `json:"snapshot,omitempty"`
+	// Savepoints is what the savepoint workload's rollback checks saw.
+	Savepoints *SavepointStats `json:"savepoints,omitempty"`
+	FK         *FKStats        `json:"fk,omitempty"` // foreign key workloads' enforcement checks
+	// Hotspot is what the hotspot workload's contention retries saw.
+	Hotspot *HotspotStats `json:"hotspot,omitempty"`
+	// Cancel is how the cancel workload's cancellations were honored.
+	Cancel *CancelStats `json:"cancel,omitempty"`
+	// Conflicts is how the conflict workload's duplicate inserts were
+	// rejected.
+	Conflicts   *ConflictStats `json:"conflicts,omitempty"`
+	P50         time.Duration  `json:"p50"`
+	P95         time.Duration  `json:"p95"`
+	P99         time.Duration  `json:"p99"`
+	Trimmed     *TrimmedStats  `json:"trimmed,omitempty"` // percentiles without the first Phase.Trim
+	Net         *NetStats      `json:"net,omitempty"`     // percentiles less the harness overhead
+	IO          *IOStats       `json:"io,omitempty"`      // none for suites run in parallel
+	Aborted     bool           `json:"aborted,omitempty"`
+	AbortReason string         `json:"abort_reason,omitempty"`
+	// Stalls are the stretches of collapsed throughput Phase.StallAlarm
+	// caught while the phase ran.
+	Stalls     []Stall      `json:"stalls,omitempty"`
+	PrePhase   *PrePhase    `json:"pre_phase,omitempty"` // Config.PrePhaseSQL run before the measurement
+	Delta      *Delta       `json:"delta,omitempty"`     // change against an earlier phase (analyze, constraints experiments)
+	Health     *HealthCheck `json:"health,omitempty"`    // database check right after the phase
+	Skipped    bool         `json:"skipped,omitempty"`   // the engine lacks a feature the workload needs
+	SkipReason string       `json:"skip_reason,omitempty"`
 
 	// internal
-	hist          histogram          `json:"-"`
-	latCh         chan time.Duration `json:"-"`
-	collectorDone chan struct{}      `json:"-"`
+	hist          histogram            `json:"-"`
+	errKinds      [numErrClasses]int64 `json:"-"`
+	prepHist      histogram            `json:"-"`
+	deadHist      histogram            `json:"-"`
+	firstHist     histogram            `json:"-"`
+	latCh         chan sample          `json:"-"`
+	bufs          []latBuf             `json:"-"` // per-worker operation latencies, see addLatency
+	batchCh       chan latBatch        `json:"-"` // buffers workers flushed when full
+	collectorDone chan struct{}        `json:"-"`
+	phase         Phase                `json:"-"`
+	clock         Clock                `json:"-"` // phase.clock()
+	stop          context.CancelFunc   `json:"-"`
+	startedAt     time.Time            `json:"-"`
+	watchDone     chan struct{}        `json:"-"`
+	memDone       chan struct{}        `json:"-"`
+	memReason     string               `json:"-"` // set by watchMemory when Phase.MemLimit was exceeded
+	extendDone    chan struct{}        `json:"-"`
+	extended      bool                 `json:"-"` // set by watchSamples when the phase ran past Phase.Duration
+	stallDone     chan struct{}        `json:"-"`
+	writes        *writeLimiter        `json:"-"`
+	createdAt     time.Time            `json:"-"`
+	secStarts     []int                `json:"-"` // index in hist of each second's first sample
+	sampleWorker  []int32              `json:"-"` // worker of each sample in hist
+	log           zerolog.Logger       `json:"-"`
+	parts         []*Result            `json:"-"` // per-operation-type results, see split
+	byOp          []Result             `json:"-"` // finalized parts, reported after r
+	panicked      int32                `json:"-"` // set by the first worker panic, which writes panicReason
+	done          int32                `json:"-"` // set once the phase context ends, see stopped
+	doneSet       chan struct{}        `json:"-"` // closed once start's AfterFunc has set done
+	phaseCtx      context.Context      `json:"-"` // from start
+	panicReason   string               `json:"-"`
+	rows          *rowChanges          `json:"-"`
+	// heap allocations during the measured phase, for RunBenchmarks
+	mallocs    uint64 `json:"-"`
+	allocBytes uint64 `json:"-"`
+}
+
+// LatencyStats summarizes a secondary latency measured alongside the
+// phase's operations, such as statement preparation.
+type LatencyStats struct {
+	Count int64         `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+}
+
+func (s LatencyStats) pretty() string {
+	return fmt.Sprintf("%s  P50=%s  P95=%s  P99=%s", commaI(s.Count), fDur(s.P50), fDur(s.P95), fDur(s.P99))
+}
+
+// TrimmedStats are the operation latencies of a phase after its first
+// Dropped.
+type TrimmedStats struct {
+	Dropped time.Duration `json:"dropped"`
+	LatencyStats
+}
+
+func (s TrimmedStats) pretty() string {
+	return fmt.Sprintf("without the first %s: %s", s.Dropped, s.LatencyStats.pretty())
+}
+
+// trimmed summarizes the operations after the first Phase.Trim, rounded
+// up to whole seconds of the timeline, or returns nil when there are none.
+func (r *Result) trimmed() *TrimmedStats {
+	if r.phase.Trim <= 0 || r.phase.Warmup {
+		return nil
+	}
+	n := int((r.phase.Trim + time.Second - 1) / time.Second)
+	if n >= len(r.secStarts) {
+		return nil
+	}
+	h := histogram{r.hist.samples[r.secStarts[n]:]}
+	s := h.stats()
+	if s == nil {
+		return nil
+	}
+	return &TrimmedStats{Dropped: time.Duration(n) * time.Second, LatencyStats: *s}
+}
+
+// WorkerStats is one worker's share of a phase.
+type WorkerStats struct {
+	Ops int64         `json:"ops"`
+	P50 time.Duration `json:"p50"`
+	P99 time.Duration `json:"p99"`
+}
+
+// Fairness summarizes per-worker throughput: Gini is 0 when every worker
+// completed the same number of operations and approaches 1 when a single
+// worker did all of them.
+type Fairness struct {
+	MinOps  int64         `json:"min_ops"`
+	MaxOps  int64         `json:"max_ops"`
+	Gini    float64       `json:"gini"`
+	Workers []WorkerStats `json:"workers"`
+}
+
+// SecondStats summarizes the operations completed in one second of a phase.
+type SecondStats struct {
+	Ops int64         `json:"ops"`
+	P50 time.Duration `json:"p50"`
+	P99 time.Duration `json:"p99"`
+}
+
+// sampleKind tells operation latencies apart from the secondary latencies
+// some workloads record next to them.
+type sampleKind uint8
+
+const (
+	sampleOp sampleKind = iota
+	samplePrepare
+	sampleDeadlock
+	sampleFirstRow
+)
+
+type sample struct {
+	d      time.Duration
+	kind   sampleKind
+	worker int32
+}
+
+const (
+	// latBufSize is how many operation latencies a worker buffers before
+	// handing them to the collector itself.
+	latBufSize = 512
+	// latFlushEvery is how often the collector drains the workers'
+	// buffers, so the timeline and the watchdogs trail the operations by
+	// at most this much.
+	latFlushEvery = 10 * time.Millisecond
+)
+
+// latBuf is one worker's buffer of operation latencies. Only its worker
+// and the collector's periodic flush lock it, so unlike a channel shared
+// by every worker it is all but uncontended.
+type latBuf struct {
+	mu sync.Mutex
+	d  []time.Duration
+	_  [32]byte // keeps neighbouring workers' buffers off one cache line
+}
+
+// latBatch is a worker's flushed buffer.
+type latBatch struct {
+	worker int32
+	d      []time.Duration
 }
 
 // --------- histogram + quantile ---------
@@ -43,6 +260,36 @@ func (h *histogram) quantile(q float64) time.Duration {
 	return s[idx]
 }
 
+// stats summarizes h, or returns nil when it holds no samples.
+func (h *histogram) stats() *LatencyStats {
+	if len(h.samples) == 0 {
+		return nil
+	}
+	return &LatencyStats{
+		Count: int64(len(h.samples)),
+		P50:   h.quantile(0.50),
+		P95:   h.quantile(0.95),
+		P99:   h.quantile(0.99),
+	}
+}
+
+// cdfQuantiles is the resolution of Result.CDF.
+const cdfQuantiles = 100
+
+// cdf returns the n-quantiles of h from 1/n to 1, or nil when empty.
+func (h *histogram) cdf(n int) []time.Duration {
+	if len(h.samples) == 0 {
+		return nil
+	}
+	s := slices.Clone(h.samples)
+	slices.Sort(s)
+	out := make([]time.Duration, n)
+	for i := range out {
+		out[i] = s[int(float64(len(s)-1)*float64(i+1)/float64(n))]
+	}
+	return out
+}
+
 func (h *histogram) export() []time.Duration {
 	if len(h.samples) == 0 {
 		return nil
@@ -54,43 +301,511 @@ func (h *histogram) export() []time.Duration {
 
 // --------- constructors & updates ---------
 
-func newResult(name string, conc int, dur time.Duration) *Result {
+func newResult(name string, ph Phase) *Result {
 	r := &Result{
 		Workload:      name,
-		Concurrency:   conc,
-		Duration:      dur,
-		latCh:         make(chan time.Duration, 1<<16),
+		Concurrency:   ph.Concurrency,
+		Duration:      ph.Duration,
+		WriteLimit:    ph.WriteLimit,
+		Partitioned:   ph.Partitioned,
+		phase:         ph,
+		clock:         ph.clock(),
+		writes:        newWriteLimiter(ph.WriteLimit, ph.clock()),
+		latCh:         make(chan sample, 1<<16),
+		bufs:          make([]latBuf, ph.Concurrency),
+		batchCh:       make(chan latBatch, 64),
 		collectorDone: make(chan struct{}),
+		createdAt:     ph.clock().Now(),
+		rows:          newRowChanges(),
+		// a phase failing thousands of times a second logs a few lines
+		log: log.With().Str("workload", name).Logger().
+			Sample(&zerolog.BurstSampler{Burst: 5, Period: time.Second}),
 	}
 	go r.collector()
 	return r
 }
 
+// SyntheticResult builds the finalized Result of a phase that measured the
+// operation latencies secs, one slice per second, spread round-robin over
+// concurrency workers, without running anything, so rendering and
+// comparison can be exercised on fixed data from outside the package.
+// Other exported fields (Errors, Skipped, ...) can be set on the result.
+func SyntheticResult(workload string, concurrency int, secs [][]time.Duration) Result {
+	r := &Result{
+		Workload:    workload,
+		Concurrency: concurrency,
+		Duration:    time.Duration(len(secs)) * time.Second,
+		phase:       Phase{Concurrency: concurrency, Duration: time.Duration(len(secs)) * time.Second},
+		clock:       wallClock{},
+		rows:        newRowChanges(),
+	}
+	for _, s := range secs {
+		r.secStarts = append(r.secStarts, len(r.hist.samples))
+		for _, d := range s {
+			r.sampleWorker = append(r.sampleWorker, int32(len(r.hist.samples)%max(1, concurrency)))
+			r.hist.add(d)
+		}
+	}
+	r.Ops = int64(len(r.hist.samples))
+	return r.finalize()
+}
+
+// start derives the phase context bounded by the phase duration and arms
+// the error-budget watchdog, which may cancel it early.
+func (r *Result) start(ctx context.Context) (context.Context, context.CancelFunc) {
+	limit := r.phase.Duration
+	if r.phase.MinSamples > 0 && !r.phase.Warmup {
+		limit = max(limit, r.phase.maxDuration())
+	}
+	ctx, cancelCtx := withDeadline(ctx, r.clock, limit)
+	r.doneSet = make(chan struct{})
+	context.AfterFunc(ctx, func() {
+		atomic.StoreInt32(&r.done, 1)
+		close(r.doneSet)
+	})
+	// stopping the phase ourselves raises the flag before the workers can
+	// see their statements canceled
+	cancel := func() {
+		atomic.StoreInt32(&r.done, 1)
+		cancelCtx()
+	}
+	r.stop = cancel
+	r.phaseCtx = ctx
+	r.startedAt = r.clock.Now()
+	if limit > r.phase.Duration {
+		r.extendDone = make(chan struct{})
+		go func() {
+			defer close(r.extendDone)
+			r.watchSamples(ctx, cancel)
+		}()
+	}
+	if b := r.phase.ErrorBudget; b.enabled() {
+		r.watchDone = make(chan struct{})
+		go func() {
+			defer close(r.watchDone)
+			r.watchErrors(ctx, cancel, b)
+		}()
+	}
+	if limit := r.phase.MemLimit; limit > 0 {
+		r.memDone = make(chan struct{})
+		go func() {
+			defer close(r.memDone)
+			r.watchMemory(ctx, cancel, limit)
+		}()
+	}
+	if a := r.phase.StallAlarm; a.enabled() && !r.phase.Warmup {
+		r.stallDone = make(chan struct{})
+		go func() {
+			defer close(r.stallDone)
+			r.watchStalls(ctx, a)
+		}()
+	}
+	return ctx, cancel
+}
+
 func (r *Result) collector() {
-	for d := range r.latCh {
+	defer close(r.collectorDone)
+	tick := r.clock.NewTicker(latFlushEvery)
+	defer tick.Stop()
+	for {
+		select {
+		case s, ok := <-r.latCh:
+			if !ok { // finalize: the workers are done
+				r.drainBatches()
+				r.flushBufs()
+				return
+			}
+			switch s.kind {
+			case samplePrepare:
+				r.prepHist.add(s.d)
+			case sampleFirstRow:
+				r.firstHist.add(s.d)
+			case sampleDeadlock:
+				r.deadHist.add(s.d)
+			default:
+				r.collectOps(s.worker, s.d)
+			}
+		case b := <-r.batchCh:
+			r.collectOps(b.worker, b.d...)
+		case <-tick.C():
+			r.flushBufs()
+		}
+	}
+}
+
+// collectOps adds operation latencies of worker to the histogram. They are
+// bucketed into seconds by when the collector sees them, which trails
+// completion by up to latFlushEvery.
+func (r *Result) collectOps(worker int32, ds ...time.Duration) {
+	for sec := int(r.clock.Since(r.createdAt) / time.Second); len(r.secStarts) <= sec; {
+		r.secStarts = append(r.secStarts, len(r.hist.samples))
+	}
+	for _, d := range ds {
 		r.hist.add(d)
-		atomic.AddInt64(&r.Ops, 1)
+		r.sampleWorker = append(r.sampleWorker, worker)
+	}
+	atomic.AddInt64(&r.Ops, int64(len(ds)))
+}
+
+// flushBufs collects what the workers have buffered so far.
+func (r *Result) flushBufs() {
+	for i := range r.bufs {
+		b := &r.bufs[i]
+		b.mu.Lock()
+		ds := b.d
+		b.d = b.d[len(b.d):]
+		b.mu.Unlock()
+		r.collectOps(int32(i), ds...)
+	}
+}
+
+func (r *Result) drainBatches() {
+	for {
+		select {
+		case b := <-r.batchCh:
+			r.collectOps(b.worker, b.d...)
+		default:
+			return
+		}
+	}
+}
+
+// addLatency records an operation of worker in the worker's own buffer,
+// which the collector drains every latFlushEvery; a worker filling it
+// hands it over itself.
+func (r *Result) addLatency(worker int, d time.Duration) {
+	if worker < 0 || worker >= len(r.bufs) {
+		r.latCh <- sample{d: d, kind: sampleOp, worker: int32(worker)}
+		return
+	}
+	b := &r.bufs[worker]
+	b.mu.Lock()
+	if b.d == nil {
+		b.d = make([]time.Duration, 0, latBufSize)
+	}
+	b.d = append(b.d, d)
+	var full []time.Duration
+	if len(b.d) == cap(b.d) {
+		full, b.d = b.d, nil
+	}
+	b.mu.Unlock()
+	if full != nil {
+		r.batchCh <- latBatch{worker: int32(worker), d: full}
+	}
+}
+
+// stopped reports whether the phase context has ended. Workers check it
+// between operations: an atomic load, cheaper than a select on
+// ctx.Done() at every operation.
+func (r *Result) stopped() bool { return atomic.LoadInt32(&r.done) != 0 }
+
+// addPrepare records the time spent preparing a statement, reported in
+// Prepare rather than the operation latencies.
+func (r *Result) addPrepare(d time.Duration) {
+	r.latCh <- sample{d: d, kind: samplePrepare}
+}
+
+// addDeadlock records how long a transaction ran before the engine broke
+// the deadlock it was part of.
+func (r *Result) addDeadlock(d time.Duration) {
+	r.latCh <- sample{d: d, kind: sampleDeadlock}
+}
+
+// addFirstRow records the time from issuing a query to its first row,
+// reported in FirstRow next to the time to the last row.
+func (r *Result) addFirstRow(d time.Duration) {
+	r.latCh <- sample{d: d, kind: sampleFirstRow}
+}
+
+func (r *Result) addRows(n int64) { atomic.AddInt64(&r.RowsRead, n) }
+
+func (r *Result) addRowsDeleted(n int64) { atomic.AddInt64(&r.RowsDeleted, n) }
+
+// op bounds one operation by Phase.OpTimeout, so a stuck statement costs
+// its worker that long rather than the rest of the phase. Pass the error
+// of the operation to done, which releases the deadline and turns the
+// error into an opTimeoutError if the operation's own deadline (not the
+// phase's) ended it.
+func (r *Result) op(ctx context.Context) (context.Context, func(error) error) {
+	if r.phase.OpTimeout <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+	octx, cancel := withDeadline(ctx, r.clock, r.phase.OpTimeout)
+	return octx, func(err error) error {
+		timedOut := err != nil && ctx.Err() == nil && octx.Err() != nil
+		cancel()
+		if timedOut {
+			return &opTimeoutError{r.phase.OpTimeout}
+		}
+		return err
+	}
+}
+
+// commit commits tx, counting a failure in Errors and CommitErrors.
+func (r *Result) commit(tx *sql.Tx) error {
+	err := tx.Commit()
+	if err != nil {
+		atomic.AddInt64(&r.CommitErrors, 1)
+		r.addErrorCnt(err)
+	}
+	return err
+}
+
+// txOps records the statements of one worker's insert transactions.
+// Without Phase.DropFailedTx each latency is recorded as it comes; with it
+// they are held until end, which drops them unless the transaction
+// committed. Committed rows are counted for the kv row ledger.
+type txOps struct {
+	res     *Result
+	worker  int
+	rows    int64 // statements of the current transaction
+	pending []time.Duration
+}
+
+func (r *Result) txOps(worker int) *txOps { return &txOps{res: r, worker: worker} }
+
+func (t *txOps) add(d time.Duration) {
+	t.rows++
+	if !t.res.phase.DropFailedTx {
+		t.res.addLatency(t.worker, d)
+		return
+	}
+	t.pending = append(t.pending, d)
+}
+
+func (t *txOps) end(committed bool) {
+	if committed {
+		for _, d := range t.pending {
+			t.res.addLatency(t.worker, d)
+		}
+		t.res.addInserted(t.rows)
+	}
+	t.pending, t.rows = t.pending[:0], 0
+}
+
+// split returns the result of one operation type of r's phase, named
+// "<workload>/<op>". Workers record each operation in r and in its part;
+// the parts are finalized with r and reported as results of their own
+// after it. Call split before the workers start.
+func (r *Result) split(op string) *Result {
+	p := newResult(r.Workload+"/"+op, r.phase)
+	p.Parent = r.Workload
+	r.parts = append(r.parts, p)
+	return p
+}
+
+// acquireWrite takes a slot from the phase's write limiter; call the
+// returned func once the write (or write transaction) is done.
+func (r *Result) acquireWrite(ctx context.Context) (func(), error) {
+	return r.writes.acquire(ctx)
+}
+
+func (r *Result) addErrorCnt(err error) {
+	if err != nil && isCanceled(err) {
+		atomic.AddInt64(&r.Canceled, 1)
+		if r.phaseCtx != nil && r.phaseCtx.Err() != nil {
+			// the phase ended under the operation; don't wait for the
+			// AfterFunc in start to tell the workers
+			atomic.StoreInt32(&r.done, 1)
+		}
+		return
+	}
+	atomic.AddInt64(&r.Errors, 1)
+	if err != nil {
+		c := classifyError(err)
+		if c == errTimeout {
+			atomic.AddInt64(&r.Timeouts, 1)
+		}
+		atomic.AddInt64(&r.errKinds[c], 1)
+		r.log.Debug().Err(err).Str("kind", c.String()).Msg("operation failed")
+	}
+}
+
+// recoverWorker turns a panic in a goroutine running workload code into a
+// fatal error of the phase instead of a crash of the whole benchmark: it is
+// logged with its stack, counted as an error, and aborts the phase, whose
+// results so far are kept. Defer it right after wg.Done.
+func (r *Result) recoverWorker() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	reason := fmt.Sprintf("worker panicked: %v", p)
+	log.Error().Str("workload", r.Workload).Str("panic", fmt.Sprint(p)).Bytes("stack", debug.Stack()).Msg("worker panicked")
+	r.addErrorCnt(errors.New(reason))
+	if atomic.CompareAndSwapInt32(&r.panicked, 0, 1) {
+		r.panicReason = reason
+	}
+	if r.stop != nil {
+		r.stop()
 	}
-	close(r.collectorDone)
 }
 
-func (r *Result) addLatency(d time.Duration) {
-	r.latCh <- d
+// logError logs a worker-level failure through the phase's sampled logger
+// and counts it.
+func (r *Result) logError(err error, msg string) {
+	r.log.Error().Err(err).Msg(msg)
+	r.addErrorCnt(err)
 }
 
-func (r *Result) addErrorCnt(_ error) { atomic.AddInt64(&r.Errors, 1) }
 func (r *Result) finalize() Result {
-	close(r.latCh)
-	<-r.collectorDone
+	if r.stop != nil {
+		r.stop()
+	}
+	if r.doneSet != nil {
+		<-r.doneSet // the result is copied out below, done included
+	}
+	if r.stallDone != nil {
+		<-r.stallDone
+	}
+	if r.watchDone != nil {
+		<-r.watchDone
+		if r.AbortReason != "" {
+			r.Aborted = true
+			r.Duration = r.clock.Since(r.startedAt) // ops/s over the time actually run
+		}
+	}
+	if r.memDone != nil {
+		<-r.memDone
+		if r.memReason != "" && !r.Aborted {
+			r.Aborted, r.AbortReason = true, r.memReason
+			r.Duration = r.clock.Since(r.startedAt)
+		}
+	}
+	if r.extendDone != nil {
+		<-r.extendDone
+		if r.extended {
+			if !r.Aborted {
+				r.Duration = min(r.clock.Since(r.startedAt), r.phase.maxDuration())
+			}
+			r.Extension = max(0, r.Duration-r.phase.Duration)
+			if atomic.LoadInt64(&r.Ops) < int64(r.phase.MinSamples) && !r.Aborted {
+				r.log.Warn().Int64("ops", atomic.LoadInt64(&r.Ops)).Int("min_samples", r.phase.MinSamples).Dur("duration", r.Duration).Msg("phase reached its maximum duration short of min samples")
+			}
+		}
+	}
+	if atomic.LoadInt32(&r.panicked) == 1 && !r.Aborted {
+		r.Aborted, r.AbortReason = true, r.panicReason
+		if !r.startedAt.IsZero() {
+			r.Duration = r.clock.Since(r.startedAt)
+		}
+	}
+	if r.latCh != nil { // nil for SyntheticResult
+		close(r.latCh)
+		<-r.collectorDone
+	}
+	if !r.startedAt.IsZero() {
+		r.EffectiveWriters = r.writes.effective(r.clock.Since(r.startedAt))
+	}
+
+	for c, n := range r.errKinds {
+		if n == 0 || errClass(c) == errOther {
+			continue
+		}
+		if r.ErrorKinds == nil {
+			r.ErrorKinds = make(map[string]int64)
+		}
+		r.ErrorKinds[errClass(c).String()] = n
+	}
 
 	r.P50 = r.hist.quantile(0.50)
 	r.P95 = r.hist.quantile(0.95)
 	r.P99 = r.hist.quantile(0.99)
+	r.CDF = r.hist.cdf(cdfQuantiles)
+	r.Trimmed = r.trimmed()
+	r.Timeline = r.timeline()
+	r.Fairness = r.fairness()
+	r.Prepare = r.prepHist.stats()
+	r.Deadlock = r.deadHist.stats()
+	r.FirstRow = r.firstHist.stats()
+	for _, p := range r.parts {
+		p.Duration, p.Aborted, p.AbortReason = r.Duration, r.Aborted, r.AbortReason
+		r.byOp = append(r.byOp, p.finalize())
+	}
 	return *r
 }
 
+// seconds splits the operation latencies into per-second slices.
+func (r *Result) seconds() [][]time.Duration {
+	out := make([][]time.Duration, len(r.secStarts))
+	for i, lo := range r.secStarts {
+		hi := len(r.hist.samples)
+		if i+1 < len(r.secStarts) {
+			hi = r.secStarts[i+1]
+		}
+		out[i] = r.hist.samples[lo:hi]
+	}
+	return out
+}
+
+func (r *Result) timeline() []SecondStats {
+	secs := r.seconds()
+	if len(secs) == 0 {
+		return nil
+	}
+	out := make([]SecondStats, len(secs))
+	for i, s := range secs {
+		h := histogram{s}
+		out[i] = SecondStats{Ops: int64(len(s)), P50: h.quantile(0.50), P99: h.quantile(0.99)}
+	}
+	return out
+}
+
+func (r *Result) fairness() *Fairness {
+	if r.Concurrency < 2 || len(r.hist.samples) == 0 {
+		return nil
+	}
+	per := make([][]time.Duration, r.Concurrency)
+	for i, w := range r.sampleWorker {
+		if int(w) < len(per) {
+			per[w] = append(per[w], r.hist.samples[i])
+		}
+	}
+	workers := make([]WorkerStats, len(per))
+	for w, s := range per {
+		h := histogram{s}
+		workers[w] = WorkerStats{Ops: int64(len(s)), P50: h.quantile(0.50), P99: h.quantile(0.99)}
+	}
+	return fairnessOf(workers)
+}
+
+func fairnessOf(workers []WorkerStats) *Fairness {
+	f := &Fairness{MinOps: math.MaxInt64, Workers: workers}
+	ops := make([]float64, len(workers))
+	for w, s := range workers {
+		f.MinOps, f.MaxOps = min(f.MinOps, s.Ops), max(f.MaxOps, s.Ops)
+		ops[w] = float64(s.Ops)
+	}
+	f.Gini = gini(ops)
+	return f
+}
+
+// gini is the Gini coefficient of xs: the mean absolute difference between
+// all pairs, relative to twice the mean.
+func gini(xs []float64) float64 {
+	s := slices.Clone(xs)
+	slices.Sort(s)
+	var sum, weighted float64
+	for i, x := range s {
+		sum += x
+		weighted += float64(2*(i+1)-len(s)-1) * x
+	}
+	if sum == 0 {
+		return 0
+	}
+	return weighted / (float64(len(s)) * sum)
+}
+
 // --------- pretty printers ---------
-func (r Result) Pretty() string {
+func (r Result) Pretty() string { return r.PrettyWith(DefaultPrettyOptions) }
+
+// PrettyWith renders r like Pretty, with the given output options.
+func (r Result) PrettyWith(o PrettyOptions) string { return o.finish(r.pretty(o)) }
+
+func (r Result) pretty(o PrettyOptions) string {
+	if r.Skipped {
+		return fmt.Sprintf("Workload\t: %s\nSkipped\t\t: %s\n", r.Workload, r.SkipReason)
+	}
 	opsPerSec := 0.0
 	if r.Duration > 0 {
 		opsPerSec = float64(r.Ops) / r.Duration.Seconds()
@@ -100,18 +815,149 @@ func (r Result) Pretty() string {
 		errRate = float64(r.Errors) * 100 / float64(r.Ops)
 	}
 
-	minDur, maxDur, spark := sparkline(r.hist.samples, 14)
+	minDur, maxDur, spark := sparkline(r.hist.samples, o.sparkBins(), o.sparkRunes())
 
 	var b strings.Builder
-	fmt.Fprintf(&b, "Workload\t: %s\n", r.Workload)
-	fmt.Fprintf(&b, "Concurrency\t: %d\n", r.Concurrency)
-	fmt.Fprintf(&b, "Duration\t: %s\n", r.Duration)
+	if r.ReadDSN {
+		fmt.Fprintf(&b, "Workload\t: %s (read DSN)\n", r.Workload)
+	} else {
+		fmt.Fprintf(&b, "Workload\t: %s\n", r.Workload)
+	}
+	if r.Partitioned {
+		fmt.Fprintf(&b, "Concurrency\t: %d (partitioned key space)\n", r.Concurrency)
+	} else {
+		fmt.Fprintf(&b, "Concurrency\t: %d\n", r.Concurrency)
+	}
+	if r.Extension > 0 {
+		fmt.Fprintf(&b, "Duration\t: %s (extended by %s for min samples)\n", r.Duration, r.Extension)
+	} else {
+		fmt.Fprintf(&b, "Duration\t: %s\n", r.Duration)
+	}
 	fmt.Fprintf(&b, "Ops\t\t\t: %s (%.1f ops/s)\n", commaI(r.Ops), opsPerSec)
-	fmt.Fprintf(&b, "Errors\t\t: %s (%.2f%%)\n", commaI(r.Errors), errRate)
+	errLine := fmt.Sprintf("%s (%.2f%%)", commaI(r.Errors), errRate)
+	if r.Errors > 0 {
+		errLine = o.paint(ansiRed, errLine)
+	}
+	fmt.Fprintf(&b, "Errors\t\t: %s\n", errLine)
+	if r.CommitErrors > 0 {
+		fmt.Fprintf(&b, "Commit errors\t: %s\n", o.paint(ansiRed, commaI(r.CommitErrors)))
+	}
+	if r.Timeouts > 0 {
+		fmt.Fprintf(&b, "Timeouts\t: %s (exceeded --op-timeout %s)\n", o.paint(ansiRed, commaI(r.Timeouts)), r.phase.OpTimeout)
+	}
+	if r.Canceled > 0 {
+		fmt.Fprintf(&b, "Canceled\t: %s (cut short by the phase end, not counted as errors)\n", commaI(r.Canceled))
+	}
+	if r.RowsRead > 0 && r.Ops > 0 {
+		fmt.Fprintf(&b, "Rows read\t: %s (%.1f/op)\n", commaI(r.RowsRead), float64(r.RowsRead)/float64(r.Ops))
+	}
+	if r.RowsDeleted > 0 && r.Ops > 0 {
+		fmt.Fprintf(&b, "Rows deleted\t: %s (%.1f/op)\n", commaI(r.RowsDeleted), float64(r.RowsDeleted)/float64(r.Ops))
+	}
+	if len(r.ErrorKinds) > 0 {
+		kinds := make([]string, 0, len(r.ErrorKinds))
+		for k, n := range r.ErrorKinds {
+			kinds = append(kinds, fmt.Sprintf("%s=%s", k, commaI(n)))
+		}
+		slices.Sort(kinds)
+		fmt.Fprintf(&b, "Error kinds\t: %s\n", strings.Join(kinds, "  "))
+	}
+	if r.Aborted {
+		fmt.Fprintf(&b, "Aborted\t\t: %s\n", o.paint(ansiRed, r.AbortReason))
+	}
+	if len(r.Stalls) > 0 {
+		lines := make([]string, len(r.Stalls))
+		for i, s := range r.Stalls {
+			lines[i] = s.pretty()
+		}
+		fmt.Fprintf(&b, "Stalls\t\t: %s\n", o.paint(ansiRed, strings.Join(lines, "; ")))
+	}
+	if d := r.Delta; d != nil {
+		fmt.Fprintf(&b, "Delta\t\t: %s\n", d)
+	}
+	if p := r.PrePhase; p != nil {
+		line := p.String()
+		if p.Error != "" {
+			line = o.paint(ansiRed, line)
+		}
+		fmt.Fprintf(&b, "Pre-phase SQL\t: %s\n", line)
+	}
+	if h := r.Health; h != nil {
+		line := h.String()
+		if !h.OK() {
+			line = o.paint(ansiRed, line)
+		}
+		fmt.Fprintf(&b, "Health\t\t: %s\n", line)
+	}
+	if f := r.Fairness; f != nil && r.Duration > 0 {
+		fmt.Fprintf(&b, "Fairness\t: per-worker ops/s min %.1f  max %.1f  gini %.2f\n",
+			float64(f.MinOps)/r.Duration.Seconds(), float64(f.MaxOps)/r.Duration.Seconds(), f.Gini)
+	}
+	if r.EffectiveWriters > 0 {
+		limit := "unlimited"
+		if r.WriteLimit > 0 {
+			limit = strconv.Itoa(r.WriteLimit)
+		}
+		fmt.Fprintf(&b, "Writers\t\t: %.2f effective (limit %s, %d workers)\n", r.EffectiveWriters, limit, r.Concurrency)
+	}
 	fmt.Fprintf(&b, "Latency\t\t: P50=%s  P95=%s  P99=%s\n", fDur(r.P50), fDur(r.P95), fDur(r.P99))
+	if t := r.Trimmed; t != nil {
+		fmt.Fprintf(&b, "Trimmed\t\t: %s\n", t.pretty())
+	}
+	if n := r.Net; n != nil {
+		fmt.Fprintf(&b, "Net\t\t\t: %s\n", n.pretty())
+	}
 	if spark != "" {
 		fmt.Fprintf(&b, "Histogram\t: %s  (min %s, max %s)\n", spark, fDur(minDur), fDur(maxDur))
 	}
+	if r.Snapshot != nil {
+		fmt.Fprintf(&b, "Snapshot\t: %s\n", r.Snapshot.pretty())
+	}
+	if r.Savepoints != nil {
+		fmt.Fprintf(&b, "Savepoints\t: %s\n", r.Savepoints.pretty())
+	}
+	if r.FK != nil {
+		fmt.Fprintf(&b, "Foreign keys\t: %s\n", r.FK.pretty())
+	}
+	if r.Conflicts != nil {
+		fmt.Fprintf(&b, "Conflicts\t: %s\n", r.Conflicts.pretty())
+	}
+	if r.Hotspot != nil {
+		fmt.Fprintf(&b, "Hotspot\t\t: %s\n", r.Hotspot.pretty())
+	}
+	if r.Cancel != nil {
+		fmt.Fprintf(&b, "Cancellation\t: %s\n", r.Cancel.pretty())
+	}
+	if hm := heatmap(r.seconds(), 6, o.heatmapCols()); len(hm) > 0 {
+		fmt.Fprintf(&b, "Heatmap\t\t: %s\n", hm[0])
+		for _, line := range hm[1:] {
+			fmt.Fprintf(&b, "\t\t  %s\n", line)
+		}
+	}
+	if o.CDF {
+		if plot := cdfPlot(r.CDF, 8, o.heatmapCols(), o.cdfFill()); len(plot) > 0 {
+			fmt.Fprintf(&b, "CDF\t\t\t: %s\n", plot[0])
+			for _, line := range plot[1:] {
+				fmt.Fprintf(&b, "\t\t  %s\n", line)
+			}
+		}
+	}
+	if p := r.Prepare; p != nil {
+		fmt.Fprintf(&b, "Prepare\t\t: %s\n", p.pretty())
+	}
+	if f := r.FirstRow; f != nil {
+		fmt.Fprintf(&b, "First row\t: %s\n", f.pretty())
+	}
+	if r.PeakHeap > 0 {
+		fmt.Fprintf(&b, "Peak heap\t: +%s over the phase start\n", fBytes(r.PeakHeap))
+	}
+	if d := r.Deadlock; d != nil {
+		fmt.Fprintf(&b, "Deadlocks\t: %s\n", d.pretty())
+	}
+	if r.IO != nil {
+		fmt.Fprintf(&b, "Disk I/O\t: %s\n", r.IO.pretty())
+	}
+
 	return b.String()
 }
 
@@ -120,7 +966,7 @@ func (r Result) JSON() string {
 	return string(j)
 }
 
-func sparkline(samples []time.Duration, bins int) (time.Duration, time.Duration, string) {
+func sparkline(samples []time.Duration, bins int, chars []rune) (time.Duration, time.Duration, string) {
 	if len(samples) == 0 || bins <= 0 {
 		return 0, 0, ""
 	}
@@ -137,7 +983,7 @@ func sparkline(samples []time.Duration, bins int) (time.Duration, time.Duration,
 	lo := quantileDur(s, 0.01)
 	hi := quantileDur(s, 0.99)
 	if hi <= lo {
-		return s[0], s[len(s)-1], strings.Repeat("█", bins)
+		return s[0], s[len(s)-1], strings.Repeat(string(chars[len(chars)-1]), bins)
 	}
 
 	lf := func(d time.Duration) float64 { return math.Log(float64(d)) }
@@ -172,7 +1018,6 @@ func sparkline(samples []time.Duration, bins int) (time.Duration, time.Duration,
 	if maxCnt == 0 {
 		return s[0], s[len(s)-1], ""
 	}
-	chars := []rune("▁▂▃▄▅▆▇█")
 	var sb strings.Builder
 	for _, c := range counts {
 		level := int(math.Round((float64(c) / float64(maxCnt)) * float64(len(chars)-1)))
@@ -226,3 +1071,104 @@ func fDur(d time.Duration) string {
 	}
 	return fmt.Sprintf("%.2fs", float64(d)/float64(time.Second))
 }
+
+// heatmap renders latency (rows, log scale, slowest on top) over time
+// (columns, one per second, merged to at most maxCols). Each cell's shade
+// is its share of the column's operations, so a spike shows up as dark
+// cells high in the map even when the phase average hides it.
+func heatmap(secs [][]time.Duration, rows, maxCols int) []string {
+	var all []time.Duration
+	for _, s := range secs {
+		all = append(all, s...)
+	}
+	if len(secs) < 2 || len(all) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(all)
+	slices.Sort(sorted)
+	lo, hi := max(quantileDur(sorted, 0.001), 1), max(quantileDur(sorted, 0.999), 1)
+	if hi <= lo {
+		return nil
+	}
+	lmin, lmax := math.Log(float64(lo)), math.Log(float64(hi))
+	row := func(d time.Duration) int {
+		x := (math.Log(float64(max(d, 1))) - lmin) / (lmax - lmin)
+		return min(max(int(x*float64(rows)), 0), rows-1)
+	}
+
+	per := (len(secs) + maxCols - 1) / maxCols // seconds per column
+	cols := (len(secs) + per - 1) / per
+	counts := make([][]int, rows)
+	for i := range counts {
+		counts[i] = make([]int, cols)
+	}
+	totals := make([]int, cols)
+	for sec, s := range secs {
+		for _, d := range s {
+			counts[row(d)][sec/per]++
+			totals[sec/per]++
+		}
+	}
+
+	const shades = " .:-=+*#%@"
+	out := make([]string, 0, rows+1)
+	for i := rows - 1; i >= 0; i-- {
+		upper := time.Duration(math.Exp(lmin + (lmax-lmin)*float64(i+1)/float64(rows)))
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%9s |", fDur(upper))
+		for c, n := range counts[i] {
+			level := 0
+			if n > 0 {
+				level = max(1, int(math.Round(float64(n)/float64(totals[c])*float64(len(shades)-1))))
+			}
+			sb.WriteByte(shades[level])
+		}
+		sb.WriteByte('|')
+		out = append(out, sb.String())
+	}
+	out = append(out, fmt.Sprintf("%9s  %ds/col, %d cols", "", per, cols))
+	return out
+}
+
+// cdfPlot draws a latency CDF as the area under it: one row per 1/rows of
+// the operations, one column per step of a log latency axis, filled where
+// at least that share of operations is as fast as the column's latency.
+// A bimodal distribution shows as a step.
+func cdfPlot(cdf []time.Duration, rows, cols int, fill string) []string {
+	if len(cdf) == 0 {
+		return nil
+	}
+	lo, hi := max(cdf[0], 1), max(cdf[len(cdf)-1], 1)
+	if hi <= lo {
+		return nil
+	}
+	lmin, lmax := math.Log(float64(lo)), math.Log(float64(hi))
+	share := make([]float64, cols) // of operations at most as slow as the column
+	for c := range share {
+		x := hi // exactly, as exp(log(hi)) may fall short of it
+		if c < cols-1 {
+			x = time.Duration(math.Exp(lmin + (lmax-lmin)*float64(c+1)/float64(cols)))
+		}
+		n, _ := slices.BinarySearch(cdf, x+1)
+		share[c] = float64(n) / float64(len(cdf))
+	}
+	out := make([]string, 0, rows+1)
+	for i := rows - 1; i >= 0; i-- {
+		level := float64(i+1) / float64(rows)
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%4.0f%% |", level*100)
+		for _, s := range share {
+			if s >= level-1e-9 {
+				sb.WriteString(fill)
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('|')
+		out = append(out, sb.String())
+	}
+	left, right := fDur(lo), fDur(hi)
+	pad := max(1, cols-utf8.RuneCountInString(left)-utf8.RuneCountInString(right))
+	out = append(out, fmt.Sprintf("%5s  %s%s%s (log)", "", left, strings.Repeat(" ", pad), right))
+	return out
+}
@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// insertReturningWorkload is the insert workload with INSERT ... RETURNING:
+// every row is a query whose returned key is scanned, so the difference to
+// insert is the cost of the extra round trip of the generated values.
+func insertReturningWorkload(dollar bool, batch int, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?) RETURNING k`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("insert-returning", ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				ops := res.txOps(worker)
+
+				res.insertTxs(ctx, db, q, batch, insertBatch{
+					row: func(_ *sql.Tx, stmt *sql.Stmt, _ int) bool {
+						k, err := gen.next()
+						if err != nil {
+							res.addErrorCnt(err)
+							return false
+						}
+						start := res.clock.Now()
+						var got string
+						octx, done := res.op(ctx)
+						if err := done(stmt.QueryRowContext(octx, k, pl.pick(rnd)).Scan(&got)); err != nil {
+							res.addErrorCnt(err)
+							return interrupts(err)
+						}
+						ops.add(res.clock.Since(start))
+						return false
+					},
+					ended: ops.end,
+				})
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
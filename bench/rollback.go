@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rollbackWorkload runs insert transactions of batch rows and rolls back
+// rollbackPct percent of them after their writes instead of committing.
+// An op is a whole transaction, its end included, reported combined and
+// as rollback/committed and rollback/rolled-back: the difference between
+// the two is what discarding the work costs, cheap for copy-on-write
+// engines and a replay of the undo log for others.
+func rollbackWorkload(dollar bool, batch, rollbackPct int, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("rollback", ph)
+		committed, rolledBack := res.split("committed"), res.split("rolled-back")
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+
+				for {
+					if res.stopped() {
+						return
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					// like insert, the transaction is ended by us, not by the deadline
+					tx, err := db.BeginTx(context.WithoutCancel(ctx), nil)
+					if err != nil {
+						release()
+						res.addErrorCnt(err)
+						continue
+					}
+					octx, done := res.op(ctx)
+					for range batch {
+						var k string
+						if k, err = gen.next(); err != nil {
+							break
+						}
+						if _, err = tx.ExecContext(octx, q, k, pl.pick(rnd)); err != nil {
+							break
+						}
+					}
+					if err = done(err); err != nil {
+						_ = tx.Rollback()
+						release()
+						res.addErrorCnt(err)
+						continue
+					}
+					undo := rnd.Intn(100) < rollbackPct
+					if undo {
+						err = tx.Rollback()
+						if err != nil {
+							res.addErrorCnt(err)
+						}
+					} else {
+						err = res.commit(tx)
+					}
+					release()
+					if err != nil {
+						continue
+					}
+					d := res.clock.Since(start)
+					res.addLatency(worker, d)
+					if undo {
+						rolledBack.addLatency(worker, d)
+					} else {
+						committed.addLatency(worker, d)
+						res.addInserted(int64(batch))
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
@@ -3,9 +3,12 @@ package bench
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"runtime"
 	"time"
 
+	"github.com/chaisql/chai"
 	embed "github.com/gosuda/chaisql-benchmark/sql"
 	"github.com/rs/zerolog/log"
 )
@@ -13,78 +16,568 @@ import (
 type Config struct {
 	Engine      string
 	DSN         string
+	ReadDSN     string // replica serving the read-only phases (see readOnlyPhase); empty = DSN
 	Concurrency int
+	Conn        ConnOptions // TLS and credentials of the pgx, mariadb and tidb engines
 	Warmup      time.Duration
 	Duration    time.Duration
 	TxBatch     int
+	// DeleteStrategies are run as one delete phase each, in order (see
+	// deleteStrategies); empty runs key.
+	DeleteStrategies []string
+	// ConstraintVariants are the constraints experiment's phases after
+	// constraints-plain (see ConstraintVariants).
+	ConstraintVariants []string
+	// Trim is the start of every measured phase left out of the trimmed
+	// percentiles (Result.Trimmed); 0 = none.
+	Trim time.Duration
+	// DropFailedTx leaves the statements of an insert transaction that
+	// failed to commit out of Ops.
+	DropFailedTx bool
+	// OpTimeout bounds each operation of a phase; operations that outlive
+	// it count as timeouts rather than hanging their worker. 0 = none.
+	OpTimeout  time.Duration
+	StallAlarm StallAlarm // flags sustained throughput collapses within a phase
+	// PrePhaseSQL runs before the measurement of every phase, after its
+	// warmup (e.g. ANALYZE, PRAGMA optimize, prewarm SELECTs), so tuned
+	// and untuned runs can be compared explicitly. database/sql engines
+	// only.
+	PrePhaseSQL []string
+	PprofDir    string // per-phase CPU/heap profiles; empty disables
+	GOMAXPROCS  int    // for the run only; 0 keeps the runtime default
+	CPUSet      []int  // pin workers to these CPUs (Linux only)
+	ErrorBudget ErrorBudget
+	AbortSuite  bool     // stop the whole suite when a phase blows its error budget
+	HealthCheck bool     // verify the database after every phase (see HealthCheck)
+	Workloads   []string // phases to run, in order; empty runs DefaultWorkloads
+	// BusyTimeouts are the sqlite busy_timeout values the contention
+	// workload is repeated with.
+	BusyTimeouts []time.Duration
+	TpcbScale    int // pgbench scale factor for the tpcb workload
+	Rows         int // size of generated tables (typed)
+	SortLimit    int // N of the sort workload's ORDER BY ... LIMIT N
+	InKeys       int // keys per query of the select-in workload
+	// StreamRows bounds the stream workload's result set (0 = all of kv);
+	// StreamPause is its consumer's pause every streamPauseEvery rows.
+	StreamRows   int
+	StreamPause  time.Duration
+	MixedReadPct int    // share of reads in the mixed workload, in percent
+	RollbackPct  int    // share of the rollback workload's transactions rolled back, in percent
+	ConflictPct  int    // share of the conflict workload's inserts that reuse a key, in percent
+	HotPct       int    // share of the hotspot workload's updates on the hot keys, in percent
+	HotKeys      int    // keys in the hotspot workload's hot set
+	StmtShapes   int    // distinct query shapes cycled by the stmtcache workload
+	LongTxMode   string // idle|scan: what the longtx workload's open transaction does
+	WriteLimit   int    // max simultaneous writes across workers; 0 = unlimited
+	// CancelPct is the share of the cancel workload's queries canceled, in
+	// percent, each after a random delay below CancelAfter.
+	CancelPct   int
+	CancelAfter time.Duration
+	// IOThrottle slows down the storage of embedded engines.
+	IOThrottle IOThrottle
+	// FingerprintDisk adds a quick calibration of the data path's disk to
+	// the machine fingerprint (Metadata.Machine).
+	FingerprintDisk bool
+	// CalibrateDisk times raw fsync and O_DIRECT writes in CalibrateDir
+	// (default: the data path's directory) before the suite.
+	CalibrateDisk bool
+	CalibrateDir  string
+	// NoiseFloor runs the noop workload before the suite, and for any
+	// phase with another number of workers, and reports every phase's
+	// latencies less its overhead as well (Result.Net).
+	NoiseFloor bool
+	// GroupCommit, when positive, turns the insert workload into group
+	// commit: one committer flushes every worker's rows once per interval.
+	GroupCommit time.Duration
+	Samples     int    // raw latency samples kept per phase for compare; 0 keeps none
+	EventLog    string // JSONL file lifecycle events are appended to; empty disables
+	// PgxNative runs the core kv workloads on raw pgx connections (batches,
+	// binary protocol) instead of database/sql; reported as pgx-native.
+	PgxNative bool
+	// Driver is the database/sql driver of the generic engine, which runs
+	// the suite on any registered driver; Placeholder is its parameter
+	// style: qmark (?, default) or dollar ($1).
+	Driver      string
+	Placeholder string
+	// Tags label the run (machine, commit, experiment, ...) in the report
+	// metadata; they are not part of the configuration hash.
+	Tags map[string]string `json:"-"`
+	// Clock times the phases; nil is the wall clock.
+	Clock Clock `json:"-"`
+	// Timing picks the clock when Clock is nil: wall (default), mono or
+	// coarse; see timingClock.
+	Timing string
+	// SnapshotIsolation is the isolation level of the snapshot workload's
+	// reader: default|read-committed|repeatable-read|snapshot|serializable.
+	SnapshotIsolation string
+	Dataset           Dataset
+	// Payload selects how written values are generated (fixed|faker);
+	// PayloadCardinality is the number of distinct faker values.
+	Payload            string
+	PayloadCardinality int
+	ValueSize          int // bytes of each fixed value; 0 = the literal as is
+	// KeyGen picks how insert workloads generate keys: randflake (default),
+	// uuidv7, seq or hex; see newKeyGens.
+	KeyGen string
+	// RandflakeSecret is the 16-byte key randflake ids are encrypted with;
+	// empty uses DefaultRandflakeSecret.
+	RandflakeSecret string
+	// MaxRuntime bounds the whole suite, preloads and warmups included;
+	// 0 = unbounded. When it passes, the running phase is cut short and
+	// the report holds the phases completed so far.
+	MaxRuntime time.Duration
+	// MemLimit is the resident memory, in bytes, past which a phase is
+	// aborted and the suite stopped; 0 = no limit.
+	MemLimit int64
+	// MinSamples extends every measured phase past Duration, by at most
+	// MinSamplesMax in all (0 = 5 × Duration), until it has this many
+	// operations, for engines too slow to settle p99 within Duration;
+	// 0 = never.
+	MinSamples    int
+	MinSamplesMax time.Duration
+	// Record logs the operation stream of the kv workloads to this file
+	// (see OpEntry); Replay runs such a log instead of Workloads. Empty
+	// disables either.
+	Record string
+	Replay string
+	// parallel is set by RunEngines when other suites share the process,
+	// whose I/O counters, allocations and memory are then not the
+	// suite's own.
+	parallel bool
+	// PartitionKeys splits the key snapshot between the workers of the
+	// select, range, update, delete and mixed workloads, for contention-free
+	// throughput against the default, fully shared key space.
+	PartitionKeys bool
 }
 
-func runPhase(ctx context.Context, db *sql.DB, conc int, warmup, dur time.Duration, wf WorkloadFunc) Result {
-	if warmup > 0 {
-		_ = wf(ctx, db, conc, warmup)
+// stopGrace is how long Run waits for a step to return once MaxRuntime has
+// passed before giving it up as hung (e.g. stuck in a driver call that
+// ignores its context).
+const stopGrace = 10 * time.Second
+
+// bounded runs fn and waits until it returns or, once ctx is done, at most
+// stopGrace longer. ok is false when fn was given up on; it keeps running,
+// so the caller must not touch anything fn writes.
+func bounded(ctx context.Context, fn func()) (ok bool) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+	}
+	select {
+	case <-done:
+		return true
+	case <-time.After(stopGrace):
+		return false
 	}
-	return wf(ctx, db, conc, dur)
 }
 
-func Run(ctx context.Context, cfg Config) ([]Result, error) {
-	db, err := Open(cfg.Engine, cfg.DSN)
+func runPhase(ctx context.Context, db Executor, cfg Config, ev *eventLog, rec *opRecorder, name string, wf WorkloadFunc) Result {
+	ph := Phase{
+		Concurrency:  cfg.Concurrency,
+		Duration:     cfg.Duration,
+		CPUSet:       cfg.CPUSet,
+		ErrorBudget:  cfg.ErrorBudget,
+		WriteLimit:   cfg.WriteLimit,
+		DropFailedTx: cfg.DropFailedTx,
+		OpTimeout:    cfg.OpTimeout,
+		Trim:         cfg.Trim,
+		MemLimit:     cfg.MemLimit,
+		shared:       cfg.parallel,
+		MinSamples:   cfg.MinSamples,
+		MaxDuration:  cfg.MinSamplesMax,
+		record:       rec,
+		Partitioned:  cfg.PartitionKeys,
+		StallAlarm:   cfg.StallAlarm,
+		Clock:        cfg.Clock,
+	}
+	var warmRows *rowChanges
+	if cfg.Warmup > 0 {
+		warm := ph
+		warm.Duration, warm.Warmup = cfg.Warmup, true
+		ev.emit("warmup_start", name, map[string]any{"duration": cfg.Warmup.String()})
+		warmRows = wf(ctx, db, warm).rows // rows written in the warmup stay in kv
+	}
+	var pre *PrePhase
+	if len(cfg.PrePhaseSQL) > 0 && db != nil {
+		pre = runPrePhase(ctx, db, cfg.PrePhaseSQL)
+		ev.emit("pre_phase", name, map[string]any{"statements": pre.Statements, "took": pre.Took.String(), "error": pre.Error})
+		if pre.Error != "" {
+			log.Error().Str("workload", name).Str("error", pre.Error).Msg("pre-phase SQL failed")
+		}
+	}
+
+	stop, err := startProfiles(cfg.PprofDir, name)
 	if err != nil {
-		return nil, err
+		log.Error().Err(err).Str("workload", name).Msg("failed to start profiling")
+		stop = func() {}
 	}
-	defer db.Close()
+	defer stop()
 
-	if err := initSchema(ctx, db, cfg.Engine); err != nil {
+	// the process's I/O and allocations are the parallel suites' too, so
+	// they are left out rather than misattributed
+	ioDelta := func() *IOStats { return nil }
+	if !cfg.parallel {
+		ioDelta = measureIO(dataPath(cfg.Engine, cfg.DSN))
+	}
+	ev.emit("phase_start", name, map[string]any{"concurrency": ph.Concurrency, "duration": ph.Duration.String()})
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := ph.clock().Now()
+	res := wf(ctx, db, ph)
+	runtime.ReadMemStats(&after)
+	if !cfg.parallel {
+		res.mallocs, res.allocBytes = after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc
+	}
+	res.PrePhase = pre
+	if warmRows != nil {
+		res.rows.merge(warmRows)
+	}
+	res.IO = ioDelta()
+	if ctx.Err() != nil && !res.Aborted && !res.Skipped {
+		// the suite's max runtime, or an interrupt, ended the phase early
+		res.Aborted, res.AbortReason = true, "max runtime exceeded"
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			res.AbortReason = "interrupted"
+		}
+		res.Duration = min(res.Duration, ph.clock().Since(start))
+	}
+	ev.emit("phase_end", name, map[string]any{"ops": res.Ops, "errors": res.Errors, "p99": res.P99.String()})
+	if cfg.Samples > 0 {
+		res.Samples = thin(res.hist.samples, cfg.Samples)
+		for i := range res.byOp {
+			res.byOp[i].Samples = thin(res.byOp[i].hist.samples, cfg.Samples)
+		}
+	}
+	return res
+}
+
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	engine, err := NormalizeEngine(cfg.Engine)
+	if err != nil {
 		return nil, err
 	}
+	cfg.Engine = engine
+	if cfg.PgxNative && engine != "pgx" {
+		return nil, fmt.Errorf("pgx native mode needs the pgx engine, not %s", engine)
+	}
+	if err := setDriver(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Clock == nil {
+		clock, release, err := timingClock(cfg.Timing)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		cfg.Clock = clock
+	}
 
-	results := make([]Result, 0, 5)
+	if cfg.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.MaxRuntime)
+		defer cancel()
+	}
 
-	// insert phase
-	log.Info().Msg("1. insert workload start")
-	insertW := insertWorkload(cfg.Engine, max(1, cfg.TxBatch))
-	results = append(results, runPhase(ctx, db, cfg.Concurrency, cfg.Warmup, cfg.Duration, insertW))
+	if cfg.GOMAXPROCS > 0 && !cfg.parallel { // RunEngines sets it for parallel suites
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(cfg.GOMAXPROCS))
+	}
+	if len(cfg.PrePhaseSQL) > 0 && (cfg.Engine == "chai-native" || isKVEngine(cfg.Engine)) {
+		log.Warn().Str("engine", cfg.Engine).Msg("pre-phase SQL needs a database/sql engine; ignoring")
+	}
+	if cfg.Record != "" && nativePhase(cfg) != nil {
+		log.Warn().Str("engine", nativeEngine(cfg)).Msg("operation recording covers the database/sql kv workloads only; nothing will be recorded")
+	}
+	if len(cfg.CPUSet) > 0 && !pinSupported {
+		log.Warn().Ints("cpuset", cfg.CPUSet).Msg("cpu pinning is not supported on this platform; ignoring")
+	}
 
-	prefetch, err := FetchKeySnapshot(ctx, db, cfg.Engine, 2048)
+	phases, err := planPhases(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := newRunID()
+	ev, err := openEventLog(cfg.EventLog, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer ev.Close()
+	rec, err := openOpRecorder(cfg.Record, cfg)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err := rec.Close(); err != nil {
+			log.Warn().Err(err).Str("path", cfg.Record).Msg("failed to close operation log")
+		}
+	}()
+	ev.emit("run_start", "", map[string]any{"engine": cfg.Engine, "config_hash": configHash(cfg), "phases": len(phases)})
 
-	// select phase
-	selectW := selectWorkload(cfg.Engine, prefetch)
-	log.Info().Msg("2. select workload start")
-	results = append(results, runPhase(ctx, db, cfg.Concurrency, cfg.Warmup, cfg.Duration, selectW))
+	// chai-native works on chai's Go API alone: the database/sql driver
+	// cannot open the same files, so db stays nil and the suite (key
+	// snapshots) and its workloads go through cdb. The key-value baselines
+	// likewise go through kv only.
+	var (
+		db   *sql.DB
+		rdb  *sql.DB // Config.ReadDSN, nil without one
+		cdb  *chai.DB
+		kv   kvStore
+		caps = capabilities{}
+		// stuck is set when a step was given up on after MaxRuntime; its
+		// handle stays open as closing it would wait for the hung call
+		stuck bool
+	)
+	closeUnlessStuck := func(close func() error) {
+		if !stuck {
+			_ = close()
+		}
+	}
+	if (cfg.Engine == "chai-native" || isKVEngine(cfg.Engine)) && cfg.Dataset.Path != "" {
+		return nil, fmt.Errorf("datasets are not supported with %s", cfg.Engine)
+	}
+	if (cfg.Engine == "chai-native" || isKVEngine(cfg.Engine)) && cfg.ReadDSN != "" {
+		return nil, fmt.Errorf("a read DSN is not supported with %s", cfg.Engine)
+	}
+	unthrottle, err := throttleIO(cfg.Engine, cfg.DSN, cfg.IOThrottle)
+	if err != nil {
+		return nil, fmt.Errorf("IO throttle: %w", err)
+	}
+	defer unthrottle()
+	ev.emit("schema_init_start", "", nil)
+	switch {
+	case cfg.Engine == "chai-native":
+		if cdb, err = openChaiNative(cfg.DSN); err != nil {
+			return nil, err
+		}
+		defer closeUnlessStuck(cdb.Close)
+	case isKVEngine(cfg.Engine):
+		if kv, err = openKV(cfg.Engine, cfg.DSN, cfg.IOThrottle.FsyncDelay); err != nil {
+			return nil, err
+		}
+		defer closeUnlessStuck(kv.Close)
+	default:
+		if db, err = openDB(cfg, cfg.DSN); err != nil {
+			return nil, err
+		}
+		defer closeUnlessStuck(db.Close)
+		if err := initSchema(ctx, db, cfg.Engine); err != nil {
+			return nil, err
+		}
+		caps = detectCapabilities(ctx, db, dollarPlaceholders(cfg))
+		if cfg.ReadDSN != "" {
+			// the schema and any dataset reach the replica by
+			// replication, so nothing is created on it
+			if rdb, err = openDB(cfg, cfg.ReadDSN); err != nil {
+				return nil, fmt.Errorf("read DSN: %w", err)
+			}
+			defer closeUnlessStuck(rdb.Close)
+		}
+	}
+	ev.emit("schema_init_end", "", nil)
 
-	// range phase
-	rangeW := rangeWorkload(cfg.Engine, prefetch, 100)
-	log.Info().Msg("3. range workload start")
-	results = append(results, runPhase(ctx, db, cfg.Concurrency, cfg.Warmup, cfg.Duration, rangeW))
+	meta := newMetadata(cfg)
+	meta.EngineVersion = engineVersion(ctx, db, cfg.Engine)
+	meta.Capabilities = caps.list()
+	meta.Machine = newMachine(dataPath(cfg.Engine, cfg.DSN), cfg.FingerprintDisk)
+	if cfg.CalibrateDisk {
+		dir := cfg.CalibrateDir
+		if p := dataPath(cfg.Engine, cfg.DSN); dir == "" && p != "" {
+			dir = calibrationDir(p)
+		}
+		if dir == "" {
+			log.Warn().Str("engine", cfg.Engine).Msg("no data directory to calibrate; set calibrate_dir to the server's")
+		} else {
+			meta.Disk = calibrateDir(dir)
+			log.Info().Str("dir", dir).Msg("disk calibrated")
+		}
+	}
+	log.Info().Str("engine", cfg.Engine).Str("dsn", meta.DSN).Str("version", meta.EngineVersion).Strs("capabilities", meta.Capabilities).Msg("connected")
+	var floors *noiseFloors
+	if cfg.NoiseFloor {
+		floors = &noiseFloors{ctx: ctx, cfg: cfg, floors: map[int]*NoiseFloor{}}
+		if meta.NoiseFloor = floors.at(cfg.Concurrency); meta.NoiseFloor != nil {
+			log.Info().Dur("p50", meta.NoiseFloor.P50).Float64("ops_per_sec", meta.NoiseFloor.OpsPerSec).Msg("noise floor measured")
+		}
+	}
+
+	results := make([]Result, 0, len(phases))
+	// partial ends the suite early with the results collected so far
+	partial := func(reason string) (*Report, error) {
+		log.Warn().Str("reason", reason).Msg("suite stopped early")
+		ev.emit("run_end", "", map[string]any{"completed": false, "reason": reason})
+		return newReport(runID, cfg, meta, results), nil
+	}
+	const overtime = "max runtime exceeded"
 
-	// update phase
-	updateW := updateWorkload(cfg.Engine, prefetch)
-	log.Info().Msg("4. update workload start")
-	results = append(results, runPhase(ctx, db, cfg.Concurrency, cfg.Warmup, cfg.Duration, updateW))
+	if cfg.Dataset.Path != "" {
+		ev.emit("dataset_load_start", "", map[string]any{"path": cfg.Dataset.Path})
+		var (
+			n       int
+			loadErr error
+		)
+		if !bounded(ctx, func() { n, loadErr = loadDataset(ctx, db, dollarPlaceholders(cfg), cfg.Dataset) }) {
+			stuck = true
+			return partial(overtime + " while loading the dataset")
+		}
+		if loadErr != nil {
+			if ctx.Err() != nil {
+				return partial(overtime + " while loading the dataset")
+			}
+			return nil, loadErr
+		}
+		ev.emit("dataset_load_end", "", map[string]any{"rows": n})
+		meta.Dataset, meta.DatasetRows = cfg.Dataset.Path, n
+	}
+
+	s := &suite{ctx: ctx, chai: cdb, kv: kv, cfg: cfg, events: ev, caps: caps}
+	if db != nil { // else s.db stays a nil Executor, not a nil *sql.DB
+		s.db = db
+	}
+	// the rows kv should hold after each phase; runs bypassing database/sql
+	// do not account for their writes
+	var ledger rowLedger
+	tracked := nativePhase(cfg) == nil
+	if cfg.HealthCheck {
+		ledger.start(s.checkHealth())
+	}
+	for i, p := range phases {
+		if ctx.Err() != nil {
+			return partial(overtime)
+		}
+		ev.emit("phase_build", p.name, nil)
+		var (
+			wf  WorkloadFunc
+			err error
+		)
+		if !bounded(ctx, func() { wf, err = p.build(s) }) {
+			stuck = true
+			return partial(overtime + " while preparing " + p.name)
+		}
+		if err != nil && ctx.Err() != nil {
+			return partial(overtime + " while preparing " + p.name)
+		}
+		if res, ok := skippedResult(p.name, err); ok {
+			log.Warn().Str("workload", p.name).Str("reason", res.SkipReason).Msg("workload skipped")
+			ev.emit("skip", p.name, map[string]any{"reason": res.SkipReason})
+			results = append(results, res)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rdb != nil && readOnlyPhase(p.name) {
+			wf = onReadDB(rdb, wf)
+		}
+		log.Info().Msgf("%d. %s workload start", i+1, p.name)
+		var got Result
+		if !bounded(ctx, func() { got = runPhase(ctx, s.db, cfg, ev, rec, p.name, wf) }) {
+			stuck = true
+			results = append(results, Result{
+				Workload:    p.name,
+				Concurrency: cfg.Concurrency,
+				Aborted:     true,
+				AbortReason: fmt.Sprintf("%s; phase did not stop within %s", overtime, stopGrace),
+			})
+			return partial(overtime + " while running " + p.name)
+		}
+		res := got
+		if floors != nil && res.Ops > 0 && !res.Skipped {
+			// phases such as replay run their own number of workers
+			n := floors.at(res.Concurrency)
+			res.subtractNoise(n)
+			for i := range res.byOp {
+				res.byOp[i].subtractNoise(n)
+			}
+		}
+		if cfg.HealthCheck && ctx.Err() == nil {
+			res.Health = s.checkHealth()
+			ledger.settle(res.rows, res.Health, tracked)
+			if res.Health != nil {
+				ev.emit("health_check", p.name, map[string]any{"ok": res.Health.OK(), "rows": res.Health.Rows, "drift": res.Health.Drift, "error": res.Health.Error})
+				if !res.Health.OK() {
+					log.Error().Str("workload", p.name).Str("health", res.Health.String()).Msg("health check failed")
+				}
+			}
+		}
+		results = append(results, res)
+		results = append(results, res.byOp...)
 
-	// delete phase
-	deleteW := deleteWorkload(cfg.Engine, prefetch)
-	log.Info().Msg("5. delete workload start")
-	results = append(results, runPhase(ctx, db, cfg.Concurrency, cfg.Warmup, cfg.Duration, deleteW))
+		if res.Aborted {
+			log.Warn().Str("workload", p.name).Str("reason", res.AbortReason).Msg("workload aborted")
+			ev.emit("abort", p.name, map[string]any{"reason": res.AbortReason, "suite": cfg.AbortSuite})
+			if ctx.Err() != nil {
+				return partial(overtime)
+			}
+			if res.memReason != "" { // the next phase would only add to it
+				return partial(res.AbortReason)
+			}
+			if cfg.AbortSuite {
+				log.Warn().Msg("suite stopped after aborted workload")
+				ev.emit("run_end", "", map[string]any{"completed": false})
+				return newReport(runID, cfg, meta, results), nil
+			}
+		}
+	}
 
 	log.Info().Msg("all workloads completed")
-	return results, nil
+	ev.emit("run_end", "", map[string]any{"completed": true})
+	return newReport(runID, cfg, meta, results), nil
+}
+
+// setDriver checks cfg's driver choice and the generic engine's
+// placeholder style.
+func setDriver(cfg Config) error {
+	if cfg.Engine != "generic" {
+		if cfg.Driver != "" {
+			return fmt.Errorf("a driver can only be chosen for the generic engine, not %s", cfg.Engine)
+		}
+		return nil
+	}
+	switch cfg.Placeholder {
+	case "", "qmark", "dollar":
+	default:
+		return fmt.Errorf("unknown placeholder style %q (qmark|dollar)", cfg.Placeholder)
+	}
+	return nil
 }
 
-func initSchema(ctx context.Context, db *sql.DB, engine string) error {
-	var schema string
+func initSchema(ctx context.Context, db Executor, engine string) error {
+	if engine == "generic" {
+		return nil // unknown dialect: the kv table must already exist
+	}
+	schema, err := schemaFor(engine, embed.PgSchema, embed.SqliteSchema, embed.ChaiSchema, embed.MysqlSchema, embed.ClickhouseSchema)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, schema)
+	return err
+}
+
+// schemaFor picks the dialect variant of a schema for engine. ClickHouse
+// only has variants for the tables its workloads use; clickhouse is ""
+// otherwise.
+func schemaFor(engine, pg, sqlite, chai, mysql, clickhouse string) (string, error) {
 	switch engine {
+	case "mariadb", "tidb":
+		return mysql, nil
+	case "clickhouse":
+		if clickhouse != "" {
+			return clickhouse, nil
+		}
+	case "generic":
+		return "", &errSkip{"the generic engine has no schemas beyond the existing kv table"}
 	case "pgx":
-		schema = embed.PgSchema
+		return pg, nil
 	case "sqlite":
-		schema = embed.SqliteSchema
-	case "chai":
-		schema = embed.ChaiSchema
-	default:
-		return fmt.Errorf("unsupported engine: %s", engine)
+		return sqlite, nil
+	case "chai", "chai-native":
+		return chai, nil
 	}
-	_, err := db.ExecContext(ctx, schema)
-	return err
+	return "", fmt.Errorf("unsupported engine: %s", engine)
 }
@@ -0,0 +1,157 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestErrorAccounting fails every 5th insert and every 7th commit of the
+// insert workload and checks each lands where it belongs.
+func TestErrorAccounting(t *testing.T) {
+	var inserts, commits, insertFails, commitFails atomic.Int64
+	f := &fakeExecutor{Fail: func(q string) error {
+		if q == "COMMIT" {
+			if commits.Add(1)%7 == 0 {
+				commitFails.Add(1)
+				return errors.New("commit failed")
+			}
+			return nil
+		}
+		if inserts.Add(1)%5 == 0 {
+			insertFails.Add(1)
+			return errors.New("insert failed")
+		}
+		return nil
+	}}
+	kg, err := newKeyGens("seq", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl, err := newPayloads("fixed", 0, 0, "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{Concurrency: 2, Duration: 100 * time.Millisecond}
+
+	res := runPhase(context.Background(), newFakeExecutor(f), cfg, nil, nil, "insert", insertWorkload(false, 3, kg, pl))
+
+	if commitFails.Load() == 0 || insertFails.Load() == 0 {
+		t.Fatalf("the phase was too short to fail: %d inserts, %d commits", inserts.Load(), commits.Load())
+	}
+	if want := insertFails.Load() + commitFails.Load(); res.Errors != want {
+		t.Errorf("Errors = %d, want %d", res.Errors, want)
+	}
+	if res.CommitErrors != commitFails.Load() {
+		t.Errorf("CommitErrors = %d, want %d", res.CommitErrors, commitFails.Load())
+	}
+	if want := inserts.Load() - insertFails.Load(); res.Ops != want {
+		t.Errorf("Ops = %d, want %d", res.Ops, want)
+	}
+	if got := res.ErrorKinds; len(got) != 0 {
+		t.Errorf("ErrorKinds = %v, want none (the failures are unclassified)", got)
+	}
+}
+
+// TestCanceledNotErrors checks that the statements the phase end cuts
+// short count as Canceled rather than Errors.
+func TestCanceledNotErrors(t *testing.T) {
+	f := &fakeExecutor{Latency: 30 * time.Millisecond, Rows: 3}
+	keys := []string{"k00000001", "k00000002"}
+	cfg := Config{Concurrency: 4, Duration: 100 * time.Millisecond}
+
+	res := runPhase(context.Background(), newFakeExecutor(f), cfg, nil, nil, "range", rangeWorkload(false, keys, 10))
+
+	if res.Errors != 0 {
+		t.Errorf("Errors = %d, want 0", res.Errors)
+	}
+	if res.Canceled == 0 || res.Canceled > int64(cfg.Concurrency) {
+		t.Errorf("Canceled = %d, want 1 to %d (one per worker at most)", res.Canceled, cfg.Concurrency)
+	}
+	if res.Ops == 0 {
+		t.Error("no range query completed")
+	}
+}
+
+// TestRunPhaseWarmupAndPrePhase runs a warmup and the pre-phase SQL before
+// the measurement, all on the fake.
+func TestRunPhaseWarmupAndPrePhase(t *testing.T) {
+	var analyzed atomic.Bool
+	var measured atomic.Int64
+	f := &fakeExecutor{Latency: time.Millisecond, Rows: 3, Fail: func(q string) error {
+		if strings.HasPrefix(q, "ANALYZE") {
+			analyzed.Store(true)
+		} else if analyzed.Load() {
+			measured.Add(1)
+		}
+		return nil
+	}}
+	cfg := Config{
+		Concurrency: 2,
+		Warmup:      50 * time.Millisecond,
+		Duration:    100 * time.Millisecond,
+		PrePhaseSQL: []string{"ANALYZE"},
+	}
+	keys := []string{"k00000001", "k00000002"}
+
+	res := runPhase(context.Background(), newFakeExecutor(f), cfg, nil, nil, "range", rangeWorkload(false, keys, 10))
+
+	if res.PrePhase == nil || res.PrePhase.Error != "" || res.PrePhase.Statements != 1 {
+		t.Fatalf("PrePhase = %v, want 1 statement", res.PrePhase)
+	}
+	if res.Duration != cfg.Duration {
+		t.Errorf("Duration = %s, want %s", res.Duration, cfg.Duration)
+	}
+	// the warmup's operations, before ANALYZE, are not reported
+	if res.Ops == 0 || res.Ops > measured.Load() {
+		t.Errorf("Ops = %d, want 1 to %d", res.Ops, measured.Load())
+	}
+}
+
+// TestConfigHashIgnoresOutput checks that where a run writes its output
+// does not change the configuration it is grouped under.
+func TestConfigHashIgnoresOutput(t *testing.T) {
+	base := Config{Engine: "sqlite", Concurrency: 4, Duration: time.Second}
+	out := base
+	out.EventLog, out.PprofDir, out.Record, out.Samples = "out/20250102/events.jsonl", "out/20250102/pprof", "ops.jsonl", 10000
+	if configHash(out) != configHash(base) {
+		t.Error("the output paths change the config hash")
+	}
+	other := base
+	other.Concurrency = 8
+	if configHash(other) == configHash(base) {
+		t.Error("the concurrency does not change the config hash")
+	}
+}
+
+// TestRunPhaseMaxRuntimeFakeClock checks that a phase the suite's max
+// runtime cuts short is timed on the phase clock.
+func TestRunPhaseMaxRuntimeFakeClock(t *testing.T) {
+	clk := newFakeClock(time.Unix(0, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := Config{Concurrency: 1, Duration: time.Second, Clock: clk}
+	wf := func(ctx context.Context, _ Executor, ph Phase) Result {
+		clk.Advance(300 * time.Millisecond)
+		cancel() // the max runtime ends
+		return Result{Workload: "cut", Duration: ph.Duration}
+	}
+
+	res := runPhase(ctx, nil, cfg, nil, nil, "cut", wf)
+
+	if !res.Aborted || res.Duration != 300*time.Millisecond {
+		t.Errorf("Aborted, Duration = %v, %s; want true, 300ms", res.Aborted, res.Duration)
+	}
+}
+
+// TestNoopWorkload checks that the noise floor's loop scans its row
+// without errors.
+func TestNoopWorkload(t *testing.T) {
+	res := noopWorkload()(context.Background(), nil, Phase{Concurrency: 2, Duration: 50 * time.Millisecond})
+	if res.Errors != 0 || res.Ops == 0 {
+		t.Errorf("Errors, Ops = %d, %d; want 0, some (error kinds %v)", res.Errors, res.Ops, res.ErrorKinds)
+	}
+}
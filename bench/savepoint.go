@@ -0,0 +1,143 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SavepointStats is what the savepoint workload's checks saw: after
+// ROLLBACK TO, the row inserted since the savepoint must be gone while the
+// earlier rows of the transaction remain, on every engine alike.
+type SavepointStats struct {
+	Checks     int64 `json:"checks"`
+	Violations int64 `json:"violations"`
+	Errors     int64 `json:"errors,omitempty"`
+}
+
+func (s SavepointStats) pretty() string {
+	verdict := "consistent"
+	if s.Violations > 0 {
+		verdict = "INCONSISTENT"
+	}
+	out := fmt.Sprintf("%s: %s/%s checks violated", verdict, commaI(s.Violations), commaI(s.Checks))
+	if s.Errors > 0 {
+		out += fmt.Sprintf(", %s errors", commaI(s.Errors))
+	}
+	return out
+}
+
+// savepointWorkload is the insert workload with every row in a savepoint of
+// its own, rolling back to every other one: a partial rollback of the
+// batch. Ops time SAVEPOINT, INSERT and, for the rolled-back rows, ROLLBACK
+// TO, reported as savepoint/kept and savepoint/rolled-back; against insert
+// that is the savepoint overhead. Before committing, each batch checks
+// that its last rolled-back row is gone and its last kept row is there.
+func savepointWorkload(dollar bool, batch int, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`)
+	countQ := bind(dollar, `SELECT COUNT(*) FROM kv WHERE k = ?`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("savepoint", ph)
+		keptPart, rolledBack := res.split("kept"), res.split("rolled-back")
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		var checks, violations, checkErrs atomic.Int64
+
+		// check counts the rows of k within tx, which must be want; like
+		// the commit, it runs even if the phase just ended
+		check := func(tx *sql.Tx, k string, want int64) {
+			var n int64
+			if err := tx.QueryRowContext(context.WithoutCancel(ctx), countQ, k).Scan(&n); err != nil {
+				checkErrs.Add(1)
+				return
+			}
+			checks.Add(1)
+			if n != want {
+				violations.Add(1)
+				res.log.Warn().Str("key", k).Int64("rows", n).Int64("expected", want).Msg("savepoint rollback not honored")
+			}
+		}
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				ops := res.txOps(worker)
+
+				var kept, dropped string // last key of each kind in the batch
+				res.insertTxs(ctx, db, q, batch, insertBatch{
+					row: func(tx *sql.Tx, stmt *sql.Stmt, i int) bool {
+						k, err := gen.next()
+						if err != nil {
+							res.addErrorCnt(err)
+							return false
+						}
+						undo := i%2 == 1
+						start := res.clock.Now()
+						octx, done := res.op(ctx)
+						err = savepointInsert(octx, tx, stmt, fmt.Sprintf("sp%d", i), k, pl.pick(rnd), undo)
+						if err = done(err); err != nil {
+							res.addErrorCnt(err)
+							// a failed savepoint statement leaves the batch in an unknown state
+							return true
+						}
+						d := res.clock.Since(start)
+						if undo {
+							res.addLatency(worker, d)
+							rolledBack.addLatency(worker, d)
+							dropped = k
+						} else {
+							ops.add(d)
+							keptPart.addLatency(worker, d)
+							kept = k
+						}
+						return false
+					},
+					ending: func(tx *sql.Tx, commit bool) {
+						if commit && dropped != "" {
+							check(tx, dropped, 0)
+						}
+						if commit && kept != "" {
+							check(tx, kept, 1)
+						}
+						kept, dropped = "", ""
+					},
+					ended: ops.end,
+				})
+			}(w)
+		}
+		wg.Wait()
+		res.Savepoints = &SavepointStats{Checks: checks.Load(), Violations: violations.Load(), Errors: checkErrs.Load()}
+		return res.finalize()
+	}
+}
+
+// savepointInsert inserts (k, v) after a new savepoint sp, rolling back to
+// it again if undo.
+func savepointInsert(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, sp, k string, v []byte, undo bool) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+sp); err != nil {
+		return err
+	}
+	if _, err := stmt.ExecContext(ctx, k, v); err != nil {
+		return err
+	}
+	if undo {
+		_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+sp)
+		return err
+	}
+	return nil
+}
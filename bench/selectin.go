@@ -0,0 +1,20 @@
+package bench
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// selectInQuery fetches n random snapshot keys per query with
+// WHERE k IN (...), the batched point lookup ORMs issue for eager loading.
+// Rows read per op shows how many of the keys still existed.
+func selectInQuery(keys []string, n int) readQuery {
+	q := `SELECT k, v FROM kv WHERE k IN (?` + strings.Repeat(`, ?`, n-1) + `)`
+	return readQuery{q, func(rnd *rand.Rand) []any {
+		args := make([]any, n)
+		for i := range args {
+			args[i] = keys[rnd.Intn(len(keys))]
+		}
+		return args
+	}}
+}
@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SnapshotStats is what the snapshot reader saw: a transaction drifted
+// when its repeated COUNT(*) did not return the same number every time,
+// i.e. the engine did not give it a stable snapshot.
+type SnapshotStats struct {
+	Isolation string `json:"isolation"`
+	Txns      int64  `json:"txns"`
+	Drifted   int64  `json:"drifted"`
+	MaxDrift  int64  `json:"max_drift"` // largest count difference within one transaction, in rows
+	Errors    int64  `json:"errors,omitempty"`
+}
+
+func (s SnapshotStats) pretty() string {
+	verdict := "stable"
+	if s.Drifted > 0 {
+		verdict = "drifting"
+	}
+	out := fmt.Sprintf("%s at %s isolation: %s/%s txns drifted (max %s rows)",
+		verdict, s.Isolation, commaI(s.Drifted), commaI(s.Txns), commaI(s.MaxDrift))
+	if s.Errors > 0 {
+		out += fmt.Sprintf(", %s errors", commaI(s.Errors))
+	}
+	return out
+}
+
+const (
+	snapshotCounts = 8                     // COUNT(*) per reader transaction
+	snapshotPause  = 10 * time.Millisecond // between counts, so writers commit in between
+)
+
+// isolationLevels maps the snapshot_isolation setting to database/sql
+// levels; "default" leaves the choice to the engine.
+var isolationLevels = map[string]sql.IsolationLevel{
+	"default":         sql.LevelDefault,
+	"read-committed":  sql.LevelReadCommitted,
+	"repeatable-read": sql.LevelRepeatableRead,
+	"snapshot":        sql.LevelSnapshot,
+	"serializable":    sql.LevelSerializable,
+}
+
+// withSnapshotReader runs wf (the writers) next to one reader that keeps
+// opening transactions and counting kv several times in each, and attaches
+// what it saw to the result.
+func withSnapshotReader(name, isolation string, wf WorkloadFunc) WorkloadFunc {
+	level := isolationLevels[isolation]
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		stats := SnapshotStats{Isolation: isolation}
+		reading, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for reading.Err() == nil {
+				drift, err := snapshotTx(reading, db, level)
+				if err != nil {
+					if reading.Err() == nil {
+						stats.Errors++
+					}
+					// back off instead of spinning on e.g. an isolation
+					// level the engine rejects
+					select {
+					case <-reading.Done():
+					case <-time.After(snapshotPause):
+					}
+					continue
+				}
+				stats.Txns++
+				if drift > 0 {
+					stats.Drifted++
+					stats.MaxDrift = max(stats.MaxDrift, drift)
+				}
+			}
+		}()
+
+		res := wf(ctx, db, ph)
+		stop()
+		<-done
+		res.Workload = name
+		res.Snapshot = &stats
+		return res
+	}
+}
+
+// snapshotTx counts kv snapshotCounts times in one transaction and returns
+// the spread between the smallest and largest count.
+func snapshotTx(ctx context.Context, db Executor, level sql.IsolationLevel) (int64, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: level})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var lo, hi int64
+	for i := range snapshotCounts {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(snapshotPause):
+			}
+		}
+		var n int64
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM kv`).Scan(&n); err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			lo, hi = n, n
+		}
+		lo, hi = min(lo, n), max(hi, n)
+	}
+	return hi - lo, nil
+}
@@ -0,0 +1,21 @@
+package bench
+
+import "math/rand"
+
+// sortQueries fetch the top n rows of typed ordered by columns without an
+// index, so every engine has to sort (or heap-select) the candidate rows
+// itself. The filtered variant bounds the input by the indexed i column.
+func sortQueries(n int) []readQuery {
+	return []readQuery{
+		{`SELECT id, f FROM typed ORDER BY f DESC LIMIT ?`, func(*rand.Rand) []any {
+			return []any{n}
+		}},
+		{`SELECT id, t, ts FROM typed WHERE b = ? ORDER BY t, ts LIMIT ?`, func(rnd *rand.Rand) []any {
+			return []any{rnd.Intn(2) == 1, n}
+		}},
+		{`SELECT id, f FROM typed WHERE i >= ? AND i < ? ORDER BY f LIMIT ?`, func(rnd *rand.Rand) []any {
+			lo := rnd.Int63n(900_000)
+			return []any{lo, lo + 100_000, n}
+		}},
+	}
+}
@@ -0,0 +1,111 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// StallAlarm flags a sustained throughput collapse while a phase runs:
+// ops/s below Ratio of the rolling average for longer than Hold. Such
+// stalls (e.g. chai blocking on a checkpoint) otherwise only dilute the
+// phase's average.
+type StallAlarm struct {
+	Ratio float64       // share of the rolling average ops/s; 0 disables
+	Hold  time.Duration // how long throughput must stay below it
+}
+
+func (a StallAlarm) enabled() bool { return a.Ratio > 0 && a.Hold > 0 }
+
+const (
+	// stallAverage is how many healthy seconds the rolling average spans
+	stallAverage = 30
+	// stallWarmup is how many seconds the average needs before it is
+	// trusted; the first seconds of a phase are often uneven
+	stallWarmup = 3
+)
+
+// Stall is a period of collapsed throughput within a phase.
+type Stall struct {
+	Start    time.Time     `json:"start"`
+	Offset   time.Duration `json:"offset"` // since the phase start
+	Duration time.Duration `json:"duration"`
+	OpsPerS  float64       `json:"ops_per_sec"`      // during the stall
+	Baseline float64       `json:"baseline_ops_sec"` // rolling average before it
+}
+
+// watchStalls counts the phase's ops every second until ctx ends and
+// records each stretch below the alarm's share of the rolling average that
+// lasts longer than its hold, logging a warning as it crosses the hold.
+func (r *Result) watchStalls(ctx context.Context, a StallAlarm) {
+	t := r.clock.NewTicker(time.Second)
+	defer t.Stop()
+
+	var (
+		healthy []int64 // ops per second, the last stallAverage healthy ones
+		prev    int64
+		low     []int64 // ops per second of the current stretch below the threshold
+		since   time.Time
+		base    float64
+		alarmed bool
+	)
+	// end closes the current stall; it lasts to the phase end unless
+	// recovered
+	end := func(recovered bool) {
+		if alarmed {
+			s := &r.Stalls[len(r.Stalls)-1]
+			s.Duration = r.clock.Since(since)
+			s.OpsPerS = mean(low)
+			if recovered {
+				r.log.Info().Dur("duration", s.Duration).Msg("throughput recovered")
+			}
+		}
+		low, alarmed = nil, false
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			end(false)
+			return
+		case <-t.C():
+		}
+		cur := atomic.LoadInt64(&r.Ops)
+		n := cur - prev
+		prev = cur
+
+		avg := mean(healthy)
+		if len(healthy) >= stallWarmup && float64(n) < a.Ratio*avg {
+			if low == nil {
+				since, base = r.clock.Now().Add(-time.Second), avg
+			}
+			low = append(low, n)
+			if !alarmed && r.clock.Since(since) >= a.Hold {
+				alarmed = true
+				r.Stalls = append(r.Stalls, Stall{Start: since, Offset: since.Sub(r.startedAt), Baseline: base})
+				r.log.Warn().Time("since", since).Float64("ops_per_sec", mean(low)).Float64("baseline_ops_per_sec", base).
+					Msg("throughput collapsed")
+			}
+			continue
+		}
+		end(true)
+		if healthy = append(healthy, n); len(healthy) > stallAverage {
+			healthy = healthy[1:]
+		}
+	}
+}
+
+func mean(v []int64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	var sum int64
+	for _, x := range v {
+		sum += x
+	}
+	return float64(sum) / float64(len(v))
+}
+
+func (s Stall) pretty() string {
+	return fmt.Sprintf("%s at +%s (%.1f ops/s vs %.1f before)", s.Duration.Round(time.Second), s.Offset.Round(time.Second), s.OpsPerS, s.Baseline)
+}
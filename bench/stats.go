@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// z95 is the two-sided 95% normal quantile.
+const z95 = 1.959964
+
+// mannWhitneyU tests whether samples a and b come from the same
+// distribution. It returns U for a and the two-sided p-value from the
+// normal approximation with tie and continuity correction, which is
+// accurate for the sample sizes a benchmark phase produces.
+func mannWhitneyU(a, b []time.Duration) (u, p float64) {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	type obs struct {
+		d     time.Duration
+		fromA bool
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, d := range a {
+		all = append(all, obs{d, true})
+	}
+	for _, d := range b {
+		all = append(all, obs{d, false})
+	}
+	slices.SortFunc(all, func(x, y obs) int { return cmp.Compare(x.d, y.d) })
+
+	// average ranks over ties; ties also shrink the variance
+	var rankA, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].d == all[i].d {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // ranks are 1-based
+		for _, o := range all[i:j] {
+			if o.fromA {
+				rankA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties += t*t*t - t
+		}
+		i = j
+	}
+
+	f1, f2, n := float64(n1), float64(n2), float64(n1+n2)
+	u = rankA - f1*(f1+1)/2
+	mu := f1 * f2 / 2
+	sigma := math.Sqrt(f1 * f2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	return u, math.Erfc(max(z, 0) / math.Sqrt2)
+}
+
+// medianCI returns the median of samples and its 95% confidence interval
+// from the order statistics around it (no distribution assumed).
+func medianCI(samples []time.Duration) (med, lo, hi time.Duration) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	s := slices.Clone(samples)
+	slices.Sort(s)
+	n := float64(len(s))
+	k := z95 * math.Sqrt(n) / 2
+	at := func(x float64) time.Duration {
+		return s[min(max(int(x), 0), len(s)-1)]
+	}
+	// the bounds' 1-based ranks are ⌊n/2 − k⌋ and ⌈1 + n/2 + k⌉
+	return at((n - 1) / 2), at(math.Floor(n/2-k) - 1), at(math.Ceil(n/2 + k))
+}
+
+// thin returns at most n samples, evenly strided over arrival order so the
+// subset still covers the whole phase.
+func thin(samples []time.Duration, n int) []time.Duration {
+	if n <= 0 || len(samples) <= n {
+		return slices.Clone(samples)
+	}
+	out := make([]time.Duration, n)
+	step := float64(len(samples)) / float64(n)
+	for i := range out {
+		out[i] = samples[int(float64(i)*step)]
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// millis is 1ms × each of xs.
+func millis(xs ...int) []time.Duration {
+	out := make([]time.Duration, len(xs))
+	for i, x := range xs {
+		out[i] = time.Duration(x) * time.Millisecond
+	}
+	return out
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b []time.Duration
+		u, p float64
+	}{
+		// U = 0, z = 12 / √(25/12 × 11)
+		{"separated", millis(1, 2, 3, 4, 5), millis(6, 7, 8, 9, 10), 0, 0.012186},
+		{"separated, swapped", millis(6, 7, 8, 9, 10), millis(1, 2, 3, 4, 5), 25, 0.012186},
+		{"interleaved", millis(1, 3, 5, 7, 9), millis(2, 4, 6, 8, 10), 10, 0.6761},
+		{"identical", millis(4, 4, 4), millis(4, 4, 4), 4.5, 1},
+		// two ties of three: σ² = 9/12 × (7 − 48/30) = 4.05
+		{"ties", millis(1, 1, 2), millis(1, 2, 2), 3, 0.6193},
+		{"empty", nil, millis(1, 2), 0, 1},
+	} {
+		u, p := mannWhitneyU(tc.a, tc.b)
+		if u != tc.u || math.Abs(p-tc.p) > 5e-4 {
+			t.Errorf("%s: U, p = %g, %.4f; want %g, %.4f", tc.name, u, p, tc.u, tc.p)
+		}
+	}
+}
+
+func TestMedianCI(t *testing.T) {
+	hundred := make([]int, 100)
+	for i := range hundred {
+		hundred[i] = 100 - i // unsorted
+	}
+	for _, tc := range []struct {
+		name        string
+		samples     []time.Duration
+		med, lo, hi int
+	}{
+		// ranks ⌊50 − 9.8⌋ = 40 and ⌈51 + 9.8⌉ = 61
+		{"100", millis(hundred...), 50, 40, 61},
+		{"odd", millis(5, 1, 4, 2, 3), 3, 1, 5},
+		{"one", millis(7), 7, 7, 7},
+	} {
+		med, lo, hi := medianCI(tc.samples)
+		if want := millis(tc.med, tc.lo, tc.hi); med != want[0] || lo != want[1] || hi != want[2] {
+			t.Errorf("%s: median, lo, hi = %s, %s, %s; want %s, %s, %s", tc.name, med, lo, hi, want[0], want[1], want[2])
+		}
+	}
+	if med, lo, hi := medianCI(nil); med != 0 || lo != 0 || hi != 0 {
+		t.Errorf("no samples: %s, %s, %s; want zeros", med, lo, hi)
+	}
+}
@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stmtShape is one generated variation of a kv lookup: a different IN-list
+// arity and LIMIT literal give each shape its own SQL text, so no two
+// shapes share a cached statement.
+func stmtShape(dollar bool, i int) (q string, arity int) {
+	arity = 1 + i%8
+	cols := "k, v"
+	if i/8%2 == 1 {
+		cols = "v, k"
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", arity), ", ")
+	q = fmt.Sprintf(`SELECT %s FROM kv WHERE k IN (%s) LIMIT %d`, cols, marks, arity+i/16)
+	return bind(dollar, q), arity
+}
+
+// stmtCacheWorkload cycles through shapes distinct queries, preparing each
+// one on the worker's connection before executing it. Prepare time is
+// reported in Result.Prepare; the op latency covers execution only.
+func stmtCacheWorkload(dollar bool, keys []string, shapes int) WorkloadFunc {
+	qs := make([]string, shapes)
+	arities := make([]int, shapes)
+	for i := range qs {
+		qs[i], arities[i] = stmtShape(dollar, i)
+	}
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("stmtcache", ph)
+		if len(keys) == 0 {
+			return res.finalize()
+		}
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				// one connection per worker so a statement is executed
+				// where it was prepared, never re-prepared behind our back
+				conn, err := db.Conn(ctx)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				defer conn.Close()
+
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for i := worker; ; i++ {
+					if res.stopped() {
+						return
+					}
+					shape := i % shapes
+					start := res.clock.Now()
+					stmt, err := conn.PrepareContext(ctx, qs[shape])
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addPrepare(res.clock.Since(start))
+
+					args := make([]any, arities[shape])
+					for j := range args {
+						args[j] = keys[rnd.Intn(len(keys))]
+					}
+					start = res.clock.Now()
+					octx, done := res.op(ctx)
+					rows, err := stmt.QueryContext(octx, args...)
+					if err == nil {
+						_, err = drainRows(rows, nil)
+					}
+					err = done(err)
+					_ = stmt.Close()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
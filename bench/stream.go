@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"context"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// streamPauseEvery is how many rows the stream workload reads between
+// pauses.
+const streamPauseEvery = 1000
+
+// streamWorkload opens a large result set over kv (limit rows; 0 = all)
+// and consumes it row by row, sleeping pause every streamPauseEvery rows
+// like a consumer doing work per row. Ops time the whole stream; FirstRow
+// the wait for its first row; PeakHeap what the driver buffered meanwhile.
+func streamWorkload(dollar bool, limit int, pause time.Duration) WorkloadFunc {
+	q := `SELECT k, v FROM kv ORDER BY k`
+	var args []any
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	q = bind(dollar, q)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("stream", ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+		peak := watchHeap(ctx)
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				for {
+					if res.stopped() {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					n, err := streamRows(octx, db, q, args, pause, func() { res.addFirstRow(res.clock.Since(start)) })
+					err = done(err)
+					res.addRows(n)
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		cancel()
+		res.PeakHeap = peak()
+		return res.finalize()
+	}
+}
+
+// streamRows reads every row of q, calling first on the first one, and
+// returns how many it read.
+func streamRows(ctx context.Context, db Executor, q string, args []any, pause time.Duration, first func()) (int64, error) {
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var (
+		n int64
+		k string
+		v []byte
+	)
+	for rows.Next() {
+		if n == 0 {
+			first()
+		}
+		if err := rows.Scan(&k, &v); err != nil {
+			return n, err
+		}
+		if n++; pause > 0 && n%streamPauseEvery == 0 {
+			time.Sleep(pause)
+		}
+	}
+	return n, rows.Err()
+}
+
+// watchHeap samples the live heap until ctx ends. The returned func waits
+// for that and gives the largest growth over the heap at the start.
+func watchHeap(ctx context.Context) func() int64 {
+	s := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	read := func() int64 {
+		metrics.Read(s)
+		return int64(s[0].Value.Uint64())
+	}
+	base := read()
+	peak := base
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(50 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				peak = max(peak, read())
+			}
+		}
+	}()
+	return func() int64 {
+		<-done
+		return peak - base
+	}
+}
@@ -0,0 +1,537 @@
+package bench
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/chaisql/chai"
+)
+
+// DefaultWorkloads is the suite run when no workloads are configured.
+var DefaultWorkloads = []string{"insert", "select", "range", "update", "delete"}
+
+// suite is the state shared by the phases of one Run.
+type suite struct {
+	ctx  context.Context
+	db   Executor
+	chai *chai.DB // chai-native runs only; db is nil then
+	kv   kvStore  // key-value baseline runs only; db is nil then
+	cfg  Config
+	keys []string
+
+	prepared map[string]bool
+	events   *eventLog
+	caps     capabilities
+}
+
+// prepare runs fn once per suite for key, typically to load a dataset
+// shared by several phases. Failed preparations are retried.
+func (s *suite) prepare(key string, fn func() error) error {
+	if s.prepared[key] {
+		return nil
+	}
+	s.events.emit("prepare_start", "", map[string]any{"dataset": key})
+	if err := fn(); err != nil {
+		return err
+	}
+	s.events.emit("prepare_end", "", map[string]any{"dataset": key})
+	if s.prepared == nil {
+		s.prepared = make(map[string]bool)
+	}
+	s.prepared[key] = true
+	return nil
+}
+
+// snapshot returns the keys read phases operate on. It is taken once, on
+// first use, so it reflects whatever the earlier write phases inserted.
+func (s *suite) snapshot() ([]string, error) {
+	if s.keys != nil {
+		return s.keys, nil
+	}
+	var keys []string
+	var err error
+	switch {
+	case s.chai != nil:
+		keys, err = chaiKeySnapshot(s.chai, 2048)
+	case s.kv != nil:
+		keys, err = kvKeySnapshot(s.kv, 2048)
+	default:
+		keys, err = FetchKeySnapshot(s.ctx, s.db, s.cfg.Engine, 2048)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.events.emit("key_snapshot", "", map[string]any{"keys": len(keys)})
+	s.keys = keys
+	return keys, nil
+}
+
+// typedReady loads the typed table once per suite.
+func (s *suite) typedReady() error {
+	return s.prepare("typed", func() error {
+		return loadTyped(s.ctx, s.db, s.cfg.Engine, dollarPlaceholders(s.cfg), max(1, s.cfg.Rows))
+	})
+}
+
+// nativePhase returns the phase constructor of runs that bypass
+// database/sql, or nil.
+func nativePhase(cfg Config) func(string, Config) (phaseSpec, bool, error) {
+	switch {
+	case cfg.PgxNative:
+		return pgxNativePhase
+	case cfg.Engine == "chai-native":
+		return chaiNativePhase
+	case isKVEngine(cfg.Engine):
+		return kvPhase
+	}
+	return nil
+}
+
+func nativeEngine(cfg Config) string {
+	if cfg.PgxNative {
+		return "pgx-native"
+	}
+	return cfg.Engine
+}
+
+// phaseSpec is one named step of the suite. build runs right before the
+// phase so it can depend on state left by earlier phases.
+type phaseSpec struct {
+	name  string
+	build func(s *suite) (WorkloadFunc, error)
+}
+
+// planPhases expands the configured workload names into suite phases.
+func planPhases(cfg Config) ([]phaseSpec, error) {
+	if cfg.Replay != "" {
+		return replayPhases(cfg)
+	}
+	names := cfg.Workloads
+	if len(names) == 0 {
+		names = DefaultWorkloads
+	}
+	var phases []phaseSpec
+	for _, name := range names {
+		ps, err := phasesFor(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		phases = append(phases, ps...)
+	}
+	return phases, nil
+}
+
+func phasesFor(name string, cfg Config) ([]phaseSpec, error) {
+	engine, dollar := cfg.Engine, dollarPlaceholders(cfg)
+	withKeys := func(wf func(keys []string) WorkloadFunc, needs ...capability) []phaseSpec {
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.require(needs...); err != nil {
+				return nil, err
+			}
+			keys, err := s.snapshot()
+			if err != nil {
+				return nil, err
+			}
+			return wf(keys), nil
+		}}}
+	}
+
+	if native := nativePhase(cfg); native != nil {
+		spec, ok, err := native(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			spec = phaseSpec{name, func(*suite) (WorkloadFunc, error) {
+				return nil, &errSkip{"no " + nativeEngine(cfg) + " variant"}
+			}}
+		}
+		return []phaseSpec{spec}, nil
+	}
+
+	kg, err := newKeyGens(cfg.KeyGen, cfg.RandflakeSecret)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "insert":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		if cfg.GroupCommit > 0 {
+			phaseName := fmt.Sprintf("insert-group-%s", cfg.GroupCommit)
+			return []phaseSpec{{phaseName, func(*suite) (WorkloadFunc, error) {
+				return groupCommitWorkload(dollar, phaseName, cfg.GroupCommit, kg, pl), nil
+			}}}, nil
+		}
+		return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) {
+			return insertWorkload(dollar, max(1, cfg.TxBatch), kg, pl), nil
+		}}}, nil
+	case "insert-returning":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.require(capReturning); err != nil {
+				return nil, err
+			}
+			return insertReturningWorkload(dollar, max(1, cfg.TxBatch), kg, pl), nil
+		}}}, nil
+	case "autoinc":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			schema, err := autoIncSchema(engine)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.prepare("autoinc", func() error {
+				_, err := s.db.ExecContext(s.ctx, schema)
+				return err
+			}); err != nil {
+				return nil, err
+			}
+			return autoIncWorkload(dollar, max(1, cfg.TxBatch), pl), nil
+		}}}, nil
+	case "conflict":
+		if cfg.ConflictPct < 0 || cfg.ConflictPct > 100 {
+			return nil, fmt.Errorf("conflict percentage must be within 0-100, got %d", cfg.ConflictPct)
+		}
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) {
+			return conflictWorkload(dollar, cfg.ConflictPct, kg, pl), nil
+		}}}, nil
+	case "rollback":
+		if cfg.RollbackPct < 0 || cfg.RollbackPct > 100 {
+			return nil, fmt.Errorf("rollback percentage must be within 0-100, got %d", cfg.RollbackPct)
+		}
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) {
+			return rollbackWorkload(dollar, max(1, cfg.TxBatch), cfg.RollbackPct, kg, pl), nil
+		}}}, nil
+	case "fk":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "child")
+		if err != nil {
+			return nil, err
+		}
+		build := func(wf func(keys []string) WorkloadFunc) func(s *suite) (WorkloadFunc, error) {
+			return func(s *suite) (WorkloadFunc, error) {
+				schema, err := fkSchema(engine)
+				if err != nil {
+					return nil, err
+				}
+				if err := s.prepare("fk", func() error {
+					_, err := s.db.ExecContext(s.ctx, schema)
+					return err
+				}); err != nil {
+					return nil, err
+				}
+				keys, err := s.snapshot()
+				if err != nil {
+					return nil, err
+				}
+				return wf(keys), nil
+			}
+		}
+		return []phaseSpec{
+			{"fk-insert", build(func(keys []string) WorkloadFunc { return fkInsertWorkload(engine, dollar, keys, kg, pl) })},
+			{"fk-delete", build(func(keys []string) WorkloadFunc { return fkDeleteWorkload(engine, dollar, keys) })},
+		}, nil
+	case "savepoint":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.require(capSavepoint); err != nil {
+				return nil, err
+			}
+			// at least one kept and one rolled-back row per batch
+			return savepointWorkload(dollar, max(2, cfg.TxBatch), kg, pl), nil
+		}}}, nil
+	case "select":
+		return withKeys(func(keys []string) WorkloadFunc { return selectWorkload(dollar, keys) }), nil
+	case "range":
+		return withKeys(func(keys []string) WorkloadFunc { return rangeWorkload(dollar, keys, 100) }), nil
+	case "update":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "updated")
+		if err != nil {
+			return nil, err
+		}
+		return withKeys(func(keys []string) WorkloadFunc { return updateWorkload(dollar, keys, pl) }, capPointUpdate), nil
+	case "update-wide":
+		return widePhases(engine), nil
+	case "delete":
+		strategies := cfg.DeleteStrategies
+		if len(strategies) == 0 {
+			strategies = []string{"key"}
+		}
+		phases := make([]phaseSpec, 0, len(strategies))
+		for _, strategy := range strategies {
+			if err := validDeleteStrategy(strategy); err != nil {
+				return nil, err
+			}
+			phaseName := deletePhaseName(strategy)
+			phases = append(phases, phaseSpec{phaseName, func(s *suite) (WorkloadFunc, error) {
+				switch strategy {
+				case "all":
+					return deleteAllWorkload(engine, strategy), nil
+				case "drop":
+					if engine == "generic" {
+						return nil, &errSkip{"the generic engine cannot recreate kv"}
+					}
+					return deleteAllWorkload(engine, strategy), nil
+				}
+				if err := s.require(capPointDelete); err != nil {
+					return nil, err
+				}
+				keys, err := s.snapshot()
+				if err != nil {
+					return nil, err
+				}
+				if strategy == "range" {
+					return deleteRangeWorkload(dollar, keys), nil
+				}
+				return deleteWorkload(dollar, keys), nil
+			}})
+		}
+		return phases, nil
+	case "mixed":
+		if cfg.MixedReadPct < 0 || cfg.MixedReadPct > 100 {
+			return nil, fmt.Errorf("mixed read percentage must be within 0-100, got %d", cfg.MixedReadPct)
+		}
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "updated")
+		if err != nil {
+			return nil, err
+		}
+		return withKeys(func(keys []string) WorkloadFunc {
+			return mixedWorkload(dollar, keys, cfg.MixedReadPct, pl)
+		}, capPointUpdate), nil
+	case "longtx":
+		if err := validLongTxMode(cfg.LongTxMode); err != nil {
+			return nil, err
+		}
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		phaseName := "longtx-" + cfg.LongTxMode
+		return []phaseSpec{{phaseName, func(*suite) (WorkloadFunc, error) {
+			return withLongTx(phaseName, cfg.LongTxMode, insertWorkload(dollar, max(1, cfg.TxBatch), kg, pl)), nil
+		}}}, nil
+	case "snapshot":
+		isolation := cmp.Or(cfg.SnapshotIsolation, "default")
+		if _, ok := isolationLevels[isolation]; !ok {
+			return nil, fmt.Errorf("unknown snapshot isolation %q", isolation)
+		}
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.require(capIsolation(isolation)); err != nil {
+				return nil, err
+			}
+			return withSnapshotReader(name, isolation, insertWorkload(dollar, max(1, cfg.TxBatch), kg, pl)), nil
+		}}}, nil
+	case "deadlock":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "updated")
+		if err != nil {
+			return nil, err
+		}
+		return withKeys(func(keys []string) WorkloadFunc { return deadlockWorkload(dollar, keys, pl) }, capPointUpdate), nil
+	case "hotspot":
+		if cfg.HotPct < 0 || cfg.HotPct > 100 {
+			return nil, fmt.Errorf("hot percentage must be within 0-100, got %d", cfg.HotPct)
+		}
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "updated")
+		if err != nil {
+			return nil, err
+		}
+		return withKeys(func(keys []string) WorkloadFunc {
+			return hotspotWorkload(dollar, keys, max(1, cfg.HotKeys), cfg.HotPct, pl)
+		}, capPointUpdate), nil
+	case "select-in":
+		n := max(1, cfg.InKeys)
+		return withKeys(func(keys []string) WorkloadFunc {
+			return readWorkload(name, dollar, []readQuery{selectInQuery(keys, n)})
+		}), nil
+	case "cancel":
+		if cfg.CancelPct < 0 || cfg.CancelPct > 100 {
+			return nil, fmt.Errorf("cancel percentage must be within 0-100, got %d", cfg.CancelPct)
+		}
+		return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) {
+			return cancelWorkload(dollar, cfg.CancelPct, cfg.CancelAfter), nil
+		}}}, nil
+	case "stream":
+		return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) {
+			return streamWorkload(dollar, cfg.StreamRows, cfg.StreamPause), nil
+		}}}, nil
+	case "noop":
+		return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) { return noopWorkload(), nil }}}, nil
+	case "stmtcache":
+		return withKeys(func(keys []string) WorkloadFunc {
+			return stmtCacheWorkload(dollar, keys, max(1, cfg.StmtShapes))
+		}), nil
+	case "contention":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		if engine != "sqlite" {
+			return []phaseSpec{{name, func(*suite) (WorkloadFunc, error) {
+				return contentionWorkload(engine, dollar, name, max(1, cfg.TxBatch), 0, kg, pl), nil
+			}}}, nil
+		}
+		timeouts := cfg.BusyTimeouts
+		if len(timeouts) == 0 {
+			timeouts = []time.Duration{0}
+		}
+		phases := make([]phaseSpec, 0, len(timeouts))
+		for _, bt := range timeouts {
+			phaseName := fmt.Sprintf("contention-busy-%s", bt)
+			phases = append(phases, phaseSpec{phaseName, func(*suite) (WorkloadFunc, error) {
+				return contentionWorkload(engine, dollar, phaseName, max(1, cfg.TxBatch), bt, kg, pl), nil
+			}})
+		}
+		return phases, nil
+	case "tpcb":
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.require(capPointUpdate); err != nil {
+				return nil, err
+			}
+			scale := max(1, cfg.TpcbScale)
+			if err := loadTpcb(s.ctx, s.db, engine, dollar, scale); err != nil {
+				return nil, err
+			}
+			return tpcbWorkload(dollar, scale), nil
+		}}}, nil
+	case "types":
+		var nextID atomic.Int64
+		build := func(write bool) func(s *suite) (WorkloadFunc, error) {
+			return func(s *suite) (WorkloadFunc, error) {
+				if write {
+					if err := s.require(capPointUpdate); err != nil {
+						return nil, err
+					}
+				}
+				if err := s.typedReady(); err != nil {
+					return nil, err
+				}
+				id, err := typedMaxID(s.ctx, s.db)
+				if err != nil {
+					return nil, err
+				}
+				nextID.Store(id)
+				return typedWorkload(dollar, write, &nextID), nil
+			}
+		}
+		return []phaseSpec{{"types-write", build(true)}, {"types-read", build(false)}}, nil
+	case "filter":
+		phases := make([]phaseSpec, 0, len(filterBuckets))
+		for _, b := range filterBuckets {
+			phases = append(phases, phaseSpec{b.name(), func(s *suite) (WorkloadFunc, error) {
+				if err := s.typedReady(); err != nil {
+					return nil, err
+				}
+				return readWorkload(b.name(), dollar, b.queries()), nil
+			}})
+		}
+		return phases, nil
+	case "doc":
+		set, err := newDocSet(cfg.PayloadCardinality)
+		if err != nil {
+			return nil, err
+		}
+		build := func(wf WorkloadFunc) func(s *suite) (WorkloadFunc, error) {
+			return func(s *suite) (WorkloadFunc, error) {
+				schema, err := docSchema(engine)
+				if err != nil {
+					return nil, err
+				}
+				if err := s.prepare("doc", func() error {
+					if _, err := s.db.ExecContext(s.ctx, schema); err != nil {
+						return err
+					}
+					return loadDocs(s.ctx, s.db, engine, dollar, max(1, cfg.Rows), set)
+				}); err != nil {
+					return nil, err
+				}
+				return wf, nil
+			}
+		}
+		// queries first, so they see exactly the loaded documents
+		return []phaseSpec{
+			{"doc-query", build(readWorkload("doc-query", dollar, docQueries(engine, set)))},
+			{"doc-insert", build(docInsertWorkload(engine, dollar, set, kg))},
+		}, nil
+	case "text":
+		c := newTextCorpus(max(1, cfg.Rows))
+		queries := textQueries(c)
+		build := func(phase string, needs capability, ddl ...string) phaseSpec {
+			return phaseSpec{phase, func(s *suite) (WorkloadFunc, error) {
+				if needs != "" {
+					if err := s.require(needs); err != nil {
+						return nil, err
+					}
+				}
+				if err := s.prepare("text", func() error { return loadText(s.ctx, s.db, engine, dollar, c) }); err != nil {
+					return nil, err
+				}
+				if err := s.prepare(phase, func() error {
+					for _, q := range ddl {
+						if _, err := s.db.ExecContext(s.ctx, q); err != nil {
+							return err
+						}
+					}
+					return nil
+				}); err != nil {
+					return nil, err
+				}
+				return readWorkload(phase, dollar, queries[phase]), nil
+			}}
+		}
+		return []phaseSpec{
+			build("text-prefix", ""),
+			build("text-substring", ""),
+			build("text-fts", capFTS5, textFTS5...),
+			build("text-trigram", capTrigram, textTrigram),
+		}, nil
+	case "sort":
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.typedReady(); err != nil {
+				return nil, err
+			}
+			return readWorkload(name, dollar, sortQueries(max(1, cfg.SortLimit))), nil
+		}}}, nil
+	case "constraints":
+		pl, err := newPayloads(cfg.Payload, cfg.PayloadCardinality, cfg.ValueSize, "payload")
+		if err != nil {
+			return nil, err
+		}
+		return constraintPhases(engine, cfg.ConstraintVariants, max(1, cfg.TxBatch), kg, pl)
+	case "analyze":
+		return analyzePhases(engine, max(1, cfg.SortLimit)), nil
+	case "group":
+		return []phaseSpec{{name, func(s *suite) (WorkloadFunc, error) {
+			if err := s.typedReady(); err != nil {
+				return nil, err
+			}
+			return readWorkload(name, dollar, groupQueries), nil
+		}}}, nil
+	}
+	return nil, fmt.Errorf("unknown workload: %s", name)
+}
@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v6"
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+	"github.com/rs/zerolog/log"
+)
+
+// textCorpus is the content of kv_text: lowercase lines of 6 to 12 words
+// drawn from vocab, the same ones on every run.
+type textCorpus struct {
+	lines []string
+	vocab []string
+}
+
+func newTextCorpus(rows int) textCorpus {
+	f := gofakeit.New(payloadSeed)
+	var c textCorpus
+	seen := make(map[string]bool)
+	for range rows {
+		words := make([]string, 6+f.Rand.Intn(7))
+		for i := range words {
+			words[i] = strings.ToLower(f.Word())
+			if !seen[words[i]] {
+				seen[words[i]] = true
+				c.vocab = append(c.vocab, words[i])
+			}
+		}
+		c.lines = append(c.lines, strings.Join(words, " "))
+	}
+	slices.Sort(c.vocab)
+	return c
+}
+
+func (c textCorpus) word(rnd *rand.Rand) string { return c.vocab[rnd.Intn(len(c.vocab))] }
+
+// loadText creates kv_text and fills it with the corpus, reusing a table
+// that already has as many rows. On postgres it drops the trigram index a
+// previous text-trigram phase left, so text-substring runs without it.
+func loadText(ctx context.Context, db Executor, engine string, dollar bool, c textCorpus) error {
+	schema, err := schemaFor(engine, embed.TextPgSchema, embed.TextSqliteSchema, embed.TextChaiSchema, embed.TextMysqlSchema, embed.TextClickhouseSchema)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+	if engine == "pgx" {
+		if _, err := db.ExecContext(ctx, `DROP INDEX IF EXISTS kv_text_trgm`); err != nil {
+			return err
+		}
+	}
+
+	rows := len(c.lines)
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM kv_text`).Scan(&n); err != nil {
+		return err
+	}
+	if n == rows {
+		log.Info().Int("rows", rows).Msg("reusing text dataset")
+		return nil
+	}
+
+	log.Info().Int("rows", rows).Msg("loading text dataset")
+	wipe := `DELETE FROM kv_text`
+	if engine == "clickhouse" {
+		wipe = `TRUNCATE TABLE kv_text` // DELETE is a mutation there
+	}
+	if _, err := db.ExecContext(ctx, wipe); err != nil {
+		return err
+	}
+	id := 0
+	q := bind(dollar, `INSERT INTO kv_text(id, t) VALUES(?, ?)`)
+	return loadRows(ctx, db, q, func() ([]any, error) {
+		if id == rows {
+			return nil, io.EOF
+		}
+		id++
+		return []any{id, c.lines[id-1]}, nil
+	})
+}
+
+// textFTS5 indexes kv_text in an external-content FTS5 table, rebuilt from
+// kv_text so it matches the loaded corpus.
+var textFTS5 = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS kv_text_fts USING fts5(t, content='kv_text', content_rowid='id')`,
+	`INSERT INTO kv_text_fts(kv_text_fts) VALUES('rebuild')`,
+}
+
+// textTrigram is the pg_trgm index that lets postgres answer LIKE
+// '%...%' without a scan. The extension must already be installed.
+const textTrigram = `CREATE INDEX IF NOT EXISTS kv_text_trgm ON kv_text USING gin (t gin_trgm_ops)`
+
+// textQueries are the reads of the text phases: a LIKE prefix match, a
+// LIKE substring match (also run by text-trigram, over the trigram index)
+// and an FTS5 word match.
+func textQueries(c textCorpus) map[string][]readQuery {
+	substring := []readQuery{{`SELECT id FROM kv_text WHERE t LIKE ?`, func(rnd *rand.Rand) []any {
+		return []any{"%" + c.word(rnd) + "%"}
+	}}}
+	return map[string][]readQuery{
+		"text-prefix": {{`SELECT id FROM kv_text WHERE t LIKE ?`, func(rnd *rand.Rand) []any {
+			return []any{c.word(rnd) + " %"}
+		}}},
+		"text-substring": substring,
+		"text-fts": {{`SELECT rowid FROM kv_text_fts WHERE kv_text_fts MATCH ?`, func(rnd *rand.Rand) []any {
+			return []any{`"` + c.word(rnd) + `"`}
+		}}},
+		"text-trigram": substring,
+	}
+}
@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+	_ "unsafe" // go:linkname
+)
+
+// coarseTick is the resolution of the coarse clock.
+const coarseTick = 100 * time.Microsecond
+
+// nanotime is the runtime's monotonic clock: a single vDSO read (the TSC
+// on most x86 hosts), where time.Now also reads the wall clock.
+//
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+// timingClock is the Clock of timing mode mode (Config.Timing) and a func
+// releasing it:
+//
+//	wall:   time.Now, two clock reads per timestamp (default)
+//	mono:   the runtime's monotonic clock only, one read per timestamp
+//	coarse: a timestamp a background goroutine refreshes every coarseTick;
+//	        reading it costs an atomic load, but latencies are only good to
+//	        coarseTick (and worse when the workers leave the goroutine no
+//	        CPU), so it is for the throughput of very fast operations
+//
+// Deadlines and tickers stay on the wall clock in every mode.
+func timingClock(mode string) (Clock, func(), error) {
+	switch mode {
+	case "", "wall":
+		return wallClock{}, func() {}, nil
+	case "mono":
+		return newMonoClock(), func() {}, nil
+	case "coarse":
+		c := newCoarseClock()
+		return c, c.stop, nil
+	}
+	return nil, nil, fmt.Errorf("unknown timing mode %q (wall|mono|coarse)", mode)
+}
+
+// monoClock derives timestamps from nanotime and the wall time it was
+// created at.
+type monoClock struct {
+	wallClock
+	base     time.Time
+	baseNano int64
+}
+
+func newMonoClock() *monoClock { return &monoClock{base: time.Now(), baseNano: nanotime()} }
+
+func (c *monoClock) Now() time.Time { return c.base.Add(time.Duration(nanotime() - c.baseNano)) }
+
+func (c *monoClock) Since(t time.Time) time.Duration { return c.Now().Sub(t) }
+
+// coarseClock serves the monoClock time of its last refresh.
+type coarseClock struct {
+	monoClock
+	now  atomic.Int64 // nanotime of the last refresh
+	done chan struct{}
+}
+
+func newCoarseClock() *coarseClock {
+	c := &coarseClock{monoClock: *newMonoClock(), done: make(chan struct{})}
+	c.now.Store(nanotime())
+	go func() {
+		t := time.NewTicker(coarseTick)
+		defer t.Stop()
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-t.C:
+				c.now.Store(nanotime())
+			}
+		}
+	}()
+	return c
+}
+
+func (c *coarseClock) Now() time.Time { return c.base.Add(time.Duration(c.now.Load() - c.baseNano)) }
+
+func (c *coarseClock) Since(t time.Time) time.Duration { return c.Now().Sub(t) }
+
+func (c *coarseClock) stop() { close(c.done) }
@@ -0,0 +1,161 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+	"github.com/rs/zerolog/log"
+)
+
+// Row counts per scale factor, as created by `pgbench -i`.
+const (
+	tpcbBranches = 1
+	tpcbTellers  = 10
+	tpcbAccounts = 100000
+)
+
+// The pgbench "TPC-B (sort of)" transaction.
+var tpcbQueries = [...]string{
+	`UPDATE pgbench_accounts SET abalance = abalance + ? WHERE aid = ?`,
+	`SELECT abalance FROM pgbench_accounts WHERE aid = ?`,
+	`UPDATE pgbench_tellers SET tbalance = tbalance + ? WHERE tid = ?`,
+	`UPDATE pgbench_branches SET bbalance = bbalance + ? WHERE bid = ?`,
+	`INSERT INTO pgbench_history(tid, bid, aid, delta, mtime) VALUES(?, ?, ?, ?, ?)`,
+}
+
+// loadTpcb creates the pgbench tables and fills them for scale, reusing an
+// existing dataset of the same scale like a pgbench database would be.
+func loadTpcb(ctx context.Context, db Executor, engine string, dollar bool, scale int) error {
+	schema, err := schemaFor(engine, embed.TpcbPgSchema, embed.TpcbSqliteSchema, embed.TpcbChaiSchema, embed.TpcbMysqlSchema, "")
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	var branches int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pgbench_branches`).Scan(&branches); err != nil {
+		return err
+	}
+	if branches == scale*tpcbBranches {
+		log.Info().Int("scale", scale).Msg("reusing tpcb dataset")
+		return nil
+	}
+
+	log.Info().Int("scale", scale).Msg("loading tpcb dataset")
+	for _, t := range []string{"pgbench_history", "pgbench_accounts", "pgbench_tellers", "pgbench_branches"} {
+		if _, err := db.ExecContext(ctx, `DELETE FROM `+t); err != nil {
+			return err
+		}
+	}
+	filler := strings.Repeat(" ", 84)
+	tables := []struct {
+		q    string
+		n    int
+		args func(id int) []any
+	}{
+		{`INSERT INTO pgbench_branches(bid, bbalance) VALUES(?, 0)`, scale * tpcbBranches,
+			func(id int) []any { return []any{id} }},
+		{`INSERT INTO pgbench_tellers(tid, bid, tbalance) VALUES(?, ?, 0)`, scale * tpcbTellers,
+			func(id int) []any { return []any{id, (id-1)/tpcbTellers + 1} }},
+		{`INSERT INTO pgbench_accounts(aid, bid, abalance, filler) VALUES(?, ?, 0, ?)`, scale * tpcbAccounts,
+			func(id int) []any { return []any{id, (id-1)/tpcbAccounts + 1, filler} }},
+	}
+	for _, t := range tables {
+		id := 0
+		next := func() ([]any, error) {
+			if id++; id > t.n {
+				return nil, io.EOF
+			}
+			return t.args(id), nil
+		}
+		if err := loadRows(ctx, db, bind(dollar, t.q), next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tpcbWorkload(dollar bool, scale int) WorkloadFunc {
+	var qs [len(tpcbQueries)]string
+	for i, q := range tpcbQueries {
+		qs[i] = bind(dollar, q)
+	}
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("tpcb", ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					aid := rnd.Intn(scale*tpcbAccounts) + 1
+					tid := rnd.Intn(scale*tpcbTellers) + 1
+					bid := rnd.Intn(scale*tpcbBranches) + 1
+					delta := rnd.Intn(10001) - 5000
+
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					err = done(tpcbTx(octx, db, qs, aid, tid, bid, delta))
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+func tpcbTx(ctx context.Context, db Executor, qs [len(tpcbQueries)]string, aid, tid, bid, delta int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, qs[0], delta, aid); err != nil {
+		return fail(err)
+	}
+	var balance int
+	if err := tx.QueryRowContext(ctx, qs[1], aid).Scan(&balance); err != nil {
+		return fail(fmt.Errorf("read account %d: %w", aid, err))
+	}
+	if _, err := tx.ExecContext(ctx, qs[2], delta, tid); err != nil {
+		return fail(err)
+	}
+	if _, err := tx.ExecContext(ctx, qs[3], delta, bid); err != nil {
+		return fail(err)
+	}
+	if _, err := tx.ExecContext(ctx, qs[4], tid, bid, aid, delta, time.Now().UTC()); err != nil {
+		return fail(err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,235 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TuneConfig bounds a tune run.
+type TuneConfig struct {
+	P99Cap         time.Duration // settings with a higher p99 do not qualify; 0 = no cap
+	MaxConcurrency int           // highest concurrency TuneConcurrency tries
+	TxBatches      []int         // tx_batch values TuneTxBatch tries, in order
+}
+
+// TuneStep is one setting a tune run measured, with a full Run of the
+// workload.
+type TuneStep struct {
+	Value     int           `json:"value"`
+	OpsPerSec float64       `json:"ops_per_sec"`
+	P99       time.Duration `json:"p99"`
+	Errors    int64         `json:"errors,omitempty"`
+	OK        bool          `json:"ok"`               // qualifies: within the p99 cap, not aborted
+	Reason    string        `json:"reason,omitempty"` // why not
+}
+
+// TuneReport is what a tune run measured, in order, and the qualifying
+// setting with the most ops/s.
+type TuneReport struct {
+	Engine   string        `json:"engine"`
+	Workload string        `json:"workload"`
+	Param    string        `json:"param"`
+	P99Cap   time.Duration `json:"p99_cap,omitempty"`
+	Steps    []TuneStep    `json:"steps"`
+	Best     int           `json:"best,omitempty"` // value of the best step; 0 = none qualified
+}
+
+// tuner measures cfg's single workload at settings of one parameter, each
+// at most once.
+type tuner struct {
+	ctx   context.Context
+	cfg   Config
+	tc    TuneConfig
+	set   func(cfg *Config, v int)
+	rep   *TuneReport
+	steps map[int]TuneStep
+}
+
+func newTuner(ctx context.Context, cfg Config, tc TuneConfig, param string, set func(*Config, int)) (*tuner, error) {
+	if len(cfg.Workloads) != 1 {
+		return nil, fmt.Errorf("tuning needs exactly one workload, got %d", len(cfg.Workloads))
+	}
+	engine, err := NormalizeEngine(cfg.Engine)
+	if err != nil {
+		return nil, err
+	}
+	return &tuner{
+		ctx:   ctx,
+		cfg:   cfg,
+		tc:    tc,
+		set:   set,
+		rep:   &TuneReport{Engine: engine, Workload: cfg.Workloads[0], Param: param, P99Cap: tc.P99Cap},
+		steps: make(map[int]TuneStep),
+	}, nil
+}
+
+// measure runs the workload with the parameter at v and judges the first
+// phase it reports.
+func (t *tuner) measure(v int) (TuneStep, error) {
+	if s, ok := t.steps[v]; ok {
+		return s, nil
+	}
+	cfg := t.cfg
+	t.set(&cfg, v)
+	rep, err := Run(t.ctx, cfg)
+	if err != nil {
+		return TuneStep{}, err
+	}
+	var res *Result
+	for i := range rep.Results {
+		if rep.Results[i].Parent == "" {
+			res = &rep.Results[i]
+			break
+		}
+	}
+	if res == nil {
+		return TuneStep{}, fmt.Errorf("%s reported no results", t.rep.Workload)
+	}
+	if res.Skipped {
+		return TuneStep{}, fmt.Errorf("%s skipped: %s", res.Workload, res.SkipReason)
+	}
+
+	s := TuneStep{Value: v, OpsPerSec: opsPerSec(*res), P99: res.P99, Errors: res.Errors, OK: true}
+	switch {
+	case res.Aborted:
+		s.OK, s.Reason = false, "aborted: "+res.AbortReason
+	case res.Ops == 0:
+		s.OK, s.Reason = false, "no operations"
+	case t.tc.P99Cap > 0 && res.P99 > t.tc.P99Cap:
+		s.OK, s.Reason = false, "p99 over the cap"
+	}
+	log.Info().Str("param", t.rep.Param).Int("value", v).Float64("ops_per_sec", s.OpsPerSec).Dur("p99", s.P99).Bool("ok", s.OK).Msg("tune step")
+	t.steps[v] = s
+	t.rep.Steps = append(t.rep.Steps, s)
+	return s, nil
+}
+
+// better reports whether s qualifies and beats the best step so far.
+func (t *tuner) better(s TuneStep) bool {
+	if !s.OK {
+		return false
+	}
+	best, ok := t.steps[t.rep.Best]
+	return !ok || s.OpsPerSec > best.OpsPerSec
+}
+
+// TuneConcurrency looks for the concurrency with the most ops/s whose p99
+// stays within tc.P99Cap: it doubles the concurrency from 1 until ops/s
+// stops growing, the cap is exceeded or tc.MaxConcurrency is reached,
+// then bisects the stretch around the best value found, assuming ops/s
+// has a single peak. cfg must name exactly one workload; every step is a
+// Run of its own, warmup included.
+func TuneConcurrency(ctx context.Context, cfg Config, tc TuneConfig) (*TuneReport, error) {
+	t, err := newTuner(ctx, cfg, tc, "concurrency", func(cfg *Config, v int) { cfg.Concurrency = v })
+	if err != nil {
+		return nil, err
+	}
+	limit := max(1, tc.MaxConcurrency)
+
+	hi := limit // first value past the best on the way up
+	for c := 1; ; c = min(2*c, limit) {
+		s, err := t.measure(c)
+		if err != nil {
+			return nil, err
+		}
+		if !t.better(s) {
+			hi = c
+			break
+		}
+		t.rep.Best = c
+		if c == limit {
+			break
+		}
+	}
+	if t.rep.Best == 0 {
+		return t.rep, nil
+	}
+
+	lo, mid := max(1, t.rep.Best/2), t.rep.Best
+	for hi-lo > 2 {
+		l, r := (lo+mid)/2, (mid+hi)/2
+		sl, err := t.measure(l)
+		if err != nil {
+			return nil, err
+		}
+		sr, err := t.measure(r)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case t.better(sl) && (!t.better(sr) || sl.OpsPerSec >= sr.OpsPerSec):
+			t.rep.Best, hi, mid = l, mid, l
+		case t.better(sr):
+			t.rep.Best, lo, mid = r, mid, r
+		default:
+			lo, hi = l, r
+		}
+	}
+	return t.rep, nil
+}
+
+// TuneTxBatches are the tx_batch values TuneTxBatch tries by default.
+var TuneTxBatches = []int{1, 10, 100, 1000}
+
+// TuneTxBatch measures cfg's single workload, meant to be insert, at every
+// tx_batch of tc.TxBatches and recommends the one with the most rows per
+// second whose p99 stays within tc.P99Cap. Larger batches usually trade
+// statement latency, and the work lost to a failed commit, for throughput.
+func TuneTxBatch(ctx context.Context, cfg Config, tc TuneConfig) (*TuneReport, error) {
+	t, err := newTuner(ctx, cfg, tc, "tx_batch", func(cfg *Config, v int) { cfg.TxBatch = v })
+	if err != nil {
+		return nil, err
+	}
+	batches := tc.TxBatches
+	if len(batches) == 0 {
+		batches = TuneTxBatches
+	}
+	for _, b := range batches {
+		if b < 1 {
+			return nil, fmt.Errorf("invalid tx_batch %d", b)
+		}
+		s, err := t.measure(b)
+		if err != nil {
+			return nil, err
+		}
+		if t.better(s) {
+			t.rep.Best = b
+		}
+	}
+	return t.rep, nil
+}
+
+func (r TuneReport) Pretty() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Engine\t\t: %s\n", r.Engine)
+	fmt.Fprintf(&b, "Workload\t: %s\n", r.Workload)
+	if r.P99Cap > 0 {
+		fmt.Fprintf(&b, "P99 cap\t\t: %s\n", fDur(r.P99Cap))
+	}
+	fmt.Fprintf(&b, "  %-12s %12s %10s\n", r.Param, "ops/s", "p99")
+	for _, s := range r.Steps {
+		verdict := "ok"
+		if !s.OK {
+			verdict = s.Reason
+		}
+		fmt.Fprintf(&b, "  %-12d %12.1f %10s  %s\n", s.Value, s.OpsPerSec, fDur(s.P99), verdict)
+	}
+	for _, s := range r.Steps {
+		if s.Value == r.Best && s.OK {
+			fmt.Fprintf(&b, "Best\t\t: %s %d (%.1f ops/s, p99 %s)\n", r.Param, s.Value, s.OpsPerSec, fDur(s.P99))
+			return b.String()
+		}
+	}
+	fmt.Fprintf(&b, "Best\t\t: none within the p99 cap\n")
+	return b.String()
+}
+
+func (r TuneReport) JSON() string {
+	j, _ := json.MarshalIndent(r, "", "  ")
+	return string(j)
+}
@@ -0,0 +1,203 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+	"github.com/rs/zerolog/log"
+)
+
+// typedWords is the vocabulary of the typed.t column; a small set keeps
+// equality and LIKE predicates selective but never empty.
+var typedWords = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliet", "kilo", "lima", "mike", "november", "oscar", "papa",
+}
+
+// typedEpoch anchors typed.ts; values spread over the following ~3 years.
+var typedEpoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const typedSpan = 100_000_000 // seconds
+
+// typedGroups is the cardinality of typed.g, the GROUP BY column.
+const typedGroups = 1000
+
+// typedRow generates the columns of typed (without id). Every nullable
+// column is NULL one time in nullEvery.
+func typedRow(rnd *rand.Rand) []any {
+	const nullEvery = 10
+	null := func(v any) any {
+		if rnd.Intn(nullEvery) == 0 {
+			return nil
+		}
+		return v
+	}
+	return []any{
+		null(rnd.Int63n(1_000_000)),
+		null(rnd.Float64() * 1000),
+		null(rnd.Intn(2) == 1),
+		null(typedEpoch.Add(time.Duration(rnd.Int63n(typedSpan)) * time.Second)),
+		null(fmt.Sprintf("%s-%d", typedWords[rnd.Intn(len(typedWords))], rnd.Intn(1000))),
+		null(rnd.Intn(typedGroups)),
+	}
+}
+
+// loadTyped creates the typed table and fills it with rows rows, reusing it
+// when it already holds exactly that many.
+func loadTyped(ctx context.Context, db Executor, engine string, dollar bool, rows int) error {
+	schema, err := schemaFor(engine, embed.TypedPgSchema, embed.TypedSqliteSchema, embed.TypedChaiSchema, embed.TypedMysqlSchema, embed.TypedClickhouseSchema)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM typed`).Scan(&n); err != nil {
+		return err
+	}
+	if n == rows {
+		log.Info().Int("rows", rows).Msg("reusing typed dataset")
+		return nil
+	}
+
+	log.Info().Int("rows", rows).Msg("loading typed dataset")
+	wipe := `DELETE FROM typed`
+	if engine == "clickhouse" {
+		wipe = `TRUNCATE TABLE typed` // DELETE is a mutation there
+	}
+	if _, err := db.ExecContext(ctx, wipe); err != nil {
+		return err
+	}
+	rnd := rand.New(rand.NewSource(payloadSeed))
+	id := 0
+	q := bind(dollar, `INSERT INTO typed(id, i, f, b, ts, t, g) VALUES(?, ?, ?, ?, ?, ?, ?)`)
+	return loadRows(ctx, db, q, func() ([]any, error) {
+		if id++; id > rows {
+			return nil, io.EOF
+		}
+		return append([]any{id}, typedRow(rnd)...), nil
+	})
+}
+
+var typedReads = []readQuery{
+	{`SELECT id, i, f, b, ts, t FROM typed WHERE i >= ? AND i < ?`, func(rnd *rand.Rand) []any {
+		lo := rnd.Int63n(1_000_000)
+		return []any{lo, lo + 1000}
+	}},
+	{`SELECT id, i, f, b, ts, t FROM typed WHERE b = ? AND f > ? LIMIT 100`, func(rnd *rand.Rand) []any {
+		return []any{rnd.Intn(2) == 1, rnd.Float64() * 1000}
+	}},
+	{`SELECT id, i, f, b, ts, t FROM typed WHERE ts < ? AND i IS NOT NULL LIMIT 100`, func(rnd *rand.Rand) []any {
+		return []any{typedEpoch.Add(time.Duration(rnd.Int63n(typedSpan)) * time.Second)}
+	}},
+	{`SELECT id, i, f, b, ts, t FROM typed WHERE t = ?`, func(rnd *rand.Rand) []any {
+		return []any{fmt.Sprintf("%s-%d", typedWords[rnd.Intn(len(typedWords))], rnd.Intn(1000))}
+	}},
+	{`SELECT id, i, f, b, ts, t FROM typed WHERE f IS NULL LIMIT 100`, func(*rand.Rand) []any { return nil }},
+}
+
+// scanTyped drains rows into nullable destinations so every column goes
+// through the driver's type conversion.
+func scanTyped(rows *sql.Rows) error {
+	var (
+		id sql.NullInt64
+		i  sql.NullInt64
+		f  sql.NullFloat64
+		b  sql.NullBool
+		ts sql.NullTime
+		t  sql.NullString
+	)
+	for rows.Next() {
+		if err := rows.Scan(&id, &i, &f, &b, &ts, &t); err != nil {
+			_ = rows.Close()
+			return err
+		}
+	}
+	return rows.Close()
+}
+
+// typedWorkload reads (write=false) or writes (write=true) the typed table.
+// Writes alternate between inserting new rows and rewriting existing ones,
+// both with NULLs mixed into every nullable column.
+func typedWorkload(dollar bool, write bool, nextID *atomic.Int64) WorkloadFunc {
+	name := "types-read"
+	if write {
+		name = "types-write"
+	}
+	reads := make([]readQuery, len(typedReads))
+	for i, r := range typedReads {
+		reads[i] = readQuery{bind(dollar, r.q), r.args}
+	}
+	insertQ := bind(dollar, `INSERT INTO typed(id, i, f, b, ts, t, g) VALUES(?, ?, ?, ?, ?, ?, ?)`)
+	updateQ := bind(dollar, `UPDATE typed SET i = ?, f = ?, b = ?, ts = ?, t = ?, g = ? WHERE id = ?`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+
+					var err error
+					release := func() {}
+					if write {
+						if release, err = res.acquireWrite(ctx); err != nil {
+							return
+						}
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					switch {
+					case !write:
+						r := reads[rnd.Intn(len(reads))]
+						var rows *sql.Rows
+						if rows, err = db.QueryContext(octx, r.q, r.args(rnd)...); err == nil {
+							err = scanTyped(rows)
+						}
+					case rnd.Intn(2) == 0:
+						_, err = db.ExecContext(octx, insertQ, append([]any{nextID.Add(1)}, typedRow(rnd)...)...)
+					default:
+						id := rnd.Int63n(nextID.Load()) + 1
+						_, err = db.ExecContext(octx, updateQ, append(typedRow(rnd), id)...)
+					}
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+// typedMaxID returns the highest id in typed, so inserts continue after it.
+func typedMaxID(ctx context.Context, db Executor) (int64, error) {
+	var id sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT MAX(id) FROM typed`).Scan(&id)
+	return id.Int64, err
+}
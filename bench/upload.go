@@ -0,0 +1,66 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	uploadAttempts = 3
+	uploadTimeout  = 30 * time.Second // per attempt
+)
+
+// Upload POSTs the JSON report to url, e.g. the ingest endpoint of a shared
+// results dashboard, with token (if any) as a bearer token. The run id is
+// sent as Idempotency-Key, so a server may drop the duplicates of retried
+// uploads. Network errors and 5xx responses are retried with backoff.
+func Upload(ctx context.Context, url, token string, rep *Report) error {
+	body := []byte(rep.JSON())
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		var retry bool
+		if retry, err = uploadOnce(ctx, url, token, rep.RunID, body); err == nil || !retry {
+			return err
+		}
+		if attempt < uploadAttempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+	return fmt.Errorf("after %d attempts: %w", uploadAttempts, err)
+}
+
+// uploadOnce makes one upload attempt; retry reports whether a failure
+// may go away on its own.
+func uploadOnce(ctx context.Context, url, token, runID string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", runID)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return resp.StatusCode >= 500, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
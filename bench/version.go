@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+)
+
+// driverModules are the modules whose versions determine what is being
+// measured; they are reported alongside the server/library version.
+var driverModules = []string{
+	"github.com/chaisql/chai",
+	"github.com/glebarez/go-sqlite",
+	"modernc.org/sqlite",
+	"github.com/jackc/pgx/v5",
+	"github.com/go-sql-driver/mysql",
+	"github.com/ClickHouse/clickhouse-go",
+	"github.com/dgraph-io/badger/v4",
+	"github.com/cockroachdb/pebble",
+	"go.etcd.io/bbolt",
+}
+
+// engineVersion asks the engine for its own version. Chai has no SQL
+// version function, so its library module version is used instead.
+func engineVersion(ctx context.Context, db *sql.DB, engine string) string {
+	var q string
+	switch engine {
+	case "pgx", "clickhouse":
+		q = `SELECT version()`
+	case "mariadb", "tidb":
+		q = `SELECT VERSION()`
+	case "sqlite", "sqlite3":
+		q = `SELECT sqlite_version()`
+	case "chai", "chai-native":
+		return driverVersions()["github.com/chaisql/chai"]
+	case "badger":
+		return driverVersions()["github.com/dgraph-io/badger/v4"]
+	case "pebble":
+		return driverVersions()["github.com/cockroachdb/pebble"]
+	case "bbolt":
+		return driverVersions()["go.etcd.io/bbolt"]
+	default:
+		return ""
+	}
+
+	var v string
+	if err := db.QueryRowContext(ctx, q).Scan(&v); err != nil {
+		log.Warn().Err(err).Str("engine", engine).Msg("failed to detect engine version")
+		return ""
+	}
+	return v
+}
+
+// driverVersions returns the linked versions of driverModules, taking
+// replace directives into account. Binaries built without module support
+// report nothing.
+func driverVersions() map[string]string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(driverModules))
+	for _, dep := range bi.Deps {
+		for _, m := range driverModules {
+			if dep.Path != m {
+				continue
+			}
+			v := dep.Version
+			if dep.Replace != nil {
+				v = dep.Replace.Path + "@" + dep.Replace.Version
+			}
+			out[m] = v
+		}
+	}
+	return out
+}
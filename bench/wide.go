@@ -0,0 +1,172 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	embed "github.com/gosuda/chaisql-benchmark/sql"
+	"github.com/rs/zerolog/log"
+)
+
+// wideCols and wideColSize shape a kv_wide row: one counter and wideCols
+// payload columns of wideColSize bytes each, about 1KB in all.
+const (
+	wideCols    = 8
+	wideColSize = 128
+)
+
+// loadWide creates kv_wide and fills it with ids 1..rows, reusing a table
+// that already has as many rows.
+func loadWide(ctx context.Context, db Executor, engine string, dollar bool, rows int) error {
+	schema, err := schemaFor(engine, embed.WidePgSchema, embed.WideSqliteSchema, embed.WideChaiSchema, embed.WideMysqlSchema, "")
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM kv_wide`).Scan(&n); err != nil {
+		return err
+	}
+	if n == rows {
+		log.Info().Int("rows", rows).Msg("reusing wide dataset")
+		return nil
+	}
+
+	log.Info().Int("rows", rows).Msg("loading wide dataset")
+	if _, err := db.ExecContext(ctx, `DELETE FROM kv_wide`); err != nil {
+		return err
+	}
+	rnd := rand.New(rand.NewSource(payloadSeed))
+	id := 0
+	q := bind(dollar, `INSERT INTO kv_wide(id, n, `+wideColList("")+`) VALUES(?, 0`+strings.Repeat(", ?", wideCols)+`)`)
+	return loadRows(ctx, db, q, func() ([]any, error) {
+		if id++; id > rows {
+			return nil, io.EOF
+		}
+		return append([]any{id}, wideRow(rnd)...), nil
+	})
+}
+
+// wideColList is "c1, c2, ..." up to wideCols, each column followed by
+// suffix.
+func wideColList(suffix string) string {
+	cols := make([]string, wideCols)
+	for i := range cols {
+		cols[i] = fmt.Sprintf("c%d%s", i+1, suffix)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// wideRow is fresh random content for every payload column.
+func wideRow(rnd *rand.Rand) []any {
+	row := make([]any, wideCols)
+	for i := range row {
+		b := make([]byte, wideColSize)
+		rnd.Read(b)
+		row[i] = b
+	}
+	return row
+}
+
+// widePhases are the row rewrite comparison on kv_wide: update-partial
+// bumps the counter column only, update-full rewrites every column of the
+// row, with a Delta against update-partial. The closer the two are, the
+// more a one-column update costs the engine a rewrite of the whole row.
+func widePhases(engine string) []phaseSpec {
+	var partial *Result
+	build := func(full bool) func(s *suite) (WorkloadFunc, error) {
+		return func(s *suite) (WorkloadFunc, error) {
+			if err := s.require(capPointUpdate); err != nil {
+				return nil, err
+			}
+			rows := max(1, s.cfg.Rows)
+			if err := s.prepare("wide", func() error {
+				return loadWide(s.ctx, s.db, engine, dollarPlaceholders(s.cfg), rows)
+			}); err != nil {
+				return nil, err
+			}
+			wf := wideUpdateWorkload(dollarPlaceholders(s.cfg), full, rows)
+			return func(ctx context.Context, db Executor, ph Phase) Result {
+				res := wf(ctx, db, ph)
+				switch {
+				case ph.Warmup:
+				case !full:
+					partial = &res
+				case partial != nil && !partial.Aborted:
+					res.Delta = newDelta(*partial, res)
+				}
+				return res
+			}, nil
+		}
+	}
+	return []phaseSpec{{"update-partial", build(false)}, {"update-full", build(true)}}
+}
+
+// wideUpdateWorkload updates random kv_wide rows: only the counter, or
+// with full every payload column too.
+func wideUpdateWorkload(dollar bool, full bool, rows int) WorkloadFunc {
+	name, q := "update-partial", `UPDATE kv_wide SET n = n + 1 WHERE id = ?`
+	if full {
+		name = "update-full"
+		q = `UPDATE kv_wide SET n = n + 1, ` + wideColList(" = ?") + ` WHERE id = ?`
+	}
+	q = bind(dollar, q)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		stmt, err := db.PrepareContext(ctx, q)
+		if err != nil {
+			res.addErrorCnt(err)
+			return res.finalize()
+		}
+		defer stmt.Close()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					var args []any
+					if full {
+						args = wideRow(rnd)
+					}
+					args = append(args, 1+rnd.Intn(rows))
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					_, err = stmt.ExecContext(octx, args...)
+					err = done(err)
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
@@ -7,68 +7,112 @@ import (
 	"math/rand"
 	"sync"
 	"time"
-
-	"github.com/rs/zerolog/log"
 )
 
-type WorkloadFunc func(ctx context.Context, db *sql.DB, conc int, dur time.Duration) Result
+type WorkloadFunc func(ctx context.Context, db Executor, ph Phase) Result
+
+// Phase carries the knobs shared by every workload for a single run of it.
+type Phase struct {
+	Concurrency int
+	Duration    time.Duration
+	CPUSet      []int // pin workers to these CPUs; empty leaves scheduling to the OS
+	ErrorBudget ErrorBudget
+	WriteLimit  int // max simultaneous write operations; 0 = one per worker
+	// DropFailedTx holds the statements of a transaction out of Ops until
+	// it commits (see txOps).
+	DropFailedTx bool
+	Warmup       bool // the unreported run before the measured one
+	// OpTimeout bounds every operation (statement or transaction); 0 = the
+	// phase deadline only. See Result.op.
+	OpTimeout time.Duration
+	// Trim is how much of the measured phase's start Result.Trimmed
+	// leaves out, in whole seconds; 0 = none.
+	Trim       time.Duration
+	StallAlarm StallAlarm
+	// MemLimit aborts the phase once the process's resident memory exceeds
+	// it, in bytes; 0 = no limit. See Result.watchMemory.
+	MemLimit int64
+	shared   bool  // other suites run in this process too (see RunEngines)
+	Clock    Clock // nil is the wall clock
+	// MinSamples keeps the measured phase running past Duration, up to
+	// MaxDuration, until it has this many operations; 0 = stop at Duration.
+	// See Result.watchSamples.
+	MinSamples  int
+	MaxDuration time.Duration // 0 = 5 × Duration
+	record      *opRecorder   // operation log of the kv workloads; nil = off
+	// Partitioned gives every worker a disjoint part of the key
+	// snapshot instead of all of it; see workerKeys.
+	Partitioned bool
+}
 
-func insertWorkload(engine string, batch int) WorkloadFunc {
-	q := `INSERT INTO kv(k, v) VALUES(?, ?)`
-	if engine == "pgx" {
-		q = `INSERT INTO kv(k, v) VALUES($1, $2)`
+// workerKeys is the part of keys worker draws from: all of them or, with
+// Partitioned, a contiguous range of its own, so no two workers touch
+// the same row. With fewer keys than workers they are all shared.
+func (ph Phase) workerKeys(keys []string, worker int) []string {
+	if !ph.Partitioned || len(keys) < ph.Concurrency {
+		return keys
 	}
+	n := len(keys)
+	return keys[worker*n/ph.Concurrency : (worker+1)*n/ph.Concurrency]
+}
 
-	return func(ctx context.Context, db *sql.DB, conc int, dur time.Duration) Result {
-		res := newResult("insert", conc, dur)
-		ctx, cancel := context.WithTimeout(ctx, dur)
+func (ph Phase) clock() Clock {
+	if ph.Clock == nil {
+		return wallClock{}
+	}
+	return ph.Clock
+}
+
+func insertWorkload(dollar bool, batch int, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("insert", ph)
+		ctx, cancel := res.start(ctx)
 		defer cancel()
 		var wg sync.WaitGroup
-		for w := range conc {
+		for w := range ph.Concurrency {
 			wg.Add(1)
 			go func(worker int) {
 				defer wg.Done()
-				gen, err := NewRandflake(worker)
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
 				if err != nil {
 					res.addErrorCnt(err)
 					return
 				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				ops := res.txOps(worker)
 
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					default:
-					}
-					tx, err := db.BeginTx(ctx, nil)
-					if err != nil {
-						res.addErrorCnt(err)
-						continue
-					}
-					stmt, err := tx.PrepareContext(ctx, q)
-					if err != nil {
-						log.Error().Err(err).Msg("failed to prepare statement")
-						_ = tx.Rollback()
-						continue
-					}
-					for range batch {
-						k, err := gen.GenerateString()
+				res.insertTxs(ctx, db, q, batch, insertBatch{
+					row: func(_ *sql.Tx, stmt *sql.Stmt, _ int) bool {
+						k, err := gen.next()
 						if err != nil {
 							res.addErrorCnt(err)
-							continue
+							return false
 						}
-
-						v := []byte("payload")
-						start := time.Now()
-						if _, err := stmt.ExecContext(ctx, k, v); err != nil {
+						v := pl.pick(rnd)
+						res.record(worker, "insert", k, v)
+						start := res.clock.Now()
+						octx, done := res.op(ctx)
+						_, err = stmt.ExecContext(octx, k, v)
+						if err = done(err); err != nil {
 							res.addErrorCnt(err)
-							continue
+							return interrupts(err)
 						}
-						res.addLatency(time.Since(start))
-					}
-					stmt.Close()
-					_ = tx.Commit()
-				}
+						ops.add(res.clock.Since(start))
+						return false
+					},
+					ending: func(_ *sql.Tx, commit bool) {
+						op := "rollback"
+						if commit {
+							op = "commit"
+						}
+						res.record(worker, op, "", nil)
+					},
+					ended: ops.end,
+				})
 			}(w)
 		}
 		wg.Wait()
@@ -76,18 +120,88 @@ func insertWorkload(engine string, batch int) WorkloadFunc {
 	}
 }
 
-func selectWorkload(engine string, keys []string) WorkloadFunc {
-	query := `SELECT v FROM kv WHERE k = ?`
-	if engine == "pgx" {
-		query = `SELECT v FROM kv WHERE k = $1`
+// insertBatch is what a worker of an insert-like workload does within the
+// transactions of Result.insertTxs.
+type insertBatch struct {
+	// row inserts row i of the batch on tx, through stmt when the query is
+	// prepared, counting its own errors and latency, and reports whether
+	// it was interrupted: the batch then ends in a rollback.
+	row func(tx *sql.Tx, stmt *sql.Stmt, i int) (interrupted bool)
+	// ending, if set, runs before the batch commits or rolls back.
+	ending func(tx *sql.Tx, commit bool)
+	// ended, if set, learns whether the batch committed.
+	ended func(committed bool)
+}
+
+// insertTxs is the transaction loop of insert and the workloads built like
+// it. Until the phase ends, it takes a write slot, begins a transaction,
+// prepares q on it (unless q is empty), runs b.row for each of up to batch
+// rows while the phase lasts, and then commits, or rolls back if a row was
+// interrupted. The transaction is not bound to the phase deadline, so a
+// batch the deadline cuts short is still committed or rolled back by us
+// instead of being torn down under its statements.
+func (r *Result) insertTxs(ctx context.Context, db Executor, q string, batch int, b insertBatch) {
+	for !r.stopped() {
+		release, err := r.acquireWrite(ctx)
+		if err != nil {
+			return
+		}
+		tx, err := db.BeginTx(context.WithoutCancel(ctx), nil)
+		if err != nil {
+			release()
+			r.addErrorCnt(err)
+			continue
+		}
+		var stmt *sql.Stmt
+		if q != "" {
+			if stmt, err = tx.PrepareContext(ctx, q); err != nil {
+				r.logError(err, "failed to prepare statement")
+				_ = tx.Rollback()
+				release()
+				continue
+			}
+		}
+		interrupted := false
+		for i := range batch {
+			if ctx.Err() != nil {
+				break
+			}
+			if interrupted = b.row(tx, stmt, i); interrupted {
+				break
+			}
+		}
+		if stmt != nil {
+			stmt.Close()
+		}
+		if b.ending != nil {
+			b.ending(tx, !interrupted)
+		}
+		committed := false
+		if interrupted {
+			_ = tx.Rollback()
+		} else {
+			committed = r.commit(tx) == nil
+		}
+		if b.ended != nil {
+			b.ended(committed)
+		}
+		release()
 	}
-	return func(ctx context.Context, db *sql.DB, conc int, dur time.Duration) Result {
-		res := newResult("select", conc, dur)
+}
+
+// interrupts reports whether err, from a statement of a transaction, is a
+// cancellation or timeout, which may leave the transaction unusable.
+func interrupts(err error) bool { return isCanceled(err) || isOpTimeout(err) }
+
+func selectWorkload(dollar bool, keys []string) WorkloadFunc {
+	query := bind(dollar, `SELECT v FROM kv WHERE k = ?`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("select", ph)
 		if len(keys) == 0 {
 			return res.finalize()
 		}
 
-		ctx, cancel := context.WithTimeout(ctx, dur)
+		ctx, cancel := res.start(ctx)
 		defer cancel()
 
 		stmt, err := db.PrepareContext(ctx, query)
@@ -98,25 +212,28 @@ func selectWorkload(engine string, keys []string) WorkloadFunc {
 		defer stmt.Close()
 
 		var wg sync.WaitGroup
-		for w := 0; w < conc; w++ {
+		for w := 0; w < ph.Concurrency; w++ {
 			wg.Add(1)
 			go func(worker int) {
 				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				keys := ph.workerKeys(keys, worker)
 				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
 				for {
-					select {
-					case <-ctx.Done():
+					if res.stopped() {
 						return
-					default:
 					}
 					key := keys[rnd.Intn(len(keys))]
-					start := time.Now()
+					res.record(worker, "select", key, nil)
+					start := res.clock.Now()
 					var v []byte
-					if err := stmt.QueryRowContext(ctx, key).Scan(&v); err != nil {
+					octx, done := res.op(ctx)
+					if err := done(stmt.QueryRowContext(octx, key).Scan(&v)); err != nil {
 						res.addErrorCnt(err) // 원한다면 ErrNoRows는 별도 계수
 						continue
 					}
-					res.addLatency(time.Since(start))
+					res.addLatency(worker, res.clock.Since(start))
 				}
 			}(w)
 		}
@@ -125,18 +242,15 @@ func selectWorkload(engine string, keys []string) WorkloadFunc {
 	}
 }
 
-func rangeWorkload(engine string, keys []string, limit int) WorkloadFunc {
-	query := `SELECT k,v FROM kv WHERE k BETWEEN ? AND ? LIMIT ?`
-	if engine == "pgx" {
-		query = `SELECT k,v FROM kv WHERE k BETWEEN $1 AND $2 LIMIT $3`
-	}
-	return func(ctx context.Context, db *sql.DB, conc int, dur time.Duration) Result {
-		res := newResult("range", conc, dur)
+func rangeWorkload(dollar bool, keys []string, limit int) WorkloadFunc {
+	query := bind(dollar, `SELECT k,v FROM kv WHERE k BETWEEN ? AND ? LIMIT ?`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("range", ph)
 		if len(keys) < 2 {
 			return res.finalize()
 		}
 
-		ctx, cancel := context.WithTimeout(ctx, dur)
+		ctx, cancel := res.start(ctx)
 		defer cancel()
 
 		stmt, err := db.PrepareContext(ctx, query)
@@ -147,16 +261,17 @@ func rangeWorkload(engine string, keys []string, limit int) WorkloadFunc {
 		defer stmt.Close()
 
 		var wg sync.WaitGroup
-		for w := 0; w < conc; w++ {
+		for w := 0; w < ph.Concurrency; w++ {
 			wg.Add(1)
 			go func(worker int) {
 				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				keys := ph.workerKeys(keys, worker)
 				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
 				for {
-					select {
-					case <-ctx.Done():
+					if res.stopped() {
 						return
-					default:
 					}
 					a := keys[rnd.Intn(len(keys))]
 					b := keys[rnd.Intn(len(keys))]
@@ -164,20 +279,29 @@ func rangeWorkload(engine string, keys []string, limit int) WorkloadFunc {
 					if lo > hi {
 						lo, hi = hi, lo
 					}
+					res.recordRange(worker, lo, hi, limit)
 
-					start := time.Now()
-					rows, err := stmt.QueryContext(ctx, lo, hi, limit)
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					rows, err := stmt.QueryContext(octx, lo, hi, limit)
 					if err != nil {
-						res.addErrorCnt(err)
+						res.addErrorCnt(done(err))
 						continue
 					}
-					for rows.Next() {
+					for first := true; rows.Next(); first = false {
+						if first {
+							res.addFirstRow(res.clock.Since(start))
+						}
 						var k string
 						var v []byte
 						_ = rows.Scan(&k, &v)
 					}
 					_ = rows.Close()
-					res.addLatency(time.Since(start))
+					if err := done(rows.Err()); err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
 				}
 			}(w)
 		}
@@ -186,18 +310,15 @@ func rangeWorkload(engine string, keys []string, limit int) WorkloadFunc {
 	}
 }
 
-func updateWorkload(engine string, keys []string) WorkloadFunc {
-	q := `UPDATE kv SET v = ? WHERE k = ?`
-	if engine == "pgx" {
-		q = `UPDATE kv SET v = $1 WHERE k = $2`
-	}
-	return func(ctx context.Context, db *sql.DB, conc int, dur time.Duration) Result {
-		res := newResult("update", conc, dur)
+func updateWorkload(dollar bool, keys []string, pl payloads) WorkloadFunc {
+	q := bind(dollar, `UPDATE kv SET v = ? WHERE k = ?`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("update", ph)
 		if len(keys) == 0 {
 			return res.finalize()
 		}
 
-		ctx, cancel := context.WithTimeout(ctx, dur)
+		ctx, cancel := res.start(ctx)
 		defer cancel()
 
 		stmtUpd, err := db.PrepareContext(ctx, q)
@@ -208,24 +329,34 @@ func updateWorkload(engine string, keys []string) WorkloadFunc {
 		defer stmtUpd.Close()
 
 		var wg sync.WaitGroup
-		for w := 0; w < conc; w++ {
+		for w := 0; w < ph.Concurrency; w++ {
 			wg.Add(1)
 			go func(worker int) {
 				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				keys := ph.workerKeys(keys, worker)
 				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
 				for {
-					select {
-					case <-ctx.Done():
+					if res.stopped() {
 						return
-					default:
 					}
-					k := keys[rnd.Intn(len(keys))]
-					start := time.Now()
-					if _, err := stmtUpd.ExecContext(ctx, []byte("updated"), k); err != nil {
+					k, v := keys[rnd.Intn(len(keys))], pl.pick(rnd)
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					res.record(worker, "update", k, v)
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					_, err = stmtUpd.ExecContext(octx, v, k)
+					err = done(err)
+					release()
+					if err != nil {
 						res.addErrorCnt(err)
 						continue
 					}
-					res.addLatency(time.Since(start))
+					res.addLatency(worker, res.clock.Since(start))
 				}
 			}(w)
 		}
@@ -234,18 +365,15 @@ func updateWorkload(engine string, keys []string) WorkloadFunc {
 	}
 }
 
-func deleteWorkload(engine string, keys []string) WorkloadFunc {
-	q := `DELETE FROM kv WHERE k = ?`
-	if engine == "pgx" {
-		q = `DELETE FROM kv WHERE k = $1`
-	}
-	return func(ctx context.Context, db *sql.DB, conc int, dur time.Duration) Result {
-		res := newResult("delete", conc, dur)
+func deleteWorkload(dollar bool, keys []string) WorkloadFunc {
+	q := bind(dollar, `DELETE FROM kv WHERE k = ?`)
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult("delete", ph)
 		if len(keys) == 0 {
 			return res.finalize()
 		}
 
-		ctx, cancel := context.WithTimeout(ctx, dur)
+		ctx, cancel := res.start(ctx)
 		defer cancel()
 
 		stmtDel, err := db.PrepareContext(ctx, q)
@@ -256,24 +384,38 @@ func deleteWorkload(engine string, keys []string) WorkloadFunc {
 		defer stmtDel.Close()
 
 		var wg sync.WaitGroup
-		for w := 0; w < conc; w++ {
+		for w := 0; w < ph.Concurrency; w++ {
 			wg.Add(1)
 			go func(worker int) {
 				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				keys := ph.workerKeys(keys, worker)
 				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
 				for {
-					select {
-					case <-ctx.Done():
+					if res.stopped() {
 						return
-					default:
 					}
 					k := keys[rnd.Intn(len(keys))]
-					start := time.Now()
-					if _, err := stmtDel.ExecContext(ctx, k); err != nil {
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					res.record(worker, "delete", k, nil)
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					r, err := stmtDel.ExecContext(octx, k)
+					err = done(err)
+					release()
+					if err != nil {
 						res.addErrorCnt(err)
 						continue
 					}
-					res.addLatency(time.Since(start))
+					res.addLatency(worker, res.clock.Since(start))
+					res.addDeleted(k)
+					if n, err := r.RowsAffected(); err == nil {
+						res.addRowsDeleted(n)
+					}
 				}
 			}(w)
 		}
@@ -281,7 +423,7 @@ func deleteWorkload(engine string, keys []string) WorkloadFunc {
 		return res.finalize()
 	}
 }
-func FetchKeySnapshot(ctx context.Context, db *sql.DB, engine string, n int) ([]string, error) {
+func FetchKeySnapshot(ctx context.Context, db Executor, engine string, n int) ([]string, error) {
 	q := fmt.Sprintf(`SELECT k FROM kv ORDER BY k DESC LIMIT %d`, n)
 
 	rows, err := db.QueryContext(ctx, q)
@@ -307,3 +449,225 @@ func FetchKeySnapshot(ctx context.Context, db *sql.DB, engine string, n int) ([]
 	}
 	return keys, nil
 }
+
+// contentionWorkload runs writers that each hold a dedicated connection and
+// insert batch rows per transaction, so they fight over the engine's write
+// lock. On sqlite every connection gets busyTimeout, which decides whether a
+// blocked writer waits or fails with SQLITE_BUSY; the error kinds of the
+// result show which of busy/locked actually happened.
+func contentionWorkload(engine string, dollar bool, name string, batch int, busyTimeout time.Duration, kg keyGens, pl payloads) WorkloadFunc {
+	q := bind(dollar, `INSERT INTO kv(k, v) VALUES(?, ?)`)
+
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				gen, err := kg(worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				conn, err := db.Conn(ctx)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				defer conn.Close()
+
+				if engine == "sqlite" {
+					var prev int64
+					if err := conn.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&prev); err != nil {
+						res.addErrorCnt(err)
+						return
+					}
+					// the connection goes back to the pool; leave it as we found it
+					defer conn.ExecContext(context.Background(), fmt.Sprintf(`PRAGMA busy_timeout = %d`, prev))
+					if _, err := conn.ExecContext(ctx, fmt.Sprintf(`PRAGMA busy_timeout = %d`, busyTimeout.Milliseconds())); err != nil {
+						res.addErrorCnt(err)
+						return
+					}
+				}
+
+				for {
+					if res.stopped() {
+						return
+					}
+					release, err := res.acquireWrite(ctx)
+					if err != nil {
+						return
+					}
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					err = done(contendedTx(octx, conn, q, gen, batch, func() []byte { return pl.pick(rnd) }))
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+					res.addInserted(int64(batch))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+func contendedTx(ctx context.Context, conn *sql.Conn, q string, gen keyGen, batch int, value func() []byte) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for range batch {
+		k, err := gen.next()
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, q, k, value()); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// readQuery is a parameterized read; args draws its parameters.
+type readQuery struct {
+	q    string
+	args func(rnd *rand.Rand) []any
+}
+
+// readWorkload runs a randomly chosen query from queries per operation and
+// drains its result set, counting the rows returned.
+func readWorkload(name string, dollar bool, queries []readQuery) WorkloadFunc {
+	qs := make([]readQuery, len(queries))
+	for i, r := range queries {
+		qs[i] = readQuery{bind(dollar, r.q), r.args}
+	}
+	return func(ctx context.Context, db Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := 0; w < ph.Concurrency; w++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					r := qs[rnd.Intn(len(qs))]
+					start := res.clock.Now()
+					octx, done := res.op(ctx)
+					rows, err := db.QueryContext(octx, r.q, r.args(rnd)...)
+					if err != nil {
+						res.addErrorCnt(done(err))
+						continue
+					}
+					n, err := drainRows(rows, func() { res.addFirstRow(res.clock.Since(start)) })
+					if err = done(err); err != nil {
+						res.addErrorCnt(err)
+						continue
+					}
+					res.addLatency(worker, res.clock.Since(start))
+					res.addRows(n)
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
+
+// drainRows scans every row of rows into generic destinations and closes
+// it. first, if not nil, is called when the first row arrives.
+func drainRows(rows *sql.Rows, first func()) (int64, error) {
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	vals := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	var n int64
+	for rows.Next() {
+		if n == 0 && first != nil {
+			first()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// opFunc is one worker iteration of a workload that bypasses database/sql;
+// it times each operation it performs on clk, the phase clock, and reports
+// the latency through observe.
+type opFunc func(ctx context.Context, rnd *rand.Rand, clk Clock, observe func(time.Duration)) error
+
+// opWorkload runs the op newOp builds for each worker until the phase
+// ends. Write ops go through the phase's write limiter.
+func opWorkload(name string, write bool, newOp func(ctx context.Context, worker int) (opFunc, error)) WorkloadFunc {
+	return func(ctx context.Context, _ Executor, ph Phase) Result {
+		res := newResult(name, ph)
+		ctx, cancel := res.start(ctx)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for w := range ph.Concurrency {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				defer res.recoverWorker()
+				pinWorker(ph.CPUSet)
+				op, err := newOp(ctx, worker)
+				if err != nil {
+					res.addErrorCnt(err)
+					return
+				}
+				observe := func(d time.Duration) { res.addLatency(worker, d) }
+				rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(worker)))
+				for {
+					if res.stopped() {
+						return
+					}
+					release := func() {}
+					if write {
+						if release, err = res.acquireWrite(ctx); err != nil {
+							return
+						}
+					}
+					octx, done := res.op(ctx)
+					err := done(op(octx, rnd, res.clock, observe))
+					release()
+					if err != nil {
+						res.addErrorCnt(err)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		return res.finalize()
+	}
+}
@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestInsertStopsAtDeadline runs the insert workload on statements so slow
+// that the phase deadline always cuts a batch short.
+func TestInsertStopsAtDeadline(t *testing.T) {
+	const (
+		duration = 100 * time.Millisecond
+		latency  = 80 * time.Millisecond
+		slack    = 10 * time.Millisecond // scheduling, and the deadline is set a little after start
+	)
+	type event struct {
+		query string
+		at    time.Duration
+	}
+	var (
+		mu     sync.Mutex
+		events []event
+		start  = time.Now()
+	)
+	f := &fakeExecutor{Latency: latency, Trace: func(q string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event{q, time.Since(start)})
+	}}
+	kg, err := newKeyGens("seq", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl, err := newPayloads("fixed", 0, 0, "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := insertWorkload(false, 4, kg, pl)(context.Background(), newFakeExecutor(f), Phase{Concurrency: 1, Duration: duration})
+
+	if len(events) == 0 {
+		t.Fatal("no statements ran")
+	}
+	ends := 0
+	for i, e := range events {
+		switch {
+		case strings.HasPrefix(e.query, "INSERT"):
+			if e.at > duration+slack {
+				t.Errorf("INSERT started %s into a %s phase", e.at, duration)
+			}
+			ends = 0
+		case e.query == "COMMIT" || e.query == "ROLLBACK":
+			if ends++; ends > 1 {
+				t.Errorf("statement %d: %s ends a batch already ended", i, e.query)
+			}
+		default:
+			t.Errorf("unexpected statement %q", e.query)
+		}
+	}
+	if last := events[len(events)-1].query; last != "COMMIT" && last != "ROLLBACK" {
+		t.Errorf("the last batch ends in %s, not COMMIT or ROLLBACK", last)
+	}
+	if res.Errors != 0 {
+		t.Errorf("Errors = %d, want 0 (error kinds %v)", res.Errors, res.ErrorKinds)
+	}
+	if res.Ops == 0 {
+		t.Error("no insert completed before the deadline")
+	}
+}
@@ -2,9 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gosuda/chaisql-benchmark/bench"
@@ -13,6 +23,7 @@ import (
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/knadh/koanf/v2"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/pflag"
 )
@@ -20,14 +31,127 @@ import (
 var k = koanf.New(".")
 
 func main() {
-	mustSetDefault("engine", "chai") // chai|sqlite|pgx
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		compare(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "charts" {
+		charts(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "probe" {
+		probe(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importLog(os.Args[2:])
+		return
+	}
+
+	mustSetDefault("engine", "chai") // chai|chai-native|badger|pebble|bbolt|sqlite|pgx|mariadb|tidb|clickhouse|generic
 	mustSetDefault("dsn", "")        // auto-fill by engine if empty
+	mustSetDefault("read_dsn", "")   // replica serving the read-only phases; empty = DSN
+	mustSetDefault("engines", "")    // several engines, one suite each on its dsns.<engine>; overrides engine
+	mustSetDefault("parallel", false)
+	// TLS and credentials of the pgx, mariadb and tidb engines, overriding the DSN
+	mustSetDefault("tls_ca", "")
+	mustSetDefault("tls_cert", "")
+	mustSetDefault("tls_key", "")
+	mustSetDefault("tls_skip_verify", false)
+	mustSetDefault("db_user", "")
+	mustSetDefault("db_password_file", "")
+	mustSetDefault("db_password_env", "")
 	mustSetDefault("concurrency", 1)
 	mustSetDefault("warmup", "5s")    // duration string
 	mustSetDefault("duration", "20s") // duration string
 	mustSetDefault("tx_batch", 1)
+	mustSetDefault("drop_failed_tx", false)
+	mustSetDefault("op_timeout", "0s") // per statement/transaction; 0 = none
+	mustSetDefault("trim", "0s")       // start of each phase left out of the trimmed percentiles
+	mustSetDefault("min_samples", 0)
+	mustSetDefault("min_samples_max", "0s")
 	mustSetDefault("rows", 10000)
+	mustSetDefault("sort_limit", 100)
+	mustSetDefault("in_keys", 10)
+	mustSetDefault("stream_rows", 0)      // rows per stream query; 0 = all of kv
+	mustSetDefault("stream_pause", "1ms") // consumer pause every 1000 streamed rows
+	mustSetDefault("mixed_read_pct", 90)
+	mustSetDefault("partition_keys", false)
+	mustSetDefault("rollback_pct", 50)
+	mustSetDefault("conflict_pct", 10)
+	mustSetDefault("hot_pct", 50)
+	mustSetDefault("hot_keys", 1)
+	mustSetDefault("cancel_pct", 50)
+	mustSetDefault("cancel_after", "5ms")
+	mustSetDefault("stmt_shapes", 1000) // above pgx's default statement cache (512)
+	mustSetDefault("longtx_mode", "idle")
+	mustSetDefault("snapshot_isolation", "default")
+	mustSetDefault("write_limit", 0)     // e.g. 1 for engines that serialize writers anyway
+	mustSetDefault("group_commit", "0s") // insert flush interval; 0 commits per worker
+	mustSetDefault("samples", 0)         // raw latencies kept per phase in JSON, for compare
+	mustSetDefault("width", 80)          // pretty output width
+	mustSetDefault("ascii", false)
+	mustSetDefault("cdf", false)    // plot latency CDFs in pretty output
+	mustSetDefault("color", "auto") // auto|always|never
+	mustSetDefault("log_level", "info")
+	mustSetDefault("log_format", "json") // json|console
+	mustSetDefault("events", "")         // JSONL lifecycle event log; empty disables
+	mustSetDefault("record", "")         // operation log of the kv workloads, for --replay
+	mustSetDefault("replay", "")         // operation log run instead of the workloads
+	mustSetDefault("out_dir", "")        // base of per-run artifact directories; empty disables
+	mustSetDefault("pgx_native", false)
+	mustSetDefault("driver", "")           // generic engine only
+	mustSetDefault("placeholder", "qmark") // generic engine only: qmark|dollar
+	mustSetDefault("pprof", "")            // per-phase profile dir; empty disables
+	mustSetDefault("pprof_addr", "localhost:6060")
+	mustSetDefault("gomaxprocs", 0)         // 0 keeps the runtime default
+	mustSetDefault("cpuset", "")            // e.g. "0-3,6" (Linux only)
+	mustSetDefault("format", "pretty")      // pretty|markdown|json|csv|openmetrics
+	mustSetDefault("openmetrics", "")       // OpenMetrics histogram file; empty disables
+	mustSetDefault("upload_url", "")        // results server the JSON report is POSTed to; empty disables
+	mustSetDefault("abort_error_rate", 0.0) // percent; 0 disables the error budget
+	mustSetDefault("abort_window", "5s")
+	mustSetDefault("stall_ratio", 0.1)   // warn when ops/s stays below this share of the rolling average...
+	mustSetDefault("stall_hold", "5s")   // ...for this long; 0 disables
+	mustSetDefault("abort_suite", false) // stop the suite instead of moving to the next phase
+	mustSetDefault("max_runtime", "0s")  // bound on the whole suite; 0 = none
+	mustSetDefault("mem_limit_mb", 0)    // resident memory past which the suite stops; 0 = none
+	mustSetDefault("tune", "")           // concurrency or tx-batch: search that setting instead of a plain run
+	mustSetDefault("tune_p99", "0s")     // p99 a tuned setting must stay within; 0 = no cap
+	mustSetDefault("health_check", true) // row count (+ sqlite quick_check) after every phase
+	mustSetDefault("tune_max_concurrency", 256)
+	mustSetDefault("fingerprint_disk", true)
+	mustSetDefault("calibrate_disk", false)
+	mustSetDefault("calibrate_dir", "")
+	mustSetDefault("noise_floor", true)
+	mustSetDefault("timing", "wall")
+	// IO throttle: fsync delay (pebble) and cgroup v2 io.max limits (Linux); 0 = none
+	mustSetDefault("fsync_delay", "0s")
+	mustSetDefault("io_read_mbps", 0)
+	mustSetDefault("io_write_mbps", 0)
+	mustSetDefault("io_read_iops", 0)
+	mustSetDefault("io_write_iops", 0)
+	mustSetDefault("tune_tx_batches", []string{"1", "10", "100", "1000"})
+	mustSetDefault("workloads", bench.DefaultWorkloads)
+	mustSetDefault("constraint_variants", bench.ConstraintVariants)
+	mustSetDefault("busy_timeouts", []string{"0s", "100ms", "1s"}) // sqlite contention sweep
+	mustSetDefault("delete_strategies", []string{"key"})           // key|range|all|drop, one delete phase each
+	mustSetDefault("tpcb_scale", 1)                                // pgbench -s
+	mustSetDefault("dataset", "")                                  // CSV/JSONL file loaded before the suite
+	mustSetDefault("dataset_format", "")                           // csv|jsonl; from the extension if empty
+	mustSetDefault("dataset_table", "kv")
+	mustSetDefault("dataset_columns", []string{"k", "v"})
+	mustSetDefault("dataset_fields", []string{}) // source fields per column; default = columns
+	mustSetDefault("dataset_limit", 0)
+	mustSetDefault("payload", "fixed") // fixed|faker
+	mustSetDefault("payload_cardinality", 1000)
+	mustSetDefault("value_size", 0)
+	mustSetDefault("key_gen", "randflake") // randflake|uuidv7|seq|hex
+	mustSetDefault("randflake_secret", bench.DefaultRandflakeSecret)
 	mustSetDefault("config", "config.yaml") // config file path
+	mustSetDefault("profile", "")           // profiles.<name> applied over the config file's base keys
+	mustSetDefault("matrix", "")            // matrices.<name> to run instead of a single suite
+	mustSetDefault("matrix_state", "")      // progress file of --matrix, resumed if it exists
 
 	cfgPath := k.String("config")
 	if _, err := os.Stat(cfgPath); err == nil {
@@ -36,43 +160,196 @@ func main() {
 		}
 	}
 
-	if err := k.Load(env.Provider("CHB_", ".", func(s string) string {
-		return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(s, "CHB_")), "_", ".")
-	}), nil); err != nil {
-		log.Fatal().Err(err).Msg("failed to load env")
+	loadEnv := func() {
+		if err := k.Load(env.Provider("CHB_", ".", func(s string) string {
+			return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(s, "CHB_")), "_", ".")
+		}), nil); err != nil {
+			log.Fatal().Err(err).Msg("failed to load env")
+		}
 	}
+	loadEnv()
 
 	fs := pflag.NewFlagSet(os.Args[0], pflag.ContinueOnError)
 	fs.String("config", k.String("config"), "config file path (yaml)")
-	fs.String("engine", k.String("engine"), "chai|sqlite|pgx")
+	fs.String("profile", k.String("profile"), "named profile from the config file's profiles section, e.g. quick|soak|ci")
+	fs.String("matrix", k.String("matrix"), "run the experiment matrices.<name> of the config file: the suite once per combination of its dimensions")
+	fs.String("matrix-state", k.String("matrix_state"), "save --matrix progress to this file after every combination; an existing one is resumed, skipping the combinations it completed")
+	fs.String("engine", k.String("engine"), "chai|chai-native|badger|pebble|bbolt|sqlite|pgx|mariadb|tidb|clickhouse|generic (aliases: chaisql, sqlite3, postgres, ...)")
 	fs.String("dsn", k.String("dsn"), "database DSN")
+	fs.String("read-dsn", k.String("read_dsn"), "DSN of a replica the read-only phases (select, range, ...) run on; empty runs them on --dsn")
+	fs.String("engines", k.String("engines"), "comma-separated engines to run the suite on in turn, each on its dsns.<engine> or default DSN; overrides --engine")
+	fs.Bool("parallel", k.Bool("parallel"), "run --engines at the same time on disjoint CPUs (independent databases only; results may interfere)")
+	fs.String("tls-ca", k.String("tls_ca"), "PEM CA bundle verifying the server certificate (pgx, mariadb, tidb)")
+	fs.String("tls-cert", k.String("tls_cert"), "PEM client certificate for mutual TLS, with --tls-key")
+	fs.String("tls-key", k.String("tls_key"), "PEM private key of --tls-cert")
+	fs.Bool("tls-skip-verify", k.Bool("tls_skip_verify"), "use TLS without verifying the server certificate")
+	fs.String("db-user", k.String("db_user"), "database user, overriding the DSN's")
+	fs.String("db-password-file", k.String("db_password_file"), "file holding the database password, overriding the DSN's")
+	fs.String("db-password-env", k.String("db_password_env"), "environment variable holding the database password, overriding the DSN's")
 	fs.Int("concurrency", k.Int("concurrency"), "number of workers")
 	fs.String("warmup", k.String("warmup"), "warmup duration (e.g. 10s)")
 	fs.String("duration", k.String("duration"), "measurement duration (e.g. 30s)")
 	fs.Int("tx-batch", k.Int("tx_batch"), "rows per transaction for write workload")
-	fs.Int("rows", k.Int("rows"), "dataset size for kv table")
+	fs.Bool("drop-failed-tx", k.Bool("drop_failed_tx"), "leave the statements of insert transactions that failed to commit out of ops")
+	fs.String("op-timeout", k.String("op_timeout"), "deadline for each operation (e.g. 500ms); operations past it count as timeouts (0 = none)")
+	fs.String("trim", k.String("trim"), "also report percentiles without the first seconds of each measured phase, to check the warmup (e.g. 5s; 0 = off)")
+	fs.Int("min-samples", k.Int("min_samples"), "keep each measured phase running past --duration until it has this many operations (0 = off)")
+	fs.String("min-samples-max", k.String("min_samples_max"), "longest a phase extended by --min-samples runs in all (0 = 5 × --duration)")
+	fs.Int("rows", k.Int("rows"), "rows in generated tables (types, filter, sort, update-wide and doc workloads)")
+	fs.Int("sort-limit", k.Int("sort_limit"), "rows fetched per ORDER BY query in the sort workload")
+	fs.Int("in-keys", k.Int("in_keys"), "keys per WHERE k IN (...) query in the select-in workload")
+	fs.Int("stream-rows", k.Int("stream_rows"), "rows per query of the stream workload (0 = all of kv)")
+	fs.String("stream-pause", k.String("stream_pause"), "stream workload consumer pause every 1000 rows")
+	fs.Int("mixed-read-pct", k.Int("mixed_read_pct"), "percentage of reads in the mixed workload (the rest are updates)")
+	fs.Bool("partition-keys", k.Bool("partition_keys"), "give every worker of the select, range, update, delete and mixed workloads a disjoint key range instead of sharing all keys")
+	fs.Int("rollback-pct", k.Int("rollback_pct"), "percentage of the rollback workload's transactions rolled back")
+	fs.Int("conflict-pct", k.Int("conflict_pct"), "percentage of the conflict workload's inserts that reuse an existing key")
+	fs.Int("hot-pct", k.Int("hot_pct"), "percentage of the hotspot workload's updates that go to the hot keys")
+	fs.Int("hot-keys", k.Int("hot_keys"), "number of hot keys in the hotspot workload")
+	fs.Int("cancel-pct", k.Int("cancel_pct"), "percentage of the cancel workload's queries canceled through their context")
+	fs.String("cancel-after", k.String("cancel_after"), "cancel workload: cancel after a random delay below this (e.g. 5ms)")
+	fs.Int("stmt-shapes", k.Int("stmt_shapes"), "distinct query shapes cycled by the stmtcache workload")
+	fs.String("longtx-mode", k.String("longtx_mode"), "what the longtx workload's open transaction does: idle|scan")
+	fs.Int("write-limit", k.Int("write_limit"), "max simultaneous write operations across workers (0 = unlimited)")
+	fs.String("group-commit", k.String("group_commit"), "group-commit flush interval for the insert workload, e.g. 2ms (0 = off)")
+	fs.Int("samples", k.Int("samples"), "raw latency samples kept per phase in JSON output, for compare (0 = none)")
+	fs.Int("width", k.Int("width"), "pretty output width (scales histogram and heatmap)")
+	fs.Bool("ascii", k.Bool("ascii"), "pretty output without Unicode box-drawing/sparkline runes")
+	fs.Bool("cdf", k.Bool("cdf"), "plot each phase's latency CDF in pretty output")
+	fs.String("color", k.String("color"), "color pretty output: auto|always|never")
+	fs.Bool("no-color", false, "same as --color=never")
+	fs.String("log-level", k.String("log_level"), "trace|debug|info|warn|error (debug logs sampled per-operation errors)")
+	fs.String("log-format", k.String("log_format"), "log output: json|console")
+	fs.String("events", k.String("events"), "append run lifecycle events (phase start/end, aborts, ...) to this JSONL file")
+	fs.String("record", k.String("record"), "write the operations of the insert, select, range, update and delete workloads to this JSONL file")
+	fs.String("replay", k.String("replay"), "run the operations of a --record file, as recorded, instead of --workloads")
+	fs.String("out-dir", k.String("out_dir"), "write report, raw latencies, log, events, profiles and effective config to a new timestamped directory under this one")
+	fs.StringArray("tag", nil, "label the run, key=value (repeatable; adds to tags from the config file)")
+	fs.Bool("pgx-native", k.Bool("pgx_native"), "pgx only: run kv workloads on native pgx connections, reported as engine pgx-native")
+	fs.String("driver", k.String("driver"), "generic only: registered database/sql driver to benchmark")
+	fs.String("placeholder", k.String("placeholder"), "generic only: parameter style of the driver: qmark|dollar")
+	fs.String("snapshot-isolation", k.String("snapshot_isolation"), "snapshot reader isolation: default|read-committed|repeatable-read|snapshot|serializable")
+	fs.String("pprof", k.String("pprof"), "directory for per-phase CPU/heap profiles (also serves net/http/pprof)")
+	fs.Int("gomaxprocs", k.Int("gomaxprocs"), "GOMAXPROCS override (0 = runtime default)")
+	fs.String("cpuset", k.String("cpuset"), "pin workers to CPUs, e.g. 0-3,6 (Linux only)")
+	fs.String("format", k.String("format"), "output format: pretty|markdown|json|csv|openmetrics")
+	fs.String("openmetrics", k.String("openmetrics"), "also write the latency histograms in OpenMetrics text format to this file")
+	fs.String("upload-url", k.String("upload_url"), "also POST the JSON report to this results server endpoint (bearer token from $"+uploadTokenEnv+")")
+	fs.Float64("abort-error-rate", k.Float64("abort_error_rate"), "abort a phase when its error rate exceeds this percent (0 = never)")
+	fs.String("abort-window", k.String("abort_window"), "sliding window for --abort-error-rate")
+	fs.Float64("stall-ratio", k.Float64("stall_ratio"), "flag a stall when ops/s falls below this share of the rolling average (0 = never)")
+	fs.String("stall-hold", k.String("stall_hold"), "how long ops/s must stay below --stall-ratio to count as a stall")
+	fs.Bool("abort-suite", k.Bool("abort_suite"), "stop the whole suite when a phase is aborted")
+	fs.Bool("health-check", k.Bool("health_check"), "count kv rows (and run sqlite's quick_check) after every phase")
+	fs.Bool("fingerprint-disk", k.Bool("fingerprint_disk"), "time fsyncs and a 32 MiB write on the data path's disk (~1s) for the machine fingerprint")
+	fs.Bool("calibrate-disk", k.Bool("calibrate_disk"), "before the suite, measure raw fsync and O_DIRECT write latency in --calibrate-dir and report it")
+	fs.String("calibrate-dir", k.String("calibrate_dir"), "directory --calibrate-disk measures (default: the data path's; set it for server engines)")
+	fs.Bool("noise-floor", k.Bool("noise_floor"), "time the harness against a stub executor for 1s first and report latencies net of its overhead")
+	fs.String("timing", k.String("timing"), "operation clock: wall (time.Now), mono (monotonic clock only, cheaper) or coarse (100µs resolution, cheapest)")
+	fs.String("max-runtime", k.String("max_runtime"), "stop the whole suite (preload and warmups included) after this long and report what finished (0 = never)")
+	fs.Int("mem-limit-mb", k.Int("mem_limit_mb"), "abort the running phase and stop the suite once the process's resident memory exceeds this many MiB (0 = no limit)")
+	fs.String("fsync-delay", k.String("fsync_delay"), "delay added to every fsync of the pebble engine (e.g. 2ms)")
+	fs.Int("io-read-mbps", k.Int("io_read_mbps"), "cgroup io.max read bandwidth limit in MiB/s on the data path's disk (Linux; 0 = none)")
+	fs.Int("io-write-mbps", k.Int("io_write_mbps"), "cgroup io.max write bandwidth limit in MiB/s on the data path's disk (Linux; 0 = none)")
+	fs.Int("io-read-iops", k.Int("io_read_iops"), "cgroup io.max read IOPS limit on the data path's disk (Linux; 0 = none)")
+	fs.Int("io-write-iops", k.Int("io_write_iops"), "cgroup io.max write IOPS limit on the data path's disk (Linux; 0 = none)")
+	fs.String("tune", k.String("tune"), "search a setting for the single --workloads entry instead of running it once: concurrency|tx-batch")
+	fs.String("tune-p99", k.String("tune_p99"), "p99 latency a tuned setting must stay within (0 = no cap)")
+	fs.Int("tune-max-concurrency", k.Int("tune_max_concurrency"), "highest concurrency --tune=concurrency tries")
+	fs.StringSlice("tune-tx-batches", listOf("tune_tx_batches"), "tx_batch values --tune=tx-batch tries")
+	fs.StringSlice("workloads", listOf("workloads"), "workloads to run, in order (see README)")
+	fs.StringSlice("busy-timeouts", listOf("busy_timeouts"), "sqlite busy_timeout values for the contention workload")
+	fs.StringSlice("delete-strategies", listOf("delete_strategies"), "delete phases to run, in order: key|range|all|drop")
+	fs.StringSlice("constraint-variants", listOf("constraint_variants"), "constraints experiment variants, each measured against a plain table: check|trigger")
+	fs.Int("tpcb-scale", k.Int("tpcb_scale"), "pgbench scale factor for the tpcb workload")
+	fs.String("dataset", k.String("dataset"), "CSV/JSONL file loaded into the dataset table before the suite")
+	fs.String("dataset-format", k.String("dataset_format"), "csv|jsonl (default: from file extension)")
+	fs.String("dataset-table", k.String("dataset_table"), "table the dataset is loaded into")
+	fs.StringSlice("dataset-columns", listOf("dataset_columns"), "target columns")
+	fs.StringSlice("dataset-fields", listOf("dataset_fields"), "CSV header names / JSON keys feeding the columns")
+	fs.Int("dataset-limit", k.Int("dataset_limit"), "max dataset rows to load (0 = all)")
+	fs.String("payload", k.String("payload"), "value generator for writes: fixed|faker")
+	fs.Int("payload-cardinality", k.Int("payload_cardinality"), "distinct values generated in faker mode")
+	fs.Int("value-size", k.Int("value_size"), "bytes of each written value in fixed mode (the literal repeated); 0 = as is")
+	fs.String("key-gen", k.String("key_gen"), "key generator for inserts: randflake|uuidv7|seq|hex")
+	fs.String("randflake-secret", k.String("randflake_secret"), "16-byte key randflake ids are encrypted with")
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		os.Exit(2)
 	}
-	if err := k.Load(posflag.Provider(fs, ".", k), nil); err != nil {
-		log.Fatal().Err(err).Msg("failed to load flags")
+	// flags are kebab-case, config keys snake_case
+	loadFlags := func() {
+		if err := k.Load(posflag.ProviderWithFlag(fs, ".", k, func(f *pflag.Flag) (string, any) {
+			return strings.ReplaceAll(f.Name, "-", "_"), posflag.FlagVal(fs, f)
+		}), nil); err != nil {
+			log.Fatal().Err(err).Msg("failed to load flags")
+		}
 	}
+	loadFlags()
+	// a profile overrides the config file's top-level keys but not the
+	// environment or flags, so those are applied again on top of it
+	if name := k.String("profile"); name != "" {
+		if err := applyProfile(name, nil); err != nil {
+			log.Fatal().Err(err).Str("profile", name).Msg("failed to apply profile")
+		}
+		loadEnv()
+		loadFlags()
+	}
+	// --out-dir collects everything about the run in a fresh directory
+	var (
+		outDir  string
+		logFile io.Writer
+	)
+	if base := k.String("out_dir"); base != "" {
+		var err error
+		if outDir, err = newOutDir(base); err != nil {
+			log.Fatal().Err(err).Str("out_dir", base).Msg("failed to create output directory")
+		}
+		f, err := os.Create(filepath.Join(outDir, "run.log"))
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create log file")
+		}
+		defer f.Close()
+		logFile = f
+	}
+	setupLogging(k.String("log_level"), k.String("log_format"), logFile)
 
-	engine := k.String("engine")
-	dsn := k.String("dsn")
-	if dsn == "" {
-		switch engine {
-		case "chai":
-			dsn = "./data/chai/chai.db"
-		case "sqlite":
-			dsn = "file:./data/sqlite/sqlite.db?cache=shared&_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)"
-		case "pgx":
-			dsn = "postgres://postgres:pg@127.0.0.1:5432/bench?sslmode=disable"
-		default:
-			log.Fatal().Str("engine", engine).Msg("no default DSN for engine")
+	engines := listOf("engines")
+	for i, e := range engines {
+		e, err := bench.NormalizeEngine(e)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid engine")
+		}
+		engines[i] = e
+	}
+	if len(engines) > 1 || k.String("matrix") != "" {
+		// one DSN or output stream cannot serve them all
+		switch {
+		case k.String("dsn") != "" || k.String("read_dsn") != "":
+			log.Fatal().Msg("--engines takes each engine's DSN from dsns.<engine> in the config, not --dsn or --read-dsn")
+		case k.String("tune") != "":
+			log.Fatal().Msg("--tune takes one engine")
+		case k.String("format") == "openmetrics" || k.String("openmetrics") != "":
+			log.Fatal().Msg("OpenMetrics output takes one engine; use --out-dir for per-engine metrics")
 		}
 	}
+	engineName := k.String("engine")
+	if len(engines) > 0 {
+		engineName = engines[0]
+	}
+	engine, err := bench.NormalizeEngine(engineName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid engine")
+	}
+	dsn, err := engineDSN(engine, k.String("dsn"))
+	if err != nil {
+		log.Fatal().Err(err).Str("engine", engine).Msg("invalid DSN")
+	}
+
+	readDSN, err := expandDSN(k.String("read_dsn"))
+	if err != nil {
+		log.Fatal().Err(err).Str("engine", engine).Msg("invalid read DSN")
+	}
 
 	warmup, err := time.ParseDuration(k.String("warmup"))
 	if err != nil {
@@ -83,22 +360,480 @@ func main() {
 		log.Fatal().Err(err).Str("duration", k.String("duration")).Msg("invalid duration")
 	}
 
+	abortWindow, err := time.ParseDuration(k.String("abort_window"))
+	if err != nil {
+		log.Fatal().Err(err).Str("abort_window", k.String("abort_window")).Msg("invalid abort window")
+	}
+
+	streamPause, err := time.ParseDuration(k.String("stream_pause"))
+	if err != nil {
+		log.Fatal().Err(err).Str("stream_pause", k.String("stream_pause")).Msg("invalid stream pause")
+	}
+
+	fsyncDelay, err := time.ParseDuration(k.String("fsync_delay"))
+	if err != nil {
+		log.Fatal().Err(err).Str("fsync_delay", k.String("fsync_delay")).Msg("invalid fsync delay")
+	}
+
+	cancelAfter, err := time.ParseDuration(k.String("cancel_after"))
+	if err != nil {
+		log.Fatal().Err(err).Str("cancel_after", k.String("cancel_after")).Msg("invalid cancel delay")
+	}
+
+	maxRuntime, err := time.ParseDuration(k.String("max_runtime"))
+	if err != nil {
+		log.Fatal().Err(err).Str("max_runtime", k.String("max_runtime")).Msg("invalid max runtime")
+	}
+
+	minSamplesMax, err := time.ParseDuration(k.String("min_samples_max"))
+	if err != nil {
+		log.Fatal().Err(err).Str("min_samples_max", k.String("min_samples_max")).Msg("invalid min samples max")
+	}
+
+	stallHold, err := time.ParseDuration(k.String("stall_hold"))
+	if err != nil {
+		log.Fatal().Err(err).Str("stall_hold", k.String("stall_hold")).Msg("invalid stall hold")
+	}
+
+	opTimeout, err := time.ParseDuration(k.String("op_timeout"))
+	if err != nil {
+		log.Fatal().Err(err).Str("op_timeout", k.String("op_timeout")).Msg("invalid op timeout")
+	}
+
+	trim, err := time.ParseDuration(k.String("trim"))
+	if err != nil || trim < 0 {
+		log.Fatal().Err(err).Str("trim", k.String("trim")).Msg("invalid trim duration")
+	}
+
+	groupCommit, err := time.ParseDuration(k.String("group_commit"))
+	if err != nil {
+		log.Fatal().Err(err).Str("group_commit", k.String("group_commit")).Msg("invalid group commit interval")
+	}
+
+	var busyTimeouts []time.Duration
+	for _, s := range listOf("busy_timeouts") {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			log.Fatal().Err(err).Str("busy_timeout", s).Msg("invalid busy timeout")
+		}
+		busyTimeouts = append(busyTimeouts, d)
+	}
+
+	pretty, err := prettyOptions(k.Int("width"), k.Bool("ascii"), k.String("color"), k.Bool("no_color"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid pretty options")
+	}
+	pretty.CDF = k.Bool("cdf")
+
+	format := k.String("format")
+	switch format {
+	case "pretty", "json", "csv", "openmetrics":
+	case "markdown":
+		pretty.Markdown = true
+	default:
+		log.Fatal().Str("format", format).Msg("unknown output format")
+	}
+
+	// --tag adds to (and overrides) the tags map of the config file
+	tags := k.StringMap("tags")
+	for _, t := range k.Strings("tag") {
+		key, val, ok := strings.Cut(t, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			log.Fatal().Str("tag", t).Msg("invalid tag, want key=value")
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	// write_limits.<engine> in the config file overrides write_limit
+	writeLimit := engineWriteLimit(engine)
+
+	// pre_phase_sql.<engine> in the config file; statements may contain
+	// commas, so this is not a listOf list
+	prePhaseSQL := k.Strings("pre_phase_sql." + engine)
+
+	cpus, err := bench.ParseCPUSet(k.String("cpuset"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid cpuset")
+	}
+
+	if pprofDir := k.String("pprof"); pprofDir != "" {
+		addr := k.String("pprof_addr")
+		go func() {
+			log.Info().Str("addr", addr).Str("dir", pprofDir).Msg("pprof enabled")
+			if err := http.ListenAndServe(addr, nil); err != nil {
+				log.Error().Err(err).Str("addr", addr).Msg("pprof server stopped")
+			}
+		}()
+	}
+
+	throttle := bench.IOThrottle{
+		FsyncDelay: fsyncDelay,
+		ReadBPS:    int64(k.Int("io_read_mbps")) << 20,
+		WriteBPS:   int64(k.Int("io_write_mbps")) << 20,
+		ReadIOPS:   int64(k.Int("io_read_iops")),
+		WriteIOPS:  int64(k.Int("io_write_iops")),
+	}
+	conn := bench.ConnOptions{
+		CAFile:       k.String("tls_ca"),
+		CertFile:     k.String("tls_cert"),
+		KeyFile:      k.String("tls_key"),
+		SkipVerify:   k.Bool("tls_skip_verify"),
+		User:         k.String("db_user"),
+		PasswordFile: k.String("db_password_file"),
+		PasswordEnv:  k.String("db_password_env"),
+	}
 	cfg := bench.Config{
-		Engine:      engine,
-		DSN:         dsn,
-		Concurrency: k.Int("concurrency"),
-		Warmup:      warmup,
-		Duration:    dur,
-		TxBatch:     k.Int("tx_batch"),
+		Engine:            engine,
+		DSN:               dsn,
+		ReadDSN:           readDSN,
+		Conn:              conn,
+		Concurrency:       k.Int("concurrency"),
+		Warmup:            warmup,
+		Duration:          dur,
+		TxBatch:           k.Int("tx_batch"),
+		DropFailedTx:      k.Bool("drop_failed_tx"),
+		OpTimeout:         opTimeout,
+		Trim:              trim,
+		StallAlarm:        bench.StallAlarm{Ratio: k.Float64("stall_ratio"), Hold: stallHold},
+		PrePhaseSQL:       prePhaseSQL,
+		PprofDir:          k.String("pprof"),
+		GOMAXPROCS:        k.Int("gomaxprocs"),
+		CPUSet:            cpus,
+		ErrorBudget:       bench.ErrorBudget{MaxRate: k.Float64("abort_error_rate"), Window: abortWindow},
+		AbortSuite:        k.Bool("abort_suite"),
+		MaxRuntime:        maxRuntime,
+		MemLimit:          int64(k.Int("mem_limit_mb")) << 20,
+		IOThrottle:        throttle,
+		MinSamples:        k.Int("min_samples"),
+		MinSamplesMax:     minSamplesMax,
+		HealthCheck:       k.Bool("health_check"),
+		FingerprintDisk:   k.Bool("fingerprint_disk"),
+		CalibrateDisk:     k.Bool("calibrate_disk"),
+		CalibrateDir:      k.String("calibrate_dir"),
+		NoiseFloor:        k.Bool("noise_floor"),
+		Timing:            k.String("timing"),
+		Workloads:         listOf("workloads"),
+		BusyTimeouts:      busyTimeouts,
+		DeleteStrategies:  listOf("delete_strategies"),
+		TpcbScale:         k.Int("tpcb_scale"),
+		Rows:              k.Int("rows"),
+		SortLimit:         k.Int("sort_limit"),
+		InKeys:            k.Int("in_keys"),
+		StreamRows:        k.Int("stream_rows"),
+		StreamPause:       streamPause,
+		MixedReadPct:      k.Int("mixed_read_pct"),
+		PartitionKeys:     k.Bool("partition_keys"),
+		RollbackPct:       k.Int("rollback_pct"),
+		ConflictPct:       k.Int("conflict_pct"),
+		HotPct:            k.Int("hot_pct"),
+		HotKeys:           k.Int("hot_keys"),
+		StmtShapes:        k.Int("stmt_shapes"),
+		LongTxMode:        k.String("longtx_mode"),
+		SnapshotIsolation: k.String("snapshot_isolation"),
+		WriteLimit:        writeLimit,
+		CancelPct:         k.Int("cancel_pct"),
+		CancelAfter:       cancelAfter,
+		GroupCommit:       groupCommit,
+		Samples:           k.Int("samples"),
+		EventLog:          k.String("events"),
+		Record:            k.String("record"),
+		Replay:            k.String("replay"),
+		PgxNative:         k.Bool("pgx_native"),
+		Tags:              tags,
+		Driver:            k.String("driver"),
+		Placeholder:       k.String("placeholder"),
+		Dataset: bench.Dataset{
+			Path:    k.String("dataset"),
+			Format:  k.String("dataset_format"),
+			Table:   k.String("dataset_table"),
+			Columns: listOf("dataset_columns"),
+			Fields:  listOf("dataset_fields"),
+			Limit:   k.Int("dataset_limit"),
+		},
+		Payload:            k.String("payload"),
+		PayloadCardinality: k.Int("payload_cardinality"),
+		ValueSize:          k.Int("value_size"),
+		KeyGen:             k.String("key_gen"),
+		RandflakeSecret:    k.String("randflake_secret"),
+		ConstraintVariants: listOf("constraint_variants"),
 	}
 
-	ctx := context.Background()
-	res, runErr := bench.Run(ctx, cfg)
+	if outDir != "" {
+		if cfg.Samples == 0 {
+			cfg.Samples = outDirSamples
+			_ = k.Set("samples", outDirSamples) // for the effective config
+		}
+		if cfg.EventLog == "" {
+			cfg.EventLog = filepath.Join(outDir, "events.jsonl")
+		}
+		if cfg.PprofDir != "" {
+			cfg.PprofDir = filepath.Join(outDir, "pprof")
+		}
+		if err := writeEffectiveConfig(outDir, dsn); err != nil {
+			log.Fatal().Err(err).Msg("failed to write effective config")
+		}
+	}
+
+	// an interrupt ends the run like --max-runtime, so Run still lifts the
+	// I/O throttle off the cgroup and the reports are written; a second
+	// one exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	if name := k.String("matrix"); name != "" {
+		runMatrix(ctx, cfg, name, format, outDir)
+		return
+	}
+	if len(engines) > 1 {
+		runEngines(ctx, cfg, engines, format, pretty, outDir)
+		return
+	}
+	if mode := k.String("tune"); mode != "" {
+		tune(ctx, cfg, mode, format)
+		return
+	}
+	start := time.Now()
+	rep, runErr := bench.Run(ctx, cfg)
 	if runErr != nil {
-		log.Fatal().Err(runErr).Msg("bench run failed")
+		log.Error().Err(runErr).Msg("bench run failed")
+		fmt.Fprintln(os.Stderr, bench.SummaryLine(nil, time.Since(start), runErr))
+		os.Exit(1)
+	}
+	if path := k.String("openmetrics"); path != "" {
+		if err := writeOpenMetrics(path, rep); err != nil {
+			log.Error().Err(err).Str("path", path).Msg("failed to write openmetrics")
+		}
 	}
-	for _, r := range res {
-		fmt.Println(r.Pretty())
+	if outDir != "" {
+		if err := writeReport(outDir, rep); err != nil {
+			log.Error().Err(err).Str("dir", outDir).Msg("failed to write report")
+		} else {
+			log.Info().Str("dir", outDir).Msg("artifacts written")
+		}
+	}
+	if url := k.String("upload_url"); url != "" {
+		if err := bench.Upload(ctx, url, os.Getenv(uploadTokenEnv), rep); err != nil {
+			log.Error().Err(err).Str("url", url).Msg("failed to upload report")
+		} else {
+			log.Info().Str("url", url).Str("run", rep.RunID).Msg("report uploaded")
+		}
+	}
+	switch format {
+	case "json":
+		fmt.Println(rep.JSON())
+	case "csv":
+		if err := rep.WriteCSV(os.Stdout); err != nil {
+			log.Fatal().Err(err).Msg("failed to write csv")
+		}
+	case "openmetrics":
+		if err := rep.WriteOpenMetrics(os.Stdout); err != nil {
+			log.Fatal().Err(err).Msg("failed to write openmetrics")
+		}
+	default:
+		fmt.Print(rep.PrettyWith(pretty))
+	}
+	// printed last, whatever the output format, for wrapper scripts
+	fmt.Fprintln(os.Stderr, bench.SummaryLine(rep, time.Since(start), nil))
+}
+
+// listOf reads key as either a YAML list or a comma-separated string (as
+// set through the environment).
+func listOf(key string) []string {
+	raw := k.Strings(key)
+	if s, ok := k.Get(key).(string); ok {
+		raw = strings.Split(s, ",")
+	}
+	out := make([]string, 0, len(raw))
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// uploadTokenEnv holds the results server's bearer token for --upload-url.
+// It is read from the environment only, so it never lands in the config
+// (or the effective config.yaml of --out-dir).
+const uploadTokenEnv = "SQLBENCH_UPLOAD_TOKEN"
+
+// engineDSN is the DSN of engine: dsn (--dsn) if set, else dsns.<engine>
+// from the config, else the engine's built-in default.
+func engineDSN(engine, dsn string) (string, error) {
+	if key := "dsns." + engine; dsn == "" && k.Exists(key) {
+		dsn = k.String(key)
+	}
+	dsn, err := expandDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	if dsn == "" {
+		if dsn = defaultDSN(engine); dsn == "" {
+			return "", errors.New("no default DSN for engine")
+		}
+	}
+	return dsn, nil
+}
+
+// engineWriteLimit is write_limits.<engine> from the config, if set, else
+// write_limit.
+func engineWriteLimit(engine string) int {
+	if key := "write_limits." + engine; k.Exists(key) {
+		return k.Int(key)
+	}
+	return k.Int("write_limit")
+}
+
+// forEngine points cfg at cfg.Engine's DSN (see engineDSN) and applies its
+// write limit and pre-phase SQL.
+func forEngine(cfg *bench.Config) error {
+	dsn, err := engineDSN(cfg.Engine, "")
+	if err != nil {
+		return err
+	}
+	cfg.DSN = dsn
+	cfg.WriteLimit = engineWriteLimit(cfg.Engine)
+	cfg.PrePhaseSQL = k.Strings("pre_phase_sql." + cfg.Engine)
+	return nil
+}
+
+// runMatrix runs the experiment matrices.<name> of the config file and
+// prints the consolidated report:
+//
+//	matrices:
+//	  sizes:
+//	    sample: 8 # optional: this many cells at random
+//	    seed: 1
+//	    dims:
+//	      engine: [chai, sqlite]
+//	      concurrency: [1, 16]
+//	      value_size: [64, 4096]
+func runMatrix(ctx context.Context, cfg bench.Config, name, format, outDir string) {
+	key := "matrices." + name
+	if !k.Exists(key + ".dims") {
+		log.Fatal().Str("matrix", name).Msg("unknown matrix or matrix without dims")
+	}
+	m := bench.Matrix{
+		Dims:      make(map[string][]string),
+		Sample:    k.Int(key + ".sample"),
+		Seed:      k.Int64(key + ".seed"),
+		ForEngine: forEngine,
+		State:     k.String("matrix_state"),
+	}
+	for _, d := range k.MapKeys(key + ".dims") {
+		m.Dims[d] = listOf(key + ".dims." + d)
+	}
+
+	start := time.Now()
+	rep, runErr := bench.RunMatrix(ctx, cfg, m)
+	if runErr != nil && rep == nil {
+		log.Fatal().Err(runErr).Str("matrix", name).Msg("cannot run matrix")
+	}
+	failed := 0
+	var reps []*bench.Report
+	for _, c := range rep.Cells {
+		if c.Report == nil {
+			failed++
+			continue
+		}
+		reps = append(reps, c.Report)
+	}
+	if outDir != "" {
+		if err := os.WriteFile(filepath.Join(outDir, "matrix.json"), []byte(rep.JSON()+"\n"), 0o644); err != nil {
+			log.Error().Err(err).Str("dir", outDir).Msg("failed to write matrix report")
+		}
+	}
+	switch format {
+	case "json":
+		fmt.Println(rep.JSON())
+	case "csv":
+		if err := bench.WriteCSVs(os.Stdout, reps); err != nil {
+			log.Fatal().Err(err).Msg("failed to write csv")
+		}
+	default:
+		fmt.Print(rep.Pretty())
+	}
+	fmt.Fprintf(os.Stderr, "matrix %s: %d of %d cells run, %d failed in %s\n", name, len(rep.Cells), rep.Total, failed, time.Since(start).Round(time.Second))
+	if runErr != nil || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runEngines runs the suite of cfg once per engine of --engines, on each
+// engine's own DSN, write limit and pre-phase SQL, and prints the reports
+// one after the other; --out-dir gets a subdirectory per engine.
+func runEngines(ctx context.Context, cfg bench.Config, engines []string, format string, pretty bench.PrettyOptions, outDir string) {
+	cfgs := make([]bench.Config, len(engines))
+	dirs := make([]string, len(engines))
+	for i, e := range engines {
+		c := cfg
+		c.Engine = e
+		if err := forEngine(&c); err != nil {
+			log.Fatal().Err(err).Str("engine", e).Msg("invalid DSN")
+		}
+		dirs[i] = e
+		if slices.Contains(dirs[:i], e) {
+			dirs[i] = fmt.Sprintf("%s-%d", e, i+1)
+		}
+		if c.PprofDir != "" { // profiles are named after the phase
+			c.PprofDir = filepath.Join(c.PprofDir, dirs[i])
+		}
+		if c.Record != "" { // ops.jsonl becomes ops-pgx.jsonl
+			ext := filepath.Ext(c.Record)
+			c.Record = strings.TrimSuffix(c.Record, ext) + "-" + dirs[i] + ext
+		}
+		cfgs[i] = c
+	}
+
+	start := time.Now()
+	reps, runErr := bench.RunEngines(ctx, cfgs, k.Bool("parallel"))
+	if runErr != nil {
+		log.Error().Err(runErr).Msg("bench run failed")
+	}
+	var done []*bench.Report
+	for i, rep := range reps {
+		if rep == nil {
+			continue
+		}
+		done = append(done, rep)
+		if outDir != "" {
+			dir := filepath.Join(outDir, dirs[i])
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				log.Error().Err(err).Str("dir", dir).Msg("failed to write report")
+			} else if err := writeReport(dir, rep); err != nil {
+				log.Error().Err(err).Str("dir", dir).Msg("failed to write report")
+			}
+		}
+		if url := k.String("upload_url"); url != "" {
+			if err := bench.Upload(ctx, url, os.Getenv(uploadTokenEnv), rep); err != nil {
+				log.Error().Err(err).Str("url", url).Str("engine", rep.Meta.Engine).Msg("failed to upload report")
+			}
+		}
+	}
+	switch format {
+	case "json":
+		out := make([]string, len(done))
+		for i, rep := range done {
+			out[i] = rep.JSON()
+		}
+		fmt.Printf("[%s]\n", strings.Join(out, ",\n"))
+	case "csv":
+		if err := bench.WriteCSVs(os.Stdout, done); err != nil {
+			log.Fatal().Err(err).Msg("failed to write csv")
+		}
+	default:
+		for i, rep := range done {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(rep.PrettyWith(pretty))
+		}
+	}
+	for _, rep := range done {
+		fmt.Fprintln(os.Stderr, bench.SummaryLine(rep, time.Since(start), nil))
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, bench.SummaryLine(nil, time.Since(start), runErr))
+		os.Exit(1)
 	}
 }
 
@@ -107,3 +842,306 @@ func mustSetDefault(key string, v any) {
 		_ = k.Set(key, v)
 	}
 }
+
+// compare implements `sqlbench compare [--alpha=0.05] base.json current.json`.
+func compare(args []string) {
+	fs := pflag.NewFlagSet("compare", pflag.ContinueOnError)
+	alpha := fs.Float64("alpha", 0.05, "significance level of the Mann-Whitney U test")
+	width := fs.Int("width", 80, "output width")
+	ascii := fs.Bool("ascii", false, "output without Unicode runes")
+	color := fs.String("color", "auto", "color regressions/improvements: auto|always|never")
+	noColor := fs.Bool("no-color", false, "same as --color=never")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if fs.NArg() != 2 {
+		log.Fatal().Msg("usage: compare [--alpha=0.05] <baseline.json> <current.json>")
+	}
+	var reps [2]*bench.Report
+	for i, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("failed to open report")
+		}
+		reps[i], err = bench.DecodeReport(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("failed to decode report")
+		}
+	}
+	pretty, err := prettyOptions(*width, *ascii, *color, *noColor)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid pretty options")
+	}
+	for i, label := range []string{"Baseline", "Current"} {
+		fmt.Printf("%s\t: %s %s\n", label, reps[i].RunID, reps[i].Meta.TagList())
+	}
+	if diffs := bench.Incomparable(reps[0].Meta.Machine, reps[1].Meta.Machine); len(diffs) > 0 {
+		fmt.Printf("Warning\t\t: measured on different machines (%s); the comparison may reflect hardware, not the change\n", strings.Join(diffs, "; "))
+	}
+	fmt.Println()
+	fmt.Print(bench.ComparePretty(bench.Compare(reps[0], reps[1], *alpha), pretty))
+}
+
+// charts implements `sqlbench charts [--out=charts] report.json...`, writing
+// Vega-Lite specs that draw the reports side by side.
+func charts(args []string) {
+	fs := pflag.NewFlagSet("charts", pflag.ContinueOnError)
+	out := fs.String("out", "charts", "directory the specs are written to")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if fs.NArg() == 0 {
+		log.Fatal().Msg("usage: charts [--out=charts] <report.json>...")
+	}
+	reps := make([]*bench.Report, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("failed to open report")
+		}
+		rep, err := bench.DecodeReport(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatal().Err(err).Str("path", path).Msg("failed to decode report")
+		}
+		reps = append(reps, rep)
+	}
+	paths, err := writeCharts(*out, reps)
+	if err != nil {
+		log.Fatal().Err(err).Str("dir", *out).Msg("failed to write charts")
+	}
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+}
+
+// importLog implements `sqlbench import --from=postgres|sqlite [--phase=trace]
+// [--out=ops.jsonl] <log>`, converting a query log into an operation log
+// for --replay.
+func importLog(args []string) {
+	fs := pflag.NewFlagSet("import", pflag.ContinueOnError)
+	from := fs.String("from", "postgres", "log format: postgres (log_statement=all) or sqlite (.trace output)")
+	phase := fs.String("phase", "trace", "phase name the statements are replayed under")
+	out := fs.String("out", "", "operation log to write (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if fs.NArg() != 1 {
+		log.Fatal().Msg("usage: import --from=postgres|sqlite [--phase=trace] [--out=ops.jsonl] <log>")
+	}
+	var convert func(io.Reader, io.Writer, string) (int, error)
+	switch *from {
+	case "postgres":
+		convert = bench.ImportPostgresLog
+	case "sqlite":
+		convert = bench.ImportSQLiteTrace
+	default:
+		log.Fatal().Str("from", *from).Msg("unknown log format (postgres|sqlite)")
+	}
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatal().Err(err).Str("path", fs.Arg(0)).Msg("failed to open log")
+	}
+	defer in.Close()
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", *out).Msg("failed to create operation log")
+		}
+		defer f.Close()
+		w = f
+	}
+	n, err := convert(in, w, *phase)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", fs.Arg(0)).Msg("failed to import log")
+	}
+	log.Info().Int("statements", n).Str("phase", *phase).Msg("log imported")
+}
+
+// probe prints which optional SQL features an engine supports, by the
+// same probes the suite skips workloads with.
+func probe(args []string) {
+	fs := pflag.NewFlagSet("probe", pflag.ContinueOnError)
+	engine := fs.String("engine", "chai", "engine to probe")
+	dsn := fs.String("dsn", "", "connection string; empty uses the engine's default")
+	driver := fs.String("driver", "", "database/sql driver of the generic engine")
+	placeholder := fs.String("placeholder", "qmark", "generic engine parameter style: qmark|dollar")
+	format := fs.String("format", "pretty", "output format: pretty|json")
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if *format != "pretty" && *format != "json" {
+		log.Fatal().Str("format", *format).Msg("unknown output format")
+	}
+	e, err := bench.NormalizeEngine(*engine)
+	if err != nil {
+		log.Fatal().Err(err).Str("engine", *engine).Msg("unknown engine")
+	}
+	d, err := expandDSN(*dsn)
+	if err != nil {
+		log.Fatal().Err(err).Str("engine", e).Msg("invalid DSN")
+	}
+	if d == "" {
+		if d = defaultDSN(e); d == "" {
+			log.Fatal().Str("engine", e).Msg("no default DSN for engine")
+		}
+	}
+	p, err := bench.Probe(context.Background(), bench.Config{Engine: e, DSN: d, Driver: *driver, Placeholder: *placeholder})
+	if err != nil {
+		log.Fatal().Err(err).Str("engine", e).Msg("probe failed")
+	}
+	if *format == "json" {
+		fmt.Println(p.JSON())
+	} else {
+		fmt.Print(p.Pretty())
+	}
+}
+
+// tune runs the --tune search for cfg and prints its report as pretty or
+// JSON output.
+func tune(ctx context.Context, cfg bench.Config, mode, format string) {
+	p99Cap, err := time.ParseDuration(k.String("tune_p99"))
+	if err != nil {
+		log.Fatal().Err(err).Str("tune_p99", k.String("tune_p99")).Msg("invalid tune p99 cap")
+	}
+	tc := bench.TuneConfig{P99Cap: p99Cap, MaxConcurrency: k.Int("tune_max_concurrency")}
+	for _, s := range listOf("tune_tx_batches") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			log.Fatal().Err(err).Str("tx_batch", s).Msg("invalid tune tx_batch")
+		}
+		tc.TxBatches = append(tc.TxBatches, n)
+	}
+	var rep *bench.TuneReport
+	switch mode {
+	case "concurrency":
+		rep, err = bench.TuneConcurrency(ctx, cfg, tc)
+	case "tx-batch":
+		rep, err = bench.TuneTxBatch(ctx, cfg, tc)
+	default:
+		log.Fatal().Str("tune", mode).Msg("unknown tune mode (concurrency|tx-batch)")
+	}
+	if err != nil {
+		log.Fatal().Err(err).Str("tune", mode).Msg("tuning failed")
+	}
+	if format == "json" {
+		fmt.Println(rep.JSON())
+	} else {
+		fmt.Print(rep.Pretty())
+	}
+}
+
+// defaultDSN is the engine's DSN when neither --dsn nor the config file
+// sets one; "" for engines without a default (generic).
+func defaultDSN(engine string) string {
+	switch engine {
+	case "chai":
+		return "./data/chai/chai.db"
+	case "chai-native":
+		return "./data/chai-native/chai.db"
+	case "badger":
+		return "./data/badger"
+	case "pebble":
+		return "./data/pebble"
+	case "bbolt":
+		return "./data/bbolt/bolt.db"
+	case "sqlite":
+		return "file:./data/sqlite/sqlite.db?cache=shared&_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)"
+	case "pgx":
+		return "postgres://postgres:pg@127.0.0.1:5432/bench?sslmode=disable"
+	case "mariadb":
+		return "root:mariadb@tcp(127.0.0.1:3306)/bench"
+	case "tidb":
+		return "root@tcp(127.0.0.1:4000)/test"
+	case "clickhouse":
+		return "tcp://127.0.0.1:9000?database=default"
+	}
+	return ""
+}
+
+// setupLogging applies --log-level and --log-format to the global zerolog
+// logger, which the bench package logs through.
+// A non-nil file also gets every log line, as JSON.
+func setupLogging(level, format string, file io.Writer) {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		log.Fatal().Err(err).Str("log_level", level).Msg("invalid log level")
+	}
+	zerolog.SetGlobalLevel(lvl)
+	var out io.Writer
+	switch format {
+	case "json":
+		out = os.Stderr
+	case "console":
+		out = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.TimeOnly}
+	default:
+		log.Fatal().Str("log_format", format).Msg("unknown log format")
+	}
+	if file != nil {
+		out = zerolog.MultiLevelWriter(out, file)
+	}
+	log.Logger = zerolog.New(out).With().Timestamp().Logger()
+}
+
+// applyProfile merges profiles.<name> of the config file over the base
+// (top-level) keys, after the profile it names in inherits, if any.
+func applyProfile(name string, seen []string) error {
+	if slices.Contains(seen, name) {
+		return fmt.Errorf("profile inheritance cycle: %s", strings.Join(append(seen, name), " -> "))
+	}
+	key := "profiles." + name
+	if !k.Exists(key) {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	p := k.Cut(key)
+	if parent := p.String("inherits"); parent != "" {
+		if err := applyProfile(parent, append(seen, name)); err != nil {
+			return err
+		}
+	}
+	p.Delete("inherits")
+	return k.Merge(p)
+}
+
+var dsnVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandDSN replaces ${VAR} in a DSN template with the environment variable
+// VAR, so secrets can stay out of the config file. Bare $ is left alone as
+// passwords may contain it; unset variables are an error rather than "".
+func expandDSN(dsn string) (string, error) {
+	var missing []string
+	out := dsnVar.ReplaceAllStringFunc(dsn, func(m string) string {
+		name := dsnVar.FindStringSubmatch(m)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("DSN references unset environment variables: %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// prettyOptions resolves the pretty-output flags; color=auto enables colors
+// only on a terminal and honors NO_COLOR.
+func prettyOptions(width int, ascii bool, color string, noColor bool) (bench.PrettyOptions, error) {
+	o := bench.PrettyOptions{Width: width, ASCII: ascii}
+	if noColor {
+		color = "never"
+	}
+	switch color {
+	case "always":
+		o.Color = true
+	case "never":
+	case "auto", "":
+		fi, err := os.Stdout.Stat()
+		o.Color = err == nil && fi.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == ""
+	default:
+		return o, fmt.Errorf("unknown color mode %q (want auto|always|never)", color)
+	}
+	return o, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gosuda/chaisql-benchmark/bench"
+	"github.com/knadh/koanf/parsers/yaml"
+)
+
+// outDirSamples is the number of raw latencies kept per phase when
+// --out-dir is set and --samples is not, so the directory is enough to
+// compare the run later.
+const outDirSamples = 10000
+
+// newOutDir creates the artifact directory of this run under base, named
+// after its UTC start time like the run id.
+func newOutDir(base string) (string, error) {
+	if err := os.MkdirAll(base, 0o755); err != nil {
+		return "", err
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	dir := filepath.Join(base, stamp)
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0o755)
+		if !os.IsExist(err) {
+			return dir, err
+		}
+		// another run started within the same second
+		dir = filepath.Join(base, fmt.Sprintf("%s-%d", stamp, i))
+	}
+}
+
+// writeEffectiveConfig saves the configuration after the config file,
+// profile, environment and flags were merged, as a config file that
+// reproduces the run with --config. DSNs are masked like in the report.
+func writeEffectiveConfig(dir, dsn string) error {
+	c := k.Copy()
+	for _, key := range []string{"config", "profile", "profiles", "out_dir"} {
+		c.Delete(key)
+	}
+	_ = c.Set("dsn", bench.SanitizeDSN(dsn))
+	for _, engine := range c.MapKeys("dsns") {
+		_ = c.Set("dsns."+engine, bench.SanitizeDSN(c.String("dsns."+engine)))
+	}
+	b, err := c.Marshal(yaml.Parser())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "config.yaml"), b, 0o644)
+}
+
+// writeReport saves the JSON report, its charts and the raw latencies it
+// carries, one per line, so other tools need not parse the report for them.
+func writeReport(dir string, rep *bench.Report) error {
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), []byte(rep.JSON()+"\n"), 0o644); err != nil {
+		return err
+	}
+	if _, err := writeCharts(filepath.Join(dir, "charts"), []*bench.Report{rep}); err != nil {
+		return err
+	}
+	if err := writeOpenMetrics(filepath.Join(dir, "metrics.txt"), rep); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("workload,latency_ns\n")
+	for _, r := range rep.Results {
+		for _, s := range r.Samples {
+			fmt.Fprintf(&b, "%s,%d\n", r.Workload, s.Nanoseconds())
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, "latencies.csv"), []byte(b.String()), 0o644)
+}
+
+// writeCharts writes the Vega-Lite specs of reps into dir and returns
+// their paths.
+func writeCharts(dir string, reps []*bench.Report) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, c := range bench.Charts(reps) {
+		p := filepath.Join(dir, c.Name)
+		if err := os.WriteFile(p, c.JSON(), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// writeOpenMetrics writes the latency histograms of rep to path.
+func writeOpenMetrics(path string, rep *bench.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := rep.WriteOpenMetrics(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,7 @@
+-- Table keyed by the engine for the autoinc workload (ChaiSQL dialect)
+-- Chai has no AUTOINCREMENT; the key defaults to the next sequence value.
+CREATE SEQUENCE IF NOT EXISTS kv_auto_seq;
+CREATE TABLE IF NOT EXISTS kv_auto (
+    id BIGINT PRIMARY KEY DEFAULT NEXT VALUE FOR kv_auto_seq,
+    v BLOB NOT NULL
+);
\ No newline at end of file
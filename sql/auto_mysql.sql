@@ -0,0 +1,8 @@
+-- Table keyed by the engine for the autoinc workload (MySQL dialect)
+-- TiDB hands out AUTO_INCREMENT ranges per server, so ids are unique but
+-- not consecutive there.
+CREATE TABLE IF NOT EXISTS kv_auto (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    v LONGBLOB NOT NULL,
+    PRIMARY KEY (id)
+);
\ No newline at end of file
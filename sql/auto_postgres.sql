@@ -0,0 +1,5 @@
+-- Table keyed by the engine for the autoinc workload (PostgreSQL dialect)
+CREATE TABLE IF NOT EXISTS kv_auto (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    v BYTEA NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Table keyed by the engine for the autoinc workload (SQLite dialect)
+-- AUTOINCREMENT keeps the high-water mark in sqlite_sequence, which is
+-- written by every insert, instead of reusing max(rowid) + 1.
+CREATE TABLE IF NOT EXISTS kv_auto (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    v BLOB NOT NULL
+);
\ No newline at end of file
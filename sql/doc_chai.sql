@@ -0,0 +1,6 @@
+-- Document table for the doc workloads (ChaiSQL dialect)
+-- Documents are stored as objects, queried by path (doc.address.city).
+CREATE TABLE IF NOT EXISTS kv_doc (
+    k TEXT PRIMARY KEY,
+    doc OBJECT NOT NULL
+);
\ No newline at end of file
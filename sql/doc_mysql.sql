@@ -0,0 +1,8 @@
+-- Document table for the doc workloads (MySQL dialect)
+-- MariaDB's JSON is LONGTEXT with a validity check; MySQL and TiDB store
+-- a binary form.
+CREATE TABLE IF NOT EXISTS kv_doc (
+    k VARCHAR(255) NOT NULL,
+    doc JSON NOT NULL,
+    PRIMARY KEY (k) /*T![clustered_index] CLUSTERED */
+);
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Document table for the doc workloads (PostgreSQL dialect)
+CREATE TABLE IF NOT EXISTS kv_doc (
+    k TEXT PRIMARY KEY,
+    doc JSONB NOT NULL
+);
\ No newline at end of file
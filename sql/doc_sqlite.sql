@@ -0,0 +1,6 @@
+-- Document table for the doc workloads (SQLite dialect)
+-- Documents are JSON text, queried with json_extract.
+CREATE TABLE IF NOT EXISTS kv_doc (
+    k TEXT PRIMARY KEY,
+    doc TEXT NOT NULL
+);
\ No newline at end of file
@@ -10,3 +10,96 @@ var ChaiSchema string
 
 //go:embed schema_postgres.sql
 var PgSchema string
+
+//go:embed tpcb_sqlite.sql
+var TpcbSqliteSchema string
+
+//go:embed tpcb_chai.sql
+var TpcbChaiSchema string
+
+//go:embed tpcb_postgres.sql
+var TpcbPgSchema string
+
+//go:embed typed_sqlite.sql
+var TypedSqliteSchema string
+
+//go:embed typed_chai.sql
+var TypedChaiSchema string
+
+//go:embed typed_postgres.sql
+var TypedPgSchema string
+
+//go:embed schema_mysql.sql
+var MysqlSchema string
+
+//go:embed tpcb_mysql.sql
+var TpcbMysqlSchema string
+
+//go:embed typed_mysql.sql
+var TypedMysqlSchema string
+
+//go:embed schema_clickhouse.sql
+var ClickhouseSchema string
+
+//go:embed typed_clickhouse.sql
+var TypedClickhouseSchema string
+
+//go:embed fk_sqlite.sql
+var FkSqliteSchema string
+
+//go:embed fk_postgres.sql
+var FkPgSchema string
+
+//go:embed fk_mysql.sql
+var FkMysqlSchema string
+
+//go:embed auto_sqlite.sql
+var AutoSqliteSchema string
+
+//go:embed auto_chai.sql
+var AutoChaiSchema string
+
+//go:embed auto_postgres.sql
+var AutoPgSchema string
+
+//go:embed auto_mysql.sql
+var AutoMysqlSchema string
+
+//go:embed wide_sqlite.sql
+var WideSqliteSchema string
+
+//go:embed wide_chai.sql
+var WideChaiSchema string
+
+//go:embed wide_postgres.sql
+var WidePgSchema string
+
+//go:embed wide_mysql.sql
+var WideMysqlSchema string
+
+//go:embed doc_sqlite.sql
+var DocSqliteSchema string
+
+//go:embed doc_chai.sql
+var DocChaiSchema string
+
+//go:embed doc_postgres.sql
+var DocPgSchema string
+
+//go:embed doc_mysql.sql
+var DocMysqlSchema string
+
+//go:embed text_sqlite.sql
+var TextSqliteSchema string
+
+//go:embed text_chai.sql
+var TextChaiSchema string
+
+//go:embed text_postgres.sql
+var TextPgSchema string
+
+//go:embed text_mysql.sql
+var TextMysqlSchema string
+
+//go:embed text_clickhouse.sql
+var TextClickhouseSchema string
@@ -0,0 +1,10 @@
+-- Child table referencing kv for the foreign key workloads (MySQL dialect)
+-- InnoDB indexes the referencing column itself; TiDB enforces foreign keys
+-- from v6.6 with foreign_key_checks on (its default).
+CREATE TABLE IF NOT EXISTS kv_child (
+    id VARCHAR(255) NOT NULL,
+    k VARCHAR(255) NOT NULL,
+    v LONGBLOB NOT NULL,
+    PRIMARY KEY (id) /*T![clustered_index] CLUSTERED */,
+    FOREIGN KEY (k) REFERENCES kv (k) ON DELETE CASCADE
+);
\ No newline at end of file
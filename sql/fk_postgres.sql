@@ -0,0 +1,7 @@
+-- Child table referencing kv for the foreign key workloads (PostgreSQL dialect)
+CREATE TABLE IF NOT EXISTS kv_child (
+    id TEXT PRIMARY KEY,
+    k TEXT NOT NULL REFERENCES kv (k) ON DELETE CASCADE,
+    v BYTEA NOT NULL
+);
+CREATE INDEX IF NOT EXISTS kv_child_k ON kv_child (k);
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- Child table referencing kv for the foreign key workloads (SQLite dialect)
+-- Enforcement is per connection: PRAGMA foreign_keys = ON.
+CREATE TABLE IF NOT EXISTS kv_child (
+    id TEXT PRIMARY KEY,
+    k TEXT NOT NULL REFERENCES kv(k) ON DELETE CASCADE,
+    v BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS kv_child_k ON kv_child(k);
\ No newline at end of file
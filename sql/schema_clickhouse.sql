@@ -0,0 +1,5 @@
+-- ClickHouse dialect schema (MergeTree, sorted by primary key)
+CREATE TABLE IF NOT EXISTS kv (
+    k String,
+    v String
+) ENGINE = MergeTree ORDER BY k
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- MySQL dialect schema (MariaDB, TiDB)
+-- TEXT cannot be a primary key, so k is a bounded VARCHAR. TiDB reads the
+-- /*T! */ comment and clusters the rows by k like InnoDB does; MariaDB
+-- ignores it.
+CREATE TABLE IF NOT EXISTS kv (
+    k VARCHAR(255) NOT NULL,
+    v LONGBLOB NOT NULL,
+    PRIMARY KEY (k) /*T![clustered_index] CLUSTERED */
+);
\ No newline at end of file
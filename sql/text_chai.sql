@@ -0,0 +1,6 @@
+-- Text table for the text search workloads (ChaiSQL dialect)
+CREATE TABLE IF NOT EXISTS kv_text (
+    id BIGINT PRIMARY KEY,
+    t TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS kv_text_t ON kv_text(t);
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Text table for the text search workloads (ClickHouse dialect)
+CREATE TABLE IF NOT EXISTS kv_text (
+    id Int64,
+    t String
+) ENGINE = MergeTree ORDER BY id
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Text table for the text search workloads (MySQL dialect)
+CREATE TABLE IF NOT EXISTS kv_text (
+    id BIGINT NOT NULL,
+    t VARCHAR(255) NOT NULL,
+    PRIMARY KEY (id) /*T![clustered_index] CLUSTERED */
+);
+CREATE INDEX IF NOT EXISTS kv_text_t ON kv_text (t);
\ No newline at end of file
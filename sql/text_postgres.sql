@@ -0,0 +1,7 @@
+-- Text table for the text search workloads (PostgreSQL dialect)
+-- text_pattern_ops lets LIKE prefix matches use the index in any locale.
+CREATE TABLE IF NOT EXISTS kv_text (
+    id BIGINT PRIMARY KEY,
+    t TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS kv_text_t ON kv_text (t text_pattern_ops);
\ No newline at end of file
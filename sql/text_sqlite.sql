@@ -0,0 +1,8 @@
+-- Text table for the text search workloads (SQLite dialect)
+-- LIKE is case-insensitive here, so the index only serves prefix matches
+-- under PRAGMA case_sensitive_like.
+CREATE TABLE IF NOT EXISTS kv_text (
+    id INTEGER PRIMARY KEY,
+    t TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS kv_text_t ON kv_text(t);
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- pgbench-compatible TPC-B tables (PostgreSQL dialect)
+CREATE TABLE IF NOT EXISTS pgbench_branches (
+    bid INTEGER PRIMARY KEY,
+    bbalance INTEGER NOT NULL,
+    filler CHAR(88)
+);
+CREATE TABLE IF NOT EXISTS pgbench_tellers (
+    tid INTEGER PRIMARY KEY,
+    bid INTEGER NOT NULL,
+    tbalance INTEGER NOT NULL,
+    filler CHAR(84)
+);
+CREATE TABLE IF NOT EXISTS pgbench_accounts (
+    aid INTEGER PRIMARY KEY,
+    bid INTEGER NOT NULL,
+    abalance INTEGER NOT NULL,
+    filler CHAR(84)
+);
+CREATE TABLE IF NOT EXISTS pgbench_history (
+    tid INTEGER NOT NULL,
+    bid INTEGER NOT NULL,
+    aid INTEGER NOT NULL,
+    delta INTEGER NOT NULL,
+    mtime TIMESTAMP NOT NULL,
+    filler CHAR(22)
+);
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- Typed table for NULL/type-diversity workloads (ClickHouse dialect)
+CREATE TABLE IF NOT EXISTS typed (
+    id Int64,
+    i Nullable(Int64),
+    f Nullable(Float64),
+    b Nullable(UInt8),
+    ts Nullable(DateTime64(6)),
+    t Nullable(String),
+    g Nullable(Int64)
+) ENGINE = MergeTree ORDER BY id
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- Typed table for NULL/type-diversity workloads (MySQL dialect)
+CREATE TABLE IF NOT EXISTS typed (
+    id BIGINT NOT NULL,
+    i BIGINT,
+    f DOUBLE,
+    b BOOLEAN,
+    ts DATETIME(6),
+    t TEXT,
+    g BIGINT,
+    PRIMARY KEY (id) /*T![clustered_index] CLUSTERED */
+);
+CREATE INDEX IF NOT EXISTS typed_i ON typed (i);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- Typed table for NULL/type-diversity workloads (PostgreSQL dialect)
+CREATE TABLE IF NOT EXISTS typed (
+    id BIGINT PRIMARY KEY,
+    i BIGINT,
+    f DOUBLE PRECISION,
+    b BOOLEAN,
+    ts TIMESTAMP,
+    t TEXT,
+    g BIGINT
+);
+CREATE INDEX IF NOT EXISTS typed_i ON typed (i);
\ No newline at end of file
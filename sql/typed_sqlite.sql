@@ -0,0 +1,11 @@
+-- Typed table for NULL/type-diversity workloads (SQLite dialect)
+CREATE TABLE IF NOT EXISTS typed (
+    id INTEGER PRIMARY KEY,
+    i INTEGER,
+    f REAL,
+    b BOOLEAN,
+    ts TIMESTAMP,
+    t TEXT,
+    g INTEGER
+);
+CREATE INDEX IF NOT EXISTS typed_i ON typed(i);
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- Wide table for the partial vs full-row update workloads (ChaiSQL dialect)
+CREATE TABLE IF NOT EXISTS kv_wide (
+    id BIGINT PRIMARY KEY,
+    n BIGINT NOT NULL,
+    c1 BLOB NOT NULL,
+    c2 BLOB NOT NULL,
+    c3 BLOB NOT NULL,
+    c4 BLOB NOT NULL,
+    c5 BLOB NOT NULL,
+    c6 BLOB NOT NULL,
+    c7 BLOB NOT NULL,
+    c8 BLOB NOT NULL
+);
\ No newline at end of file
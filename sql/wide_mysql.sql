@@ -0,0 +1,14 @@
+-- Wide table for the partial vs full-row update workloads (MySQL dialect)
+CREATE TABLE IF NOT EXISTS kv_wide (
+    id BIGINT NOT NULL,
+    n BIGINT NOT NULL,
+    c1 VARBINARY(255) NOT NULL,
+    c2 VARBINARY(255) NOT NULL,
+    c3 VARBINARY(255) NOT NULL,
+    c4 VARBINARY(255) NOT NULL,
+    c5 VARBINARY(255) NOT NULL,
+    c6 VARBINARY(255) NOT NULL,
+    c7 VARBINARY(255) NOT NULL,
+    c8 VARBINARY(255) NOT NULL,
+    PRIMARY KEY (id) /*T![clustered_index] CLUSTERED */
+);
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- Wide table for the partial vs full-row update workloads (PostgreSQL dialect)
+-- Values stay below the TOAST threshold, so every version is stored inline.
+CREATE TABLE IF NOT EXISTS kv_wide (
+    id BIGINT PRIMARY KEY,
+    n BIGINT NOT NULL,
+    c1 BYTEA NOT NULL,
+    c2 BYTEA NOT NULL,
+    c3 BYTEA NOT NULL,
+    c4 BYTEA NOT NULL,
+    c5 BYTEA NOT NULL,
+    c6 BYTEA NOT NULL,
+    c7 BYTEA NOT NULL,
+    c8 BYTEA NOT NULL
+);
\ No newline at end of file